@@ -48,7 +48,7 @@ func main() {
 			x402gin.WithMimeType(config.MimeType),
 			x402gin.WithMaxTimeoutSeconds(config.MaxTimeoutSeconds),
 		),
-		proxyHandler(config.TargetURL, config.Headers))
+		proxyHandler(config))
 
 	err = r.Run(":4021")
 	if err != nil {
@@ -68,12 +68,17 @@ type ProxyConfig struct {
 	Testnet           bool                                         `json:"testnet"`
 	Headers           map[string]string                            `json:"headers"`
 	CreateAuthHeaders func() (map[string]map[string]string, error) `json:"-"`
+	// ForwardPaymentHeader forwards the client's X-PAYMENT header so the target can do its own accounting
+	ForwardPaymentHeader bool `json:"forwardPaymentHeader"`
+	// PayerHeader, if set, is the header telling the target the verified payer, e.g. X-Payer-Address.
+	// Any value the client sent in it is removed so it can't be spoofed.
+	PayerHeader string `json:"payerHeader"`
 }
 
-func proxyHandler(targetURL string, headers map[string]string) gin.HandlerFunc {
-	fmt.Println("Proxying to:", targetURL)
+func proxyHandler(config *ProxyConfig) gin.HandlerFunc {
+	fmt.Println("Proxying to:", config.TargetURL)
 
-	target, err := url.Parse(targetURL)
+	target, err := url.Parse(config.TargetURL)
 	if err != nil {
 		fmt.Println("Error parsing target URL:", err)
 		panic(err)
@@ -101,12 +106,23 @@ func proxyHandler(targetURL string, headers map[string]string) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 		// Add any configured headers
-		for k, v := range headers {
+		for k, v := range config.Headers {
 			c.Request.Header.Set(k, v)
 		}
 
-		// Remove any payment headers
-		delete(c.Request.Header, "X-Payment")
+		// Remove the payment header unless the target wants it
+		if !config.ForwardPaymentHeader {
+			c.Request.Header.Del("X-Payment")
+		}
+
+		// Only the proxy may tell the target who paid
+		if config.PayerHeader != "" {
+			c.Request.Header.Del(config.PayerHeader)
+			if payer, ok := x402gin.PayerFromContext(c); ok && payer != "" {
+				c.Request.Header.Set(config.PayerHeader, payer)
+			}
+		}
+
 		proxy.ServeHTTP(c.Writer, c.Request)
 	}
 }
@@ -0,0 +1,82 @@
+package coinbasefacilitator
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Credentials holds a CDP API key that can be rotated while requests are in flight
+type Credentials struct {
+	mu           sync.RWMutex
+	apiKeyID     string
+	apiKeySecret string
+}
+
+// NewCredentials creates credentials for the given CDP API key.
+// Empty values fall back to the CDP_API_KEY_ID and CDP_API_KEY_SECRET environment variables.
+func NewCredentials(apiKeyID, apiKeySecret string) *Credentials {
+	return &Credentials{
+		apiKeyID:     apiKeyID,
+		apiKeySecret: apiKeySecret,
+	}
+}
+
+// SetCredentials atomically replaces the API key. Requests that already created their auth
+// headers keep using the previous key; subsequent requests use the new one.
+func (c *Credentials) SetCredentials(apiKeyID, apiKeySecret string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.apiKeyID = apiKeyID
+	c.apiKeySecret = apiKeySecret
+}
+
+// get returns the current API key, falling back to the environment
+func (c *Credentials) get() (string, string, error) {
+	c.mu.RLock()
+	id, secret := c.apiKeyID, c.apiKeySecret
+	c.mu.RUnlock()
+
+	if id == "" {
+		id = os.Getenv("CDP_API_KEY_ID")
+	}
+	if secret == "" {
+		secret = os.Getenv("CDP_API_KEY_SECRET")
+	}
+
+	if id == "" || secret == "" {
+		return "", "", fmt.Errorf("missing credentials: CDP_API_KEY_ID and CDP_API_KEY_SECRET must be set")
+	}
+
+	return id, secret, nil
+}
+
+// CreateAuthHeaders creates CDP auth headers from the current API key.
+// It can be used as the CreateAuthHeaders function of a facilitator config.
+func (c *Credentials) CreateAuthHeaders() (map[string]map[string]string, error) {
+	id, secret, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+
+	verifyPath := fmt.Sprintf("%s/verify", CoinbaseFacilitatorV2Route)
+	settlePath := fmt.Sprintf("%s/settle", CoinbaseFacilitatorV2Route)
+
+	verifyToken, err := CreateAuthHeader(id, secret, CoinbaseFacilitatorBaseURL, verifyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verify auth header: %w", err)
+	}
+
+	settleToken, err := CreateAuthHeader(id, secret, CoinbaseFacilitatorBaseURL, settlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create settle auth header: %w", err)
+	}
+
+	correlationHeader := CreateCorrelationHeader()
+
+	return map[string]map[string]string{
+		"verify": {"Authorization": verifyToken, "Correlation-Context": correlationHeader},
+		"settle": {"Authorization": settleToken, "Correlation-Context": correlationHeader},
+	}, nil
+}
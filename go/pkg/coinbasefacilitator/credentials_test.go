@@ -0,0 +1,141 @@
+package coinbasefacilitator_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/coinbasefacilitator"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newTestKeySecret(t *testing.T) string {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newTestPayload() *types.PaymentPayload {
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      "exact",
+		Network:     types.NetworkBaseSepolia,
+		Payload: &types.ExactEvmPayload{
+			Signature: "0xsignature",
+			Authorization: &types.ExactEvmPayloadAuthorization{
+				From:        "0xfrom",
+				To:          "0xto",
+				Value:       "1000",
+				ValidAfter:  "0",
+				ValidBefore: "9999999999",
+				Nonce:       "0xnonce",
+			},
+		},
+	}
+}
+
+// jwtSubject returns the sub claim of a bearer token without verifying it
+func jwtSubject(t *testing.T, authorization string) string {
+	t.Helper()
+
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	if len(parts) != 3 {
+		t.Errorf("Expected a JWT bearer token, got: %q", authorization)
+		return ""
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Errorf("Failed to decode JWT claims: %v", err)
+		return ""
+	}
+
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Errorf("Failed to unmarshal JWT claims: %v", err)
+	}
+
+	return claims.Sub
+}
+
+func TestSetCredentialsWhileRequestsInFlight(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		subjects []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject := jwtSubject(t, r.Header.Get("Authorization"))
+		mu.Lock()
+		subjects = append(subjects, subject)
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	credentials := coinbasefacilitator.NewCredentials("old-key", newTestKeySecret(t))
+	config := coinbasefacilitator.CreateFacilitatorConfigWithCredentials(credentials)
+	config.URL = server.URL
+	client := facilitatorclient.NewFacilitatorClient(config)
+
+	newSecret := newTestKeySecret(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		credentials.SetCredentials("new-key", newSecret)
+	}()
+	wg.Wait()
+
+	mu.Lock()
+	for _, subject := range subjects {
+		if subject != "old-key" && subject != "new-key" {
+			t.Errorf("Expected requests to use the old or new key, got: %q", subject)
+		}
+	}
+	subjects = nil
+	mu.Unlock()
+
+	// Once rotated, every request uses the new key
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(subjects) != 1 || subjects[0] != "new-key" {
+		t.Errorf("Expected the new key after rotation, got: %v", subjects)
+	}
+}
+
+func TestMissingCredentials(t *testing.T) {
+	t.Setenv("CDP_API_KEY_ID", "")
+	t.Setenv("CDP_API_KEY_SECRET", "")
+
+	credentials := coinbasefacilitator.NewCredentials("", "")
+	if _, err := credentials.CreateAuthHeaders(); err == nil {
+		t.Error("Expected error for missing credentials, got err == nil")
+	}
+}
@@ -0,0 +1,19 @@
+package exactevm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NormalizeAddress returns the EIP-55 checksummed form of a hex encoded address,
+// so addresses can be compared regardless of their case
+func NormalizeAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !common.IsHexAddress(address) {
+		return "", fmt.Errorf("invalid address: %s", address)
+	}
+
+	return common.HexToAddress(address).Hex(), nil
+}
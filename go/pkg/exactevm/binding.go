@@ -0,0 +1,65 @@
+package exactevm
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ReasonRequirementsMismatch is reported by CheckRequirementsBinding when the signed authorization was made for
+// other requirements than the ones it is settled against
+const ReasonRequirementsMismatch = "invalid_exact_evm_payload_requirements_mismatch"
+
+// CheckRequirementsBinding checks that the signed authorization pays for the requirements it is about to be
+// settled against, not for other requirements signed by the same payer: the signed recipient must be the
+// settlement recipient, the signed value must cover the required value, and the signature must recover to the
+// authorization's from address under the EIP-712 domain of the requirements' asset and network, which binds the
+// asset. A facilitator verifying under the requirements it is sent checks the same, but a server checking
+// locally doesn't have to trust the facilitator, or the accepts a payment was matched to, to get it right.
+// Only signatures of externally owned accounts can be checked, so payers signing through a smart contract
+// wallet fail it. Errors are *VerificationError, with ReasonRequirementsMismatch for a payment of other
+// requirements.
+func CheckRequirementsBinding(payload *types.PaymentPayload, requirements *types.PaymentRequirements) error {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return newVerificationError(ReasonInvalidPayload, "payment payload is missing its authorization")
+	}
+	authorization := payload.Payload.Authorization
+
+	recipient, err := SettlementRecipient(requirements)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+	if !common.IsHexAddress(authorization.To) || !strings.EqualFold(authorization.To, recipient) {
+		return newVerificationError(ReasonRequirementsMismatch, "signed recipient %s is not the settlement recipient %s", authorization.To, recipient)
+	}
+
+	required, err := RequiredValue(requirements)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+	value, err := parseUint256("value", authorization.Value)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidPayload, Err: err}
+	}
+	if value.Cmp(required) < 0 {
+		return newVerificationError(ReasonRequirementsMismatch, "signed value %s does not cover the required %s", value, required)
+	}
+
+	digest, err := ExactSigningDigest(requirements, authorization)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		return newVerificationError(ReasonInvalidSignature, "invalid signature encoding: %v", err)
+	}
+	signer, err := RecoverAddress(digest, signature)
+	if err != nil || signer != common.HexToAddress(authorization.From) {
+		return newVerificationError(ReasonRequirementsMismatch, "signature of %s does not cover asset %s on %s", authorization.From, requirements.Asset, requirements.Network)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package exactevm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestCheckRequirementsBinding(t *testing.T) {
+	signed := newTestRequirements(t, "")
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), signed)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := exactevm.CheckRequirementsBinding(payload, signed); err != nil {
+		t.Fatalf("Expected the payment to be bound to the requirements it was signed for, got: %v", err)
+	}
+
+	// Each case submits the payment signed for the requirements above against requirements B
+	tests := []struct {
+		name   string
+		modify func(requirements *types.PaymentRequirements, payload *types.PaymentPayload)
+	}{
+		{"other recipient", func(requirements *types.PaymentRequirements, payload *types.PaymentPayload) {
+			requirements.PayTo = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+		}},
+		{"higher amount", func(requirements *types.PaymentRequirements, payload *types.PaymentPayload) {
+			requirements.MaxAmountRequired = "20000"
+		}},
+		{"other asset", func(requirements *types.PaymentRequirements, payload *types.PaymentPayload) {
+			requirements.Asset = "0x0000000000000000000000000000000000000001"
+		}},
+		{"other network", func(requirements *types.PaymentRequirements, payload *types.PaymentPayload) {
+			requirements.Network = "base"
+			requirements.Asset = types.USDCAssets["base"].Address
+			payload.Network = "base"
+		}},
+		{"recipient rewritten to match", func(requirements *types.PaymentRequirements, payload *types.PaymentPayload) {
+			requirements.PayTo = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+			authorization := *payload.Payload.Authorization
+			authorization.To = requirements.PayTo
+			payload.Payload = &types.ExactEvmPayload{Signature: payload.Payload.Signature, Authorization: &authorization}
+		}},
+		{"value rewritten to match", func(requirements *types.PaymentRequirements, payload *types.PaymentPayload) {
+			requirements.MaxAmountRequired = "20000"
+			authorization := *payload.Payload.Authorization
+			authorization.Value = requirements.MaxAmountRequired
+			payload.Payload = &types.ExactEvmPayload{Signature: payload.Payload.Signature, Authorization: &authorization}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := *signed
+			submitted := *payload
+			tt.modify(&requirements, &submitted)
+
+			err := exactevm.CheckRequirementsBinding(&submitted, &requirements)
+			var verificationErr *exactevm.VerificationError
+			if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonRequirementsMismatch {
+				t.Errorf("Expected %s, got: %v", exactevm.ReasonRequirementsMismatch, err)
+			}
+
+			// The local verifier refuses the same payments
+			if _, err := exactevm.VerifyPayment(&submitted, &requirements); err == nil {
+				t.Error("Expected VerifyPayment to refuse the payment, got err == nil")
+			}
+		})
+	}
+}
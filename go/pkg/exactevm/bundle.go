@@ -0,0 +1,74 @@
+package exactevm
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ReasonDuplicateNonce is reported by ValidateBundle when two payloads of a bundle share an authorization nonce
+const ReasonDuplicateNonce = "invalid_exact_evm_payload_authorization_nonce_duplicate"
+
+// ValidateBundle checks that the payloads of a bundled purchase, e.g. one per item of a cart, all pay for the
+// resource of the requirements and together authorize at least expectedTotal, in the asset's base units, before
+// each is verified and settled. x402 payloads don't name their resource, so each must match the requirements'
+// scheme, network and settlement recipient, and have its own nonce so no payment is counted twice. Unlike
+// VerifyPayment it does not check signatures, validity windows or any one payload's value, which verification
+// of each payload still has to. Errors are *VerificationError, naming the offending payload by index.
+func ValidateBundle(payloads []*types.PaymentPayload, requirements *types.PaymentRequirements, expectedTotal string) error {
+	expected, err := parseUint256("expected total", expectedTotal)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+	if len(payloads) == 0 {
+		return newVerificationError(ReasonInvalidPayload, "bundle has no payments")
+	}
+	if requirements.Scheme != Scheme {
+		return newVerificationError(ReasonInvalidScheme, "unsupported scheme: %s", requirements.Scheme)
+	}
+	recipient, err := SettlementRecipient(requirements)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+
+	total := new(big.Int)
+	nonces := make(map[string]int, len(payloads))
+	for i, payload := range payloads {
+		if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+			return newVerificationError(ReasonInvalidPayload, "payment %d is missing its authorization", i)
+		}
+		if payload.Scheme != Scheme {
+			return newVerificationError(ReasonInvalidScheme, "payment %d has unsupported scheme: %s", i, payload.Scheme)
+		}
+		if err := CheckNetwork(payload, requirements); err != nil {
+			verificationErr := err.(*VerificationError)
+			return newVerificationError(verificationErr.Reason, "payment %d: %v", i, verificationErr.Err)
+		}
+
+		authorization := payload.Payload.Authorization
+		if !common.IsHexAddress(authorization.To) || !strings.EqualFold(authorization.To, recipient) {
+			return newVerificationError(ReasonRecipientMismatch, "payment %d recipient %s does not match settlement recipient %s", i, authorization.To, recipient)
+		}
+
+		nonce := strings.ToLower(authorization.Nonce)
+		if first, ok := nonces[nonce]; ok {
+			return newVerificationError(ReasonDuplicateNonce, "payments %d and %d share the nonce %s", first, i, authorization.Nonce)
+		}
+		nonces[nonce] = i
+
+		value, err := parseUint256("value", authorization.Value)
+		if err != nil {
+			return newVerificationError(ReasonInvalidPayload, "payment %d: %v", i, err)
+		}
+		total.Add(total, value)
+	}
+
+	if total.Cmp(expected) < 0 {
+		return newVerificationError(ReasonInsufficientValue, "bundle total %s does not cover the expected %s", total, expected)
+	}
+
+	return nil
+}
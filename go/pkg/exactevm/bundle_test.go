@@ -0,0 +1,61 @@
+package exactevm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestValidateBundle(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+
+	newPayment := func(value string) *types.PaymentPayload {
+		item := *requirements
+		item.MaxAmountRequired = value
+		payload, err := exactevm.CreatePayment(signer, &item)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		return payload
+	}
+	first, second := newPayment("6000"), newPayment("4000")
+
+	if err := exactevm.ValidateBundle([]*types.PaymentPayload{first, second}, requirements, "10000"); err != nil {
+		t.Errorf("Expected the bundle to cover its total, got: %v", err)
+	}
+
+	otherNetwork := newPayment("4000")
+	otherNetwork.Network = types.NetworkBase
+	otherRecipient := newPayment("4000")
+	otherRecipient.Payload.Authorization.To = "0x0000000000000000000000000000000000000001"
+
+	testCases := []struct {
+		name     string
+		payloads []*types.PaymentPayload
+		total    string
+		reason   string
+	}{
+		{"short of the total", []*types.PaymentPayload{first, second}, "10001", exactevm.ReasonInsufficientValue},
+		{"same payment twice", []*types.PaymentPayload{first, first}, "10000", exactevm.ReasonDuplicateNonce},
+		{"other network", []*types.PaymentPayload{first, otherNetwork}, "10000", exactevm.ReasonWrongNetwork},
+		{"other recipient", []*types.PaymentPayload{first, otherRecipient}, "10000", exactevm.ReasonRecipientMismatch},
+		{"empty bundle", nil, "0", exactevm.ReasonInvalidPayload},
+		{"invalid total", []*types.PaymentPayload{first}, "1.5", exactevm.ReasonInvalidRequirement},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := exactevm.ValidateBundle(tc.payloads, requirements, tc.total)
+			var verificationErr *exactevm.VerificationError
+			if !errors.As(err, &verificationErr) {
+				t.Fatalf("Expected a VerificationError, got: %v", err)
+			}
+			if verificationErr.Reason != tc.reason {
+				t.Errorf("Expected reason %s, got: %s", tc.reason, verificationErr.Reason)
+			}
+		})
+	}
+}
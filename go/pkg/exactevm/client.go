@@ -0,0 +1,256 @@
+package exactevm
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// Scheme is the identifier of the exact payment scheme
+const Scheme = "exact"
+
+// validAfterOffset backdates validAfter to tolerate small clock differences with the facilitator
+const validAfterOffset = 60 * time.Second
+
+// DefaultMaxValidityWindow is the longest authorization validity window PreparePayment creates
+// unless WithMaxValidityWindow sets another
+const DefaultMaxValidityWindow = time.Hour
+
+// nonceKeyTag keys the hash deriving nonces from idempotency keys, separating them from other uses of the key
+const nonceKeyTag = "x402 exact authorization nonce"
+
+// Clock reports the current time, so the validity window can be checked against a controlled clock in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock backed by time.Now
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// PaymentOptions is the options for creating and verifying a payment.
+type PaymentOptions struct {
+	Nonce *[32]byte
+	Clock Clock
+	// Rand is the source of random authorization nonces, crypto/rand.Reader unless set with WithRand
+	Rand io.Reader
+	// ExactAmount requires the authorized value to equal the required value instead of covering it
+	ExactAmount bool
+	// MaxValidityWindow caps validBefore - validAfter of created authorizations
+	MaxValidityWindow time.Duration
+	// ClockSkewMargin widens created authorizations on both ends, see WithClockSkewMargin
+	ClockSkewMargin time.Duration
+}
+
+// Options is the type for the options for creating and verifying a payment.
+type Options func(*PaymentOptions)
+
+// newPaymentOptions applies opts over the defaults
+func newPaymentOptions(opts []Options) *PaymentOptions {
+	options := &PaymentOptions{Clock: systemClock{}, Rand: rand.Reader, MaxValidityWindow: DefaultMaxValidityWindow}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithClock is an option for creating and verifying payments against clock instead of the system time
+func WithClock(clock Clock) Options {
+	return func(options *PaymentOptions) {
+		options.Clock = clock
+	}
+}
+
+// WithRand is an option for creating payments with random nonces read from r instead of crypto/rand.Reader,
+// so tests can assert the exact bytes of created payloads. Nonces must be unpredictable: a deterministic
+// source must never be used in production.
+func WithRand(r io.Reader) Options {
+	return func(options *PaymentOptions) {
+		options.Rand = r
+	}
+}
+
+// WithExactAmount is an option for verifying that a payment authorizes exactly the required value, maxAmountRequired
+// plus any relayer fee, rejecting overpayments with ReasonValueMismatch. By default, as in the x402 reference
+// facilitator, any value covering the required value is accepted.
+func WithExactAmount() Options {
+	return func(options *PaymentOptions) {
+		options.ExactAmount = true
+	}
+}
+
+// WithMaxValidityWindow is an option for creating payments whose authorization is valid for at most window,
+// e.g. the limit a facilitator advertises with types.SupportedKind.MaxTimeoutSeconds, so it isn't rejected for
+// an over-long validity window. Requirements asking for a longer timeout get their validBefore clamped.
+// A window that is not positive uses DefaultMaxValidityWindow.
+func WithMaxValidityWindow(window time.Duration) Options {
+	return func(options *PaymentOptions) {
+		if window <= 0 {
+			window = DefaultMaxValidityWindow
+		}
+		options.MaxValidityWindow = window
+	}
+}
+
+// WithClockSkewMargin is an option for creating payments whose authorization is valid from margin earlier and until
+// margin later than otherwise, so a facilitator whose clock is off by up to margin doesn't reject them as not yet
+// valid or expired. The window is still capped by WithMaxValidityWindow. It panics on a negative margin.
+func WithClockSkewMargin(margin time.Duration) Options {
+	if margin < 0 {
+		panic(fmt.Sprintf("invalid clock skew margin %s", margin))
+	}
+
+	return func(options *PaymentOptions) {
+		options.ClockSkewMargin = margin
+	}
+}
+
+// WithNonce is an option for creating a payment with the given authorization nonce instead of a random one
+func WithNonce(nonce [32]byte) Options {
+	return func(options *PaymentOptions) {
+		options.Nonce = &nonce
+	}
+}
+
+// WithIdempotencyKey is an option for creating a payment whose nonce is derived from key with NonceFromKey,
+// so payments re-created for the same key can be settled at most once
+func WithIdempotencyKey(key string) Options {
+	return WithNonce(NonceFromKey(key))
+}
+
+// PreparePayment builds an unsigned payment payload transferring the required amount, including
+// any relayer fee, from the given address to the requirements' settlement recipient.
+// The authorization nonce is the challenge nonce of the requirements' extra, if the server sent one,
+// unless another is set with WithNonce or WithIdempotencyKey, and otherwise random, read from the source set
+// with WithRand.
+// The authorization is valid from shortly before now until the requirements' timeout,
+// for at most DefaultMaxValidityWindow or the window set with WithMaxValidityWindow,
+// and widened on both ends by any margin set with WithClockSkewMargin.
+func PreparePayment(from common.Address, requirements *types.PaymentRequirements, opts ...Options) (*types.PaymentPayload, error) {
+	options := newPaymentOptions(opts)
+
+	value, err := RequiredValue(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient, err := SettlementRecipient(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce string
+	if options.Nonce != nil {
+		nonce = hexutil.Encode(options.Nonce[:])
+	} else if nonce, err = challengeNonce(requirements); err != nil {
+		return nil, err
+	}
+	if nonce == "" {
+		if nonce, err = readNonce(options.Rand); err != nil {
+			return nil, err
+		}
+	}
+
+	now := options.Clock.Now()
+	validAfter := now.Add(-validAfterOffset - options.ClockSkewMargin)
+	validBefore := now.Add(time.Duration(requirements.MaxTimeoutSeconds)*time.Second + options.ClockSkewMargin)
+	if validBefore.Sub(validAfter) > options.MaxValidityWindow {
+		validBefore = validAfter.Add(options.MaxValidityWindow)
+		if !validBefore.After(now) {
+			return nil, fmt.Errorf("max validity window %s does not cover the %s validAfter backdating", options.MaxValidityWindow, now.Sub(validAfter))
+		}
+	}
+
+	return &types.PaymentPayload{
+		X402Version: types.X402Version,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload: &types.ExactEvmPayload{
+			Authorization: &types.ExactEvmPayloadAuthorization{
+				From:        from.Hex(),
+				To:          recipient,
+				Value:       value.String(),
+				ValidAfter:  strconv.FormatInt(validAfter.Unix(), 10),
+				ValidBefore: strconv.FormatInt(validBefore.Unix(), 10),
+				Nonce:       nonce,
+			},
+		},
+	}, nil
+}
+
+// CreatePayment prepares and signs a payment payload satisfying the payment requirements.
+// Signing is deterministic, so with WithRand and WithClock the payload is fully reproducible.
+func CreatePayment(signer Signer, requirements *types.PaymentRequirements, opts ...Options) (*types.PaymentPayload, error) {
+	if requirements.Scheme != Scheme {
+		return nil, fmt.Errorf("unsupported scheme: %s", requirements.Scheme)
+	}
+
+	payload, err := PreparePayment(signer.Address(), requirements, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := SignAuthorization(signer, payload.Payload.Authorization, requirements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign authorization: %w", err)
+	}
+	payload.Payload.Signature = signature
+
+	return payload, nil
+}
+
+// challengeNonce returns the challenge nonce of the requirements' extra, or "" if there is none
+func challengeNonce(requirements *types.PaymentRequirements) (string, error) {
+	var extra types.ExactEvmExtra
+	if _, err := requirements.DecodeExtra(&extra); err != nil || extra.ChallengeNonce == "" {
+		return "", nil
+	}
+
+	nonce, err := hexutil.Decode(extra.ChallengeNonce)
+	if err != nil || len(nonce) != 32 {
+		return "", fmt.Errorf("invalid challenge nonce: must be 32 hex encoded bytes")
+	}
+
+	return hexutil.Encode(nonce), nil
+}
+
+// CreateNonce generates a random 32-byte hex encoded nonce for an authorization
+func CreateNonce() (string, error) {
+	return readNonce(rand.Reader)
+}
+
+// readNonce reads a 32-byte hex encoded nonce from r
+func readNonce(r io.Reader) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return hexutil.Encode(nonce), nil
+}
+
+// NonceFromKey derives an authorization nonce from an idempotency key such as an order ID.
+// A token accepts each nonce once per payer, so every payment created for the same key can settle at most once,
+// with no storage on the client. This is opt-in: unlike random nonces, derived nonces are predictable by
+// anyone who knows the key, and reveal whether two payments share a key.
+func NonceFromKey(key string) [32]byte {
+	mac := hmac.New(sha256.New, []byte(nonceKeyTag))
+	mac.Write([]byte(key))
+
+	var nonce [32]byte
+	copy(nonce[:], mac.Sum(nil))
+	return nonce
+}
@@ -0,0 +1,333 @@
+package exactevm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/middlewaretest/testclock"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const testRequirementsJSON = `{
+	"scheme": "exact",
+	"network": "base-sepolia",
+	"maxAmountRequired": "10000",
+	"resource": "https://example.com/resource",
+	"description": "Test resource",
+	"mimeType": "application/json",
+	"payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+	"maxTimeoutSeconds": 60,
+	"asset": "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+	"extra": {"name": "Bridged USDC", "version": "1"}
+}`
+
+func TestExtraRoundTrip(t *testing.T) {
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal([]byte(testRequirementsJSON), &requirements); err != nil {
+		t.Fatalf("Failed to unmarshal requirements: %v", err)
+	}
+
+	encoded, err := json.Marshal(&requirements)
+	if err != nil {
+		t.Fatalf("Failed to marshal requirements: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"extra":{"name":"Bridged USDC","version":"1"}`) {
+		t.Errorf("Expected extra to round-trip, got: %s", encoded)
+	}
+
+	domain, err := exactevm.DomainForRequirements(&requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if domain.Name != "Bridged USDC" || domain.Version != "1" {
+		t.Errorf("Expected domain to be read from extra, got: %s %s", domain.Name, domain.Version)
+	}
+	if domain.ChainID.Int64() != 84532 {
+		t.Errorf("Expected chain ID 84532, got: %d", domain.ChainID.Int64())
+	}
+}
+
+func TestDomainForRequirementsMissingExtra(t *testing.T) {
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal([]byte(testRequirementsJSON), &requirements); err != nil {
+		t.Fatalf("Failed to unmarshal requirements: %v", err)
+	}
+	requirements.Extra = nil
+	// Only assets missing from the registry have no domain to fall back to
+	requirements.Asset = "0x0000000000000000000000000000000000000001"
+
+	if _, err := exactevm.DomainForRequirements(&requirements); err == nil {
+		t.Error("Expected error for missing domain, got err == nil")
+	}
+}
+
+func TestCreatePayment(t *testing.T) {
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal([]byte(testRequirementsJSON), &requirements); err != nil {
+		t.Fatalf("Failed to unmarshal requirements: %v", err)
+	}
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, &requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	authorization := payload.Payload.Authorization
+	if authorization.From != signer.Address().Hex() {
+		t.Errorf("Expected from %s, got: %s", signer.Address().Hex(), authorization.From)
+	}
+	if authorization.To != requirements.PayTo {
+		t.Errorf("Expected to %s, got: %s", requirements.PayTo, authorization.To)
+	}
+	if authorization.Value != requirements.MaxAmountRequired {
+		t.Errorf("Expected value %s, got: %s", requirements.MaxAmountRequired, authorization.Value)
+	}
+
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil || len(signature) != 65 {
+		t.Fatalf("Expected a 65-byte signature, got: %s", payload.Payload.Signature)
+	}
+	if signature[64] != 27 && signature[64] != 28 {
+		t.Errorf("Expected V to be 27 or 28, got: %d", signature[64])
+	}
+
+	// Signing with a different domain must produce a different signature
+	otherRequirements := requirements
+	otherExtra := json.RawMessage(`{"name":"USDC","version":"2"}`)
+	otherRequirements.Extra = &otherExtra
+	otherSignature, err := exactevm.SignAuthorization(signer, authorization, &otherRequirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if otherSignature == payload.Payload.Signature {
+		t.Error("Expected the domain from extra to affect the signature")
+	}
+
+	if common.HexToAddress(authorization.From) != signer.Address() {
+		t.Errorf("Expected from to be the signer address")
+	}
+}
+
+func TestCreatePaymentWithIdempotencyKey(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+
+	first, err := exactevm.CreatePayment(signer, requirements, exactevm.WithIdempotencyKey("order-42"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	second, err := exactevm.CreatePayment(signer, requirements, exactevm.WithIdempotencyKey("order-42"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	other, err := exactevm.CreatePayment(signer, requirements, exactevm.WithIdempotencyKey("order-43"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	nonce := exactevm.NonceFromKey("order-42")
+	if first.Payload.Authorization.Nonce != hexutil.Encode(nonce[:]) {
+		t.Errorf("Expected nonce derived from the key, got: %s", first.Payload.Authorization.Nonce)
+	}
+	if second.Payload.Authorization.Nonce != first.Payload.Authorization.Nonce {
+		t.Errorf("Expected the same key to yield the same nonce")
+	}
+	if other.Payload.Authorization.Nonce == first.Payload.Authorization.Nonce {
+		t.Errorf("Expected different keys to yield different nonces")
+	}
+	if _, err := exactevm.VerifyPayment(first, requirements); err != nil {
+		t.Errorf("Expected the payment to verify, got: %v", err)
+	}
+}
+
+func TestCreatePaymentWithChallengeNonce(t *testing.T) {
+	challengeNonce := "0x" + strings.Repeat("ab", 32)
+	requirements := newTestRequirements(t, `{"name": "Bridged USDC", "version": "1", "challengeNonce": "`+challengeNonce+`"}`)
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.Payload.Authorization.Nonce != challengeNonce {
+		t.Errorf("Expected the challenge nonce %s, got: %s", challengeNonce, payload.Payload.Authorization.Nonce)
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements); err != nil {
+		t.Errorf("Expected the payment to verify, got: %v", err)
+	}
+
+	// An explicit nonce takes precedence
+	keyed, err := exactevm.CreatePayment(signer, requirements, exactevm.WithIdempotencyKey("order-42"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if nonce := exactevm.NonceFromKey("order-42"); keyed.Payload.Authorization.Nonce != hexutil.Encode(nonce[:]) {
+		t.Errorf("Expected nonce derived from the key, got: %s", keyed.Payload.Authorization.Nonce)
+	}
+
+	invalid := newTestRequirements(t, `{"name": "Bridged USDC", "version": "1", "challengeNonce": "0x1234"}`)
+	if _, err := exactevm.CreatePayment(signer, invalid); err == nil {
+		t.Error("Expected an error for an invalid challenge nonce")
+	}
+}
+
+func TestCreatePaymentMaxValidityWindow(t *testing.T) {
+	clock := testclock.New(time.Unix(1745323800, 0))
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+	window := func(payload *types.PaymentPayload) int64 {
+		validAfter, _ := strconv.ParseInt(payload.Payload.Authorization.ValidAfter, 10, 64)
+		validBefore, _ := strconv.ParseInt(payload.Payload.Authorization.ValidBefore, 10, 64)
+		return validBefore - validAfter
+	}
+
+	// A 60 second timeout plus the 60 second backdating fits the default window
+	payload, err := exactevm.CreatePayment(signer, requirements, exactevm.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if window(payload) != 120 {
+		t.Errorf("Expected a 120 second window, got: %d", window(payload))
+	}
+
+	// A day long timeout is clamped to the default window
+	requirements.MaxTimeoutSeconds = 86400
+	payload, err = exactevm.CreatePayment(signer, requirements, exactevm.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if window(payload) != int64(exactevm.DefaultMaxValidityWindow/time.Second) {
+		t.Errorf("Expected the default window, got: %d", window(payload))
+	}
+
+	payload, err = exactevm.CreatePayment(signer, requirements, exactevm.WithClock(clock), exactevm.WithMaxValidityWindow(5*time.Minute))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if window(payload) != 300 {
+		t.Errorf("Expected a 300 second window, got: %d", window(payload))
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements, exactevm.WithClock(clock)); err != nil {
+		t.Errorf("Expected the clamped payment to verify, got: %v", err)
+	}
+
+	// A window shorter than the backdating would leave no time to pay
+	if _, err := exactevm.CreatePayment(signer, requirements, exactevm.WithMaxValidityWindow(30*time.Second)); err == nil {
+		t.Error("Expected error for a window shorter than the backdating, got err == nil")
+	}
+}
+
+func TestCreatePaymentClockSkewMargin(t *testing.T) {
+	clock := testclock.New(time.Unix(1745323800, 0))
+	requirements := newTestRequirements(t, "")
+
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), requirements, exactevm.WithClock(clock), exactevm.WithClockSkewMargin(30*time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	authorization := payload.Payload.Authorization
+	if authorization.ValidAfter != "1745323710" {
+		t.Errorf("Expected validAfter backdated by 90 seconds, got: %s", authorization.ValidAfter)
+	}
+	if authorization.ValidBefore != "1745323890" {
+		t.Errorf("Expected validBefore extended by 30 seconds, got: %s", authorization.ValidBefore)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a negative margin to panic")
+		}
+	}()
+	exactevm.WithClockSkewMargin(-time.Second)
+}
+
+// TestCreatePaymentGolden creates a payment from a fixed key, clock and random source and compares it byte for byte
+func TestCreatePaymentGolden(t *testing.T) {
+	signer, err := exactevm.SignerFromHexKey("0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	clock := testclock.New(time.Unix(1745323800, 0))
+	create := func() []byte {
+		random := bytes.NewReader(bytes.Repeat([]byte{0x42}, 32))
+		payload, err := exactevm.CreatePayment(signer, newTestRequirements(t, ""), exactevm.WithClock(clock), exactevm.WithRand(random))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		encoded, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		return append(encoded, '\n')
+	}
+
+	golden, err := os.ReadFile("testdata/exact_payment.json")
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if actual := create(); !bytes.Equal(actual, golden) {
+		t.Errorf("Expected payload to match the golden file, got: %s", actual)
+	}
+	if !bytes.Equal(create(), create()) {
+		t.Error("Expected identical payloads from identical inputs")
+	}
+
+	// A source running dry fails payment creation instead of producing a short nonce
+	_, err = exactevm.CreatePayment(signer, newTestRequirements(t, ""), exactevm.WithRand(bytes.NewReader(make([]byte, 16))))
+	if err == nil {
+		t.Error("Expected error for an exhausted random source, got err == nil")
+	}
+}
+
+func TestPaymentEncodingRoundTrip(t *testing.T) {
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal([]byte(testRequirementsJSON), &requirements); err != nil {
+		t.Fatalf("Failed to unmarshal requirements: %v", err)
+	}
+
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), &requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.X402Version != types.X402Version {
+		t.Errorf("Expected x402Version %d, got: %d", types.X402Version, payload.X402Version)
+	}
+
+	encoded, err := payload.EncodeToBase64String()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	decoded, err := types.DecodePaymentPayloadFromBase64(encoded)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if decoded.Payload.Signature != payload.Payload.Signature {
+		t.Errorf("Expected signature to round-trip")
+	}
+}
+
+func TestDecodeUnsupportedVersion(t *testing.T) {
+	payload := &types.PaymentPayload{X402Version: types.X402Version + 1, Scheme: exactevm.Scheme}
+	encoded, err := payload.EncodeToBase64String()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_, err = types.DecodePaymentPayloadFromBase64(encoded)
+	if !errors.Is(err, types.ErrUnsupportedX402Version) {
+		t.Errorf("Expected ErrUnsupportedX402Version, got: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package exactevm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrNoDecimals is returned by FetchDecimals for tokens that don't implement decimals()
+var ErrNoDecimals = errors.New("token does not implement decimals()")
+
+// decimalsSelector is the selector of the ERC-20 decimals() function
+var decimalsSelector = []byte{0x31, 0x3c, 0xe5, 0x67}
+
+// fetchedDecimals caches the decimals fetched over RPC by network and lower case token address
+var fetchedDecimals sync.Map
+
+// FetchDecimals returns the decimals of a token, e.g. to price in it with types.ParseAmount.
+// Known tokens are answered from the asset registry. Others are asked for their ERC-20 decimals()
+// with caller (e.g. an *ethclient.Client), and the answer is cached for the life of the process,
+// so repeated pricing of a token makes one RPC call. Tokens that revert or return no decimals fail with ErrNoDecimals.
+func FetchDecimals(ctx context.Context, caller ethereum.ContractCaller, token string, network string) (uint8, error) {
+	if asset, ok := types.LookupAsset(network, token); ok {
+		return asset.Decimals, nil
+	}
+	if !common.IsHexAddress(token) {
+		return 0, fmt.Errorf("invalid token address: %s", token)
+	}
+
+	key := network + ":" + strings.ToLower(token)
+	if decimals, ok := fetchedDecimals.Load(key); ok {
+		return decimals.(uint8), nil
+	}
+	if caller == nil {
+		return 0, fmt.Errorf("fetching the decimals of token %s requires an RPC contract caller", token)
+	}
+
+	address := common.HexToAddress(token)
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &address, Data: decimalsSelector}, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, err
+		}
+		// A reverted call surfaces as an error, which also covers addresses without code on some nodes
+		return 0, fmt.Errorf("%w: %s: %v", ErrNoDecimals, token, err)
+	}
+	if len(result) != 32 {
+		return 0, fmt.Errorf("%w: %s returned %d bytes", ErrNoDecimals, token, len(result))
+	}
+	value := new(big.Int).SetBytes(result)
+	if !value.IsUint64() || value.Uint64() > 255 {
+		return 0, fmt.Errorf("%w: %s returned decimals %s", ErrNoDecimals, token, value)
+	}
+
+	decimals := uint8(value.Uint64())
+	fetchedDecimals.Store(key, decimals)
+
+	return decimals, nil
+}
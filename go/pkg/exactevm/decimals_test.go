@@ -0,0 +1,64 @@
+package exactevm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// fakeToken answers decimals() calls with result, or err if it is set
+type fakeToken struct {
+	result []byte
+	err    error
+	calls  int
+}
+
+func (f *fakeToken) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	f.calls++
+	return f.result, f.err
+}
+
+func TestFetchDecimals(t *testing.T) {
+	// Registry tokens don't need RPC
+	usdc := types.USDCAssets[types.NetworkBase].Address
+	decimals, err := exactevm.FetchDecimals(context.Background(), nil, usdc, types.NetworkBase)
+	if err != nil || decimals != 6 {
+		t.Errorf("Expected 6 decimals for USDC, got: %d (%v)", decimals, err)
+	}
+
+	token := &fakeToken{result: common.LeftPadBytes([]byte{18}, 32)}
+	address := "0x00000000000000000000000000000000000de018"
+	for i := 0; i < 2; i++ {
+		decimals, err := exactevm.FetchDecimals(context.Background(), token, address, types.NetworkBase)
+		if err != nil || decimals != 18 {
+			t.Errorf("Expected 18 decimals, got: %d (%v)", decimals, err)
+		}
+	}
+	if token.calls != 1 {
+		t.Errorf("Expected the decimals to be fetched once, got: %d calls", token.calls)
+	}
+}
+
+func TestFetchDecimalsNotImplemented(t *testing.T) {
+	testCases := map[string]*fakeToken{
+		"reverts":      {err: errors.New("execution reverted")},
+		"empty result": {},
+		"out of range": {result: common.LeftPadBytes([]byte{1, 0}, 32)},
+	}
+
+	for name, token := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := exactevm.FetchDecimals(context.Background(), token, "0x00000000000000000000000000000000000de000", types.NetworkBase)
+			if !errors.Is(err, exactevm.ErrNoDecimals) {
+				t.Errorf("Expected ErrNoDecimals, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package exactevm
+
+import (
+	"math/big"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// SettlementCostEstimator estimates what settling a payment costs the server, in atomic units of the
+// requirements' asset, e.g. the gas of settlements it submits or its facilitator's charges.
+// A paymentclient.FeeEstimator has the same method, so the same estimator can serve both sides.
+type SettlementCostEstimator interface {
+	EstimateFee(requirements *types.PaymentRequirements) (*big.Int, error)
+}
+
+// IsEconomical reports whether maxAmountRequired exceeds the cost of settling it estimated by estimator
+// by at least minProfit, in atomic units of the asset. A server can use it to serve dust payments for free
+// or refuse them instead of settling. The relayer fee advertised in extra is paid by the payer on top of
+// maxAmountRequired, so it doesn't reduce the profit. When no estimate is available, because estimator
+// is nil or fails, the payment is assumed to be economical.
+func IsEconomical(requirements *types.PaymentRequirements, minProfit string, estimator SettlementCostEstimator) (bool, error) {
+	threshold, err := parseUint256("minProfit", minProfit)
+	if err != nil {
+		return false, err
+	}
+	amount, err := parseUint256("maxAmountRequired", requirements.MaxAmountRequired)
+	if err != nil {
+		return false, err
+	}
+
+	if estimator == nil {
+		return true, nil
+	}
+	cost, err := estimator.EstimateFee(requirements)
+	if err != nil || cost == nil || cost.Sign() < 0 {
+		return true, nil
+	}
+
+	profit := new(big.Int).Sub(amount, cost)
+	return profit.Cmp(threshold) >= 0, nil
+}
@@ -0,0 +1,57 @@
+package exactevm_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// costEstimator estimates a fixed settlement cost, or fails if cost is nil
+type costEstimator struct {
+	cost *big.Int
+}
+
+func (e costEstimator) EstimateFee(requirements *types.PaymentRequirements) (*big.Int, error) {
+	if e.cost == nil {
+		return nil, errors.New("no estimate")
+	}
+	return e.cost, nil
+}
+
+func TestIsEconomical(t *testing.T) {
+	testCases := []struct {
+		name      string
+		extra     string
+		estimator exactevm.SettlementCostEstimator
+		minProfit string
+		expected  bool
+	}{
+		{"no estimator", "", nil, "1000000", true},
+		{"estimate unavailable", "", costEstimator{}, "1000000", true},
+		{"profit above threshold", "", costEstimator{big.NewInt(2000)}, "5000", true},
+		{"profit at threshold", "", costEstimator{big.NewInt(5000)}, "5000", true},
+		{"profit below threshold", "", costEstimator{big.NewInt(6000)}, "5000", false},
+		{"cost exceeds amount", "", costEstimator{big.NewInt(20000)}, "0", false},
+		// The relayer fee is paid by the payer on top of the amount, so the merchant still receives all of it
+		{"payer-paid fee", `{"name":"USDC","version":"2","fee":"9000"}`, costEstimator{big.NewInt(2000)}, "5000", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			economical, err := exactevm.IsEconomical(newTestRequirements(t, tc.extra), tc.minProfit, tc.estimator)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if economical != tc.expected {
+				t.Errorf("Expected %v, got: %v", tc.expected, economical)
+			}
+		})
+	}
+
+	if _, err := exactevm.IsEconomical(newTestRequirements(t, ""), "-1", nil); err == nil {
+		t.Error("Expected error for invalid minProfit, got err == nil")
+	}
+}
@@ -0,0 +1,150 @@
+package exactevm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+var (
+	// eip1271MagicValue is the isValidSignature(bytes32,bytes) selector, returned by ERC-1271 wallets for a valid signature
+	eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+	safeDomainTypeHash  = crypto.Keccak256Hash([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	safeMessageTypeHash = crypto.Keccak256Hash([]byte("SafeMessage(bytes message)"))
+)
+
+// SafeMessageDigest returns the digest the owners of a Safe sign so that the Safe's ERC-1271
+// isValidSignature accepts their signatures for hash: the EIP-712 hash of SafeMessage(abi.encode(hash))
+// under the Safe's domain
+func SafeMessageDigest(safe common.Address, chainID *big.Int, hash [32]byte) [32]byte {
+	domainSeparator := crypto.Keccak256Hash(
+		safeDomainTypeHash.Bytes(),
+		common.LeftPadBytes(chainID.Bytes(), 32),
+		common.LeftPadBytes(safe.Bytes(), 32),
+	)
+	structHash := crypto.Keccak256Hash(safeMessageTypeHash.Bytes(), crypto.Keccak256(hash[:]))
+
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator.Bytes(), structHash.Bytes())
+}
+
+// CombineSignatures signs the digest with each signer and concatenates the 65-byte signatures in ascending
+// signer address order with V as 27/28, the format Safe expects for owner ECDSA signatures
+func CombineSignatures(digest [32]byte, signers []Signer) ([]byte, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+
+	sorted := slices.Clone(signers)
+	slices.SortFunc(sorted, func(a, b Signer) int {
+		return bytes.Compare(a.Address().Bytes(), b.Address().Bytes())
+	})
+
+	combined := make([]byte, 0, len(sorted)*crypto.SignatureLength)
+	for i, signer := range sorted {
+		if i > 0 && signer.Address() == sorted[i-1].Address() {
+			return nil, fmt.Errorf("duplicate signer %s", signer.Address().Hex())
+		}
+
+		signature, err := signer.SignDigest(digest)
+		if err != nil {
+			return nil, err
+		}
+		if len(signature) != crypto.SignatureLength {
+			return nil, fmt.Errorf("signer %s returned a %d-byte signature", signer.Address().Hex(), len(signature))
+		}
+		// Safe reads V values of 0 and 1 as contract and approved-hash signatures
+		if signature[crypto.RecoveryIDOffset] < 27 {
+			signature = append([]byte(nil), signature...)
+			signature[crypto.RecoveryIDOffset] += 27
+		}
+		combined = append(combined, signature...)
+	}
+
+	return combined, nil
+}
+
+// CreateMultisigPayment prepares a payment from a Safe wallet and signs it with each of the owner signers.
+// The owners sign the SafeMessageDigest of the authorization's EIP-712 digest, so the Safe's ERC-1271
+// isValidSignature, which the token calls when settling, accepts the combined signature once enough owners signed.
+func CreateMultisigPayment(wallet common.Address, signers []Signer, requirements *types.PaymentRequirements, opts ...Options) (*types.PaymentPayload, error) {
+	if requirements.Scheme != Scheme {
+		return nil, fmt.Errorf("unsupported scheme: %s", requirements.Scheme)
+	}
+
+	chainID, err := types.GetChainID(requirements.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := PreparePayment(wallet, requirements, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := ExactSigningDigest(requirements, payload.Payload.Authorization)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := CombineSignatures(SafeMessageDigest(wallet, big.NewInt(chainID), digest), signers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign authorization: %w", err)
+	}
+	payload.Payload.Signature = hexutil.Encode(signature)
+
+	return payload, nil
+}
+
+// VerifyMultisigPayment is VerifyPayment for a contract wallet payer such as a multisig Safe.
+// Instead of recovering the from address, it asks the wallet through its ERC-1271 isValidSignature,
+// called with caller (e.g. an *ethclient.Client), so the wallet's on-chain owners and threshold decide.
+// On success it returns the wallet address.
+func VerifyMultisigPayment(ctx context.Context, caller ethereum.ContractCaller, payload *types.PaymentPayload, requirements *types.PaymentRequirements, opts ...Options) (common.Address, error) {
+	if caller == nil {
+		return common.Address{}, fmt.Errorf("verifying a contract wallet signature requires an RPC contract caller")
+	}
+
+	digest, err := verifyAuthorization(payload, requirements, opts)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		return common.Address{}, newVerificationError(ReasonInvalidSignature, "invalid signature encoding: %v", err)
+	}
+
+	wallet := common.HexToAddress(payload.Payload.Authorization.From)
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &wallet, Data: encodeIsValidSignature(digest, signature)}, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to call isValidSignature on %s: %w", wallet.Hex(), err)
+	}
+	if len(result) < len(eip1271MagicValue) || !bytes.Equal(result[:len(eip1271MagicValue)], eip1271MagicValue) {
+		return common.Address{}, newVerificationError(ReasonInvalidSignature, "wallet %s did not accept the signature", wallet.Hex())
+	}
+
+	return wallet, nil
+}
+
+// encodeIsValidSignature ABI encodes a call to isValidSignature(bytes32 hash, bytes signature)
+func encodeIsValidSignature(hash [32]byte, signature []byte) []byte {
+	padded := (len(signature) + 31) / 32 * 32
+
+	data := make([]byte, 0, 4+32*3+padded)
+	data = append(data, eip1271MagicValue...)
+	data = append(data, hash[:]...)
+	data = append(data, common.LeftPadBytes(big.NewInt(64).Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(signature))).Bytes(), 32)...)
+	data = append(data, common.RightPadBytes(signature, padded)...)
+
+	return data
+}
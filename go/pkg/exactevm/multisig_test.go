@@ -0,0 +1,191 @@
+package exactevm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+)
+
+const isValidSignatureABI = `[{"name":"isValidSignature","type":"function","stateMutability":"view",
+	"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],
+	"outputs":[{"name":"magicValue","type":"bytes4"}]}]`
+
+// fakeSafe answers isValidSignature calls the way a Safe does: the signatures must be from distinct owners
+// in ascending order over the SafeMessage digest of the hash, and at least threshold of them
+type fakeSafe struct {
+	t         *testing.T
+	address   common.Address
+	chainID   *big.Int
+	owners    []common.Address
+	threshold int
+}
+
+func (s *fakeSafe) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(isValidSignatureABI))
+	if err != nil {
+		s.t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	if call.To == nil || *call.To != s.address {
+		s.t.Fatalf("Expected a call to the Safe, got: %v", call.To)
+	}
+	method, err := parsed.MethodById(call.Data)
+	if err != nil || method.Name != "isValidSignature" {
+		s.t.Fatalf("Expected an isValidSignature call, got: %x", call.Data)
+	}
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		s.t.Fatalf("Failed to unpack call: %v", err)
+	}
+	hash, signatures := args[0].([32]byte), args[1].([]byte)
+
+	digest := exactevm.SafeMessageDigest(s.address, s.chainID, hash)
+	var previous common.Address
+	signed := 0
+	for offset := 0; offset+crypto.SignatureLength <= len(signatures); offset += crypto.SignatureLength {
+		signature := slices.Clone(signatures[offset : offset+crypto.SignatureLength])
+		if signature[crypto.RecoveryIDOffset] < 27 {
+			return make([]byte, 32), nil
+		}
+		signature[crypto.RecoveryIDOffset] -= 27
+		publicKey, err := crypto.SigToPub(digest[:], signature)
+		if err != nil {
+			return make([]byte, 32), nil
+		}
+		owner := crypto.PubkeyToAddress(*publicKey)
+		if !slices.Contains(s.owners, owner) || bytes.Compare(owner.Bytes(), previous.Bytes()) <= 0 {
+			return make([]byte, 32), nil
+		}
+		previous = owner
+		signed++
+	}
+	if signed < s.threshold {
+		return make([]byte, 32), nil
+	}
+
+	return common.RightPadBytes([]byte{0x16, 0x26, 0xba, 0x7e}, 32), nil
+}
+
+func newFakeSafe(t *testing.T, owners []exactevm.Signer, threshold int) *fakeSafe {
+	safe := &fakeSafe{
+		t:         t,
+		address:   common.HexToAddress("0x5afe5afe5afe5afe5afe5afe5afe5afe5afe5afe"),
+		chainID:   big.NewInt(84532),
+		threshold: threshold,
+	}
+	for _, owner := range owners {
+		safe.owners = append(safe.owners, owner.Address())
+	}
+
+	return safe
+}
+
+func TestMultisigPayment(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	owners := []exactevm.Signer{middlewaretest.NewTestSigner(t), middlewaretest.NewTestSigner(t), middlewaretest.NewTestSigner(t)}
+	safe := newFakeSafe(t, owners, 2)
+
+	payload, err := exactevm.CreateMultisigPayment(safe.address, owners[:2], requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	payer, err := exactevm.VerifyMultisigPayment(context.Background(), safe, payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payer != safe.address {
+		t.Errorf("Expected payer %s, got: %s", safe.address.Hex(), payer.Hex())
+	}
+
+	// A multisig blob cannot be verified as a single signature
+	_, err = exactevm.VerifyPayment(payload, requirements)
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonInvalidSignature {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonInvalidSignature, err)
+	}
+}
+
+func TestMultisigPaymentRejected(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	owner, other, outsider := middlewaretest.NewTestSigner(t), middlewaretest.NewTestSigner(t), middlewaretest.NewTestSigner(t)
+	safe := newFakeSafe(t, []exactevm.Signer{owner, other}, 2)
+
+	testCases := map[string][]exactevm.Signer{
+		"one of two owners": {owner},
+		"outsider":          {owner, outsider},
+	}
+	for name, signers := range testCases {
+		t.Run(name, func(t *testing.T) {
+			payload, err := exactevm.CreateMultisigPayment(safe.address, signers, requirements)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			_, err = exactevm.VerifyMultisigPayment(context.Background(), safe, payload, requirements)
+			var verificationErr *exactevm.VerificationError
+			if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonInvalidSignature {
+				t.Errorf("Expected %s, got: %v", exactevm.ReasonInvalidSignature, err)
+			}
+		})
+	}
+
+	// Without an RPC caller the wallet can't be asked
+	payload, err := exactevm.CreateMultisigPayment(safe.address, []exactevm.Signer{owner, other}, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := exactevm.VerifyMultisigPayment(context.Background(), nil, payload, requirements); err == nil {
+		t.Error("Expected an error without a contract caller")
+	}
+}
+
+// TestSafeMessageDigest checks the digest against go-ethereum's EIP-712 implementation of the SafeMessage typed data
+func TestSafeMessageDigest(t *testing.T) {
+	safe := common.HexToAddress("0x5afe5afe5afe5afe5afe5afe5afe5afe5afe5afe")
+	hash := crypto.Keccak256Hash([]byte("authorization"))
+
+	typedDataJSON := `{
+		"types": {
+			"EIP712Domain": [{"name": "chainId", "type": "uint256"}, {"name": "verifyingContract", "type": "address"}],
+			"SafeMessage": [{"name": "message", "type": "bytes"}]
+		},
+		"primaryType": "SafeMessage",
+		"domain": {"chainId": 84532, "verifyingContract": "` + safe.Hex() + `"},
+		"message": {"message": "` + hexutil.Encode(hash[:]) + `"}
+	}`
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal([]byte(typedDataJSON), &typedData); err != nil {
+		t.Fatalf("Failed to unmarshal typed data: %v", err)
+	}
+	expected, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("Failed to hash typed data: %v", err)
+	}
+
+	digest := exactevm.SafeMessageDigest(safe, big.NewInt(84532), hash)
+	if !bytes.Equal(expected, digest[:]) {
+		t.Errorf("Expected digest %x, got: %x", expected, digest)
+	}
+}
+
+func TestCombineSignaturesDuplicateSigner(t *testing.T) {
+	signer := middlewaretest.NewTestSigner(t)
+
+	if _, err := exactevm.CombineSignatures([32]byte{}, []exactevm.Signer{signer, signer}); err == nil {
+		t.Error("Expected error for a duplicate signer, got err == nil")
+	}
+}
@@ -0,0 +1,251 @@
+package exactevm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+var (
+	eip712DomainTypeHash = crypto.Keccak256Hash([]byte(
+		"EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)",
+	))
+	transferWithAuthorizationTypeHash = crypto.Keccak256Hash([]byte(
+		"TransferWithAuthorization(address from,address to,uint256 value,uint256 validAfter,uint256 validBefore,bytes32 nonce)",
+	))
+)
+
+// Domain represents the EIP-712 domain of an ERC-3009 token contract
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// DomainForRequirements builds the EIP-712 domain for the asset of the payment requirements.
+// The domain name and version are read from the requirements' Extra field, falling back to
+// the registered domain of a known asset such as USDC when Extra doesn't set them.
+// If Extra sets the verifying contract, the name, version and verifying contract must all be set,
+// and are used verbatim without consulting the registry. The verifying contract must then be the asset,
+// so requirements can't have payments signed for a contract other than the one they charge in.
+func DomainForRequirements(requirements *types.PaymentRequirements) (*Domain, error) {
+	chainID, err := types.GetChainID(requirements.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	if !common.IsHexAddress(requirements.Asset) {
+		return nil, fmt.Errorf("invalid asset address: %s", requirements.Asset)
+	}
+
+	var extra types.ExactEvmExtra
+	if _, err := requirements.DecodeExtra(&extra); err != nil {
+		return nil, err
+	}
+	if extra.VerifyingContract != "" {
+		if extra.Name == "" || extra.Version == "" {
+			return nil, fmt.Errorf("payment requirements extra sets the EIP-712 verifying contract without the domain name and version")
+		}
+		if !common.IsHexAddress(extra.VerifyingContract) {
+			return nil, fmt.Errorf("invalid EIP-712 verifying contract: %s", extra.VerifyingContract)
+		}
+		if common.HexToAddress(extra.VerifyingContract) != common.HexToAddress(requirements.Asset) {
+			return nil, fmt.Errorf("EIP-712 verifying contract %s is not the asset %s", extra.VerifyingContract, requirements.Asset)
+		}
+
+		return &Domain{
+			Name:              extra.Name,
+			Version:           extra.Version,
+			ChainID:           big.NewInt(chainID),
+			VerifyingContract: common.HexToAddress(extra.VerifyingContract),
+		}, nil
+	}
+	if asset, ok := types.LookupAsset(requirements.Network, requirements.Asset); ok {
+		if extra.Name == "" {
+			extra.Name = asset.EIP712Name
+		}
+		if extra.Version == "" {
+			extra.Version = asset.EIP712Version
+		}
+	}
+	if extra.Name == "" || extra.Version == "" {
+		return nil, fmt.Errorf("payment requirements extra is missing the EIP-712 domain name or version")
+	}
+
+	return &Domain{
+		Name:              extra.Name,
+		Version:           extra.Version,
+		ChainID:           big.NewInt(chainID),
+		VerifyingContract: common.HexToAddress(requirements.Asset),
+	}, nil
+}
+
+// Separator returns the EIP-712 domain separator
+func (d *Domain) Separator() common.Hash {
+	return crypto.Keccak256Hash(
+		eip712DomainTypeHash.Bytes(),
+		crypto.Keccak256([]byte(d.Name)),
+		crypto.Keccak256([]byte(d.Version)),
+		common.LeftPadBytes(d.ChainID.Bytes(), 32),
+		common.LeftPadBytes(d.VerifyingContract.Bytes(), 32),
+	)
+}
+
+// SignAuthorization signs an ERC-3009 TransferWithAuthorization message for the payment requirements
+// and returns the hex encoded signature, with V as 27 or 28 whichever form the signer returns
+func SignAuthorization(signer Signer, authorization *types.ExactEvmPayloadAuthorization, requirements *types.PaymentRequirements) (string, error) {
+	digest, err := ExactSigningDigest(requirements, authorization)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signer.SignDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	if len(signature) != crypto.SignatureLength {
+		return "", fmt.Errorf("invalid signature length: expected %d bytes, got %d", crypto.SignatureLength, len(signature))
+	}
+
+	// Signers may return V as 0/1; facilitators and tokens expect the canonical 27/28 of Ethereum signatures
+	return hexutil.Encode(normalizeRecoveryID(signature)), nil
+}
+
+// ExactSigningDigest returns the 32-byte EIP-712 digest of the authorization under the requirements' domain.
+// It lets external signers (hardware wallets, MPC) sign the payment; see AttachSignature.
+func ExactSigningDigest(requirements *types.PaymentRequirements, authorization *types.ExactEvmPayloadAuthorization) ([32]byte, error) {
+	domain, err := DomainForRequirements(requirements)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return domain.digest(authorization)
+}
+
+// digest returns the EIP-712 digest of the authorization under the domain
+func (d *Domain) digest(authorization *types.ExactEvmPayloadAuthorization) ([32]byte, error) {
+	structHash, err := hashAuthorization(authorization)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return crypto.Keccak256Hash(
+		[]byte("\x19\x01"),
+		d.Separator().Bytes(),
+		structHash.Bytes(),
+	), nil
+}
+
+// AttachSignature returns a copy of the unsigned payload carrying the given 65-byte [R || S || V] signature,
+// or the concatenated signatures of a multisig payer (see CombineSignatures)
+func AttachSignature(payload *types.PaymentPayload, signature []byte) (*types.PaymentPayload, error) {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return nil, fmt.Errorf("payment payload is missing its authorization")
+	}
+	if len(signature) == 0 || len(signature)%crypto.SignatureLength != 0 {
+		return nil, fmt.Errorf("invalid signature length: expected a multiple of %d bytes, got %d", crypto.SignatureLength, len(signature))
+	}
+
+	signed := *payload
+	signed.Payload = &types.ExactEvmPayload{
+		Signature:     hexutil.Encode(signature),
+		Authorization: payload.Payload.Authorization,
+	}
+
+	return &signed, nil
+}
+
+// RecoverAddress recovers the address that produced the 65-byte signature over the digest.
+// V may be encoded as 0/1, as returned by some wallets and signing libraries, or 27/28 as in Ethereum
+// signatures; both recover the same address. Any other V is rejected.
+func RecoverAddress(digest [32]byte, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length: expected %d bytes, got %d", crypto.SignatureLength, len(signature))
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	switch v := sig[crypto.RecoveryIDOffset]; v {
+	case 0, 1:
+	case 27, 28:
+		sig[crypto.RecoveryIDOffset] -= 27
+	default:
+		return common.Address{}, fmt.Errorf("invalid signature recovery ID: V must be 0, 1, 27 or 28, got %d", v)
+	}
+
+	publicKey, err := crypto.SigToPub(digest[:], sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// normalizeRecoveryID returns a copy of a 65-byte signature with V as 27/28, leaving other signatures unchanged
+func normalizeRecoveryID(signature []byte) []byte {
+	if len(signature) != crypto.SignatureLength || signature[crypto.RecoveryIDOffset] >= 27 {
+		return signature
+	}
+
+	normalized := append([]byte(nil), signature...)
+	normalized[crypto.RecoveryIDOffset] += 27
+	return normalized
+}
+
+// hashAuthorization returns the EIP-712 struct hash of a TransferWithAuthorization message
+func hashAuthorization(authorization *types.ExactEvmPayloadAuthorization) (common.Hash, error) {
+	if authorization == nil {
+		return common.Hash{}, fmt.Errorf("authorization is required")
+	}
+
+	if !common.IsHexAddress(authorization.From) {
+		return common.Hash{}, fmt.Errorf("invalid from address: %s", authorization.From)
+	}
+	if !common.IsHexAddress(authorization.To) {
+		return common.Hash{}, fmt.Errorf("invalid to address: %s", authorization.To)
+	}
+
+	value, err := parseUint256("value", authorization.Value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	validAfter, err := parseUint256("validAfter", authorization.ValidAfter)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	validBefore, err := parseUint256("validBefore", authorization.ValidBefore)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	nonce, err := hexutil.Decode(authorization.Nonce)
+	if err != nil || len(nonce) != 32 {
+		return common.Hash{}, fmt.Errorf("invalid nonce: must be 32 hex encoded bytes")
+	}
+
+	return crypto.Keccak256Hash(
+		transferWithAuthorizationTypeHash.Bytes(),
+		common.LeftPadBytes(common.HexToAddress(authorization.From).Bytes(), 32),
+		common.LeftPadBytes(common.HexToAddress(authorization.To).Bytes(), 32),
+		common.LeftPadBytes(value.Bytes(), 32),
+		common.LeftPadBytes(validAfter.Bytes(), 32),
+		common.LeftPadBytes(validBefore.Bytes(), 32),
+		nonce,
+	), nil
+}
+
+// parseUint256 parses a base 10 string into a non-negative integer that fits in 256 bits
+func parseUint256(field, value string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		return nil, fmt.Errorf("invalid %s: %q is not a uint256", field, value)
+	}
+
+	return n, nil
+}
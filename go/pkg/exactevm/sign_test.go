@@ -0,0 +1,257 @@
+package exactevm_test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestExactSigningDigestExternalSigner(t *testing.T) {
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal([]byte(testRequirementsJSON), &requirements); err != nil {
+		t.Fatalf("Failed to unmarshal requirements: %v", err)
+	}
+
+	// An external signer only ever sees the digest
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	unsigned, err := exactevm.PreparePayment(from, &requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	digest, err := exactevm.ExactSigningDigest(&requirements, unsigned.Payload.Authorization)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	signature, err := crypto.Sign(digest[:], key)
+	if err != nil {
+		t.Fatalf("Failed to sign digest: %v", err)
+	}
+
+	payload, err := exactevm.AttachSignature(unsigned, signature)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if unsigned.Payload.Signature != "" {
+		t.Errorf("Expected the unsigned payload not to be modified")
+	}
+
+	decoded, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		t.Fatalf("Failed to decode signature: %v", err)
+	}
+	recovered, err := exactevm.RecoverAddress(digest, decoded)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if recovered != common.HexToAddress(payload.Payload.Authorization.From) {
+		t.Errorf("Expected recovered address %s, got: %s", payload.Payload.Authorization.From, recovered.Hex())
+	}
+}
+
+func TestAttachSignatureInvalidLength(t *testing.T) {
+	unsigned, err := exactevm.PreparePayment(common.Address{}, &types.PaymentRequirements{Scheme: exactevm.Scheme, MaxAmountRequired: "10000"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := exactevm.AttachSignature(unsigned, make([]byte, 64)); err == nil {
+		t.Error("Expected error for short signature, got err == nil")
+	}
+}
+
+func TestDomainForRequirementsUSDC(t *testing.T) {
+	tests := []struct {
+		network string
+		name    string
+		version string
+		chainID int64
+	}{
+		{types.NetworkBase, "USD Coin", "2", 8453},
+		{types.NetworkBaseSepolia, "USDC", "2", 84532},
+		{types.NetworkPolygon, "USD Coin", "2", 137},
+		{types.NetworkAvalanche, "USD Coin", "2", 43114},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			requirements := &types.PaymentRequirements{
+				Scheme:  exactevm.Scheme,
+				Network: tt.network,
+				Asset:   types.USDCAssets[tt.network].Address,
+			}
+
+			domain, err := exactevm.DomainForRequirements(requirements)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if domain.Name != tt.name || domain.Version != tt.version {
+				t.Errorf("Expected domain %q version %q, got: %q version %q", tt.name, tt.version, domain.Name, domain.Version)
+			}
+			if domain.ChainID.Int64() != tt.chainID {
+				t.Errorf("Expected chain ID %d, got: %s", tt.chainID, domain.ChainID)
+			}
+			if domain.VerifyingContract != common.HexToAddress(requirements.Asset) {
+				t.Errorf("Expected verifying contract %s, got: %s", requirements.Asset, domain.VerifyingContract.Hex())
+			}
+		})
+	}
+}
+
+func TestDomainForRequirementsExtraOverride(t *testing.T) {
+	requirements := &types.PaymentRequirements{
+		Scheme:  exactevm.Scheme,
+		Network: types.NetworkPolygon,
+		Asset:   types.USDCAssets[types.NetworkPolygon].Address,
+	}
+	if err := requirements.SetExtra(types.ExactEvmExtra{Version: "1"}); err != nil {
+		t.Fatalf("Failed to set extra: %v", err)
+	}
+
+	domain, err := exactevm.DomainForRequirements(requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if domain.Name != "USD Coin" || domain.Version != "1" {
+		t.Errorf("Expected the extra version to override the registry, got: %q version %q", domain.Name, domain.Version)
+	}
+}
+
+func TestDomainForRequirementsExplicitDomain(t *testing.T) {
+	bridged := types.ExactEvmExtra{Name: "Bridged USDC", Version: "1", VerifyingContract: "0x00000000000000000000000000000000000b1d9e"}
+	requirements := newTestRequirements(t, "")
+	requirements.Asset = bridged.VerifyingContract
+	if err := requirements.SetExtra(bridged); err != nil {
+		t.Fatalf("Failed to set extra: %v", err)
+	}
+
+	domain, err := exactevm.DomainForRequirements(requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if domain.Name != bridged.Name || domain.Version != bridged.Version || domain.VerifyingContract != common.HexToAddress(bridged.VerifyingContract) {
+		t.Errorf("Expected the explicit domain to be used verbatim, got: %+v", domain)
+	}
+
+	// Payments signed under the explicit domain verify locally, and not under the registry domain
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if _, err := exactevm.VerifyPayment(payload, newTestRequirements(t, "")); err == nil {
+		t.Error("Expected the payment not to verify under the registry domain")
+	}
+
+	incomplete := map[string]types.ExactEvmExtra{
+		"missing name":    {Version: "1", VerifyingContract: bridged.VerifyingContract},
+		"missing version": {Name: "Bridged USDC", VerifyingContract: bridged.VerifyingContract},
+		"invalid address": {Name: "Bridged USDC", Version: "1", VerifyingContract: "0xnot-an-address"},
+		"not the asset":   {Name: "Bridged USDC", Version: "1", VerifyingContract: "0x036CbD53842c5426634e7929541eC2318f3dCF7e"},
+	}
+	for name, extra := range incomplete {
+		t.Run(name, func(t *testing.T) {
+			if err := requirements.SetExtra(extra); err != nil {
+				t.Fatalf("Failed to set extra: %v", err)
+			}
+			if _, err := exactevm.DomainForRequirements(requirements); err == nil {
+				t.Error("Expected error for an incomplete or mismatched domain, got err == nil")
+			}
+		})
+	}
+}
+
+// rawSigner signs like crypto.Sign, returning V as 0 or 1
+type rawSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s rawSigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s rawSigner) SignDigest(digest [32]byte) ([]byte, error) {
+	return crypto.Sign(digest[:], s.key)
+}
+
+func TestRecoverAddressRecoveryIDEncodings(t *testing.T) {
+	// Enough signatures to cover both recovery IDs
+	for i := range 16 {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		digest := crypto.Keccak256Hash([]byte{byte(i)})
+		signature, err := crypto.Sign(digest[:], key)
+		if err != nil {
+			t.Fatalf("Failed to sign digest: %v", err)
+		}
+		ethereumSignature := append([]byte(nil), signature...)
+		ethereumSignature[crypto.RecoveryIDOffset] += 27
+
+		for _, sig := range [][]byte{signature, ethereumSignature} {
+			recovered, err := exactevm.RecoverAddress(digest, sig)
+			if err != nil {
+				t.Fatalf("Expected no error for V = %d, got: %v", sig[crypto.RecoveryIDOffset], err)
+			}
+			if recovered != crypto.PubkeyToAddress(key.PublicKey) {
+				t.Errorf("Expected V = %d to recover %s, got: %s", sig[crypto.RecoveryIDOffset], crypto.PubkeyToAddress(key.PublicKey).Hex(), recovered.Hex())
+			}
+		}
+
+		for _, v := range []byte{2, 3, 26, 29, 35, 36} {
+			invalid := append([]byte(nil), signature...)
+			invalid[crypto.RecoveryIDOffset] = v
+			if _, err := exactevm.RecoverAddress(digest, invalid); err == nil {
+				t.Errorf("Expected V = %d to be rejected", v)
+			}
+		}
+	}
+}
+
+func TestSignAuthorizationCanonicalRecoveryID(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	for _, signer := range []exactevm.Signer{rawSigner{key}, exactevm.NewPrivateKeySigner(key)} {
+		for range 8 {
+			payload, err := exactevm.CreatePayment(signer, requirements)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			signature := hexutil.MustDecode(payload.Payload.Signature)
+			if v := signature[crypto.RecoveryIDOffset]; v != 27 && v != 28 {
+				t.Errorf("Expected V to be 27 or 28, got: %d", v)
+			}
+			if _, err := exactevm.VerifyPayment(payload, requirements); err != nil {
+				t.Errorf("Expected the payment to verify, got: %v", err)
+			}
+
+			// The same signature with V as 0/1 verifies too
+			signature[crypto.RecoveryIDOffset] -= 27
+			payload.Payload.Signature = hexutil.Encode(signature)
+			if _, err := exactevm.VerifyPayment(payload, requirements); err != nil {
+				t.Errorf("Expected the payment with V = %d to verify, got: %v", signature[crypto.RecoveryIDOffset], err)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package exactevm
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrWrongPassphrase is returned by SignerFromKeystore when the passphrase does not decrypt the keystore
+var ErrWrongPassphrase = errors.New("wrong keystore passphrase")
+
+// Signer signs EIP-712 digests on behalf of a payer
+type Signer interface {
+	// Address returns the address of the payer
+	Address() common.Address
+	// SignDigest signs a 32-byte digest and returns a 65-byte [R || S || V] signature
+	SignDigest(digest [32]byte) ([]byte, error)
+}
+
+// PrivateKeySigner is a Signer backed by an in-memory secp256k1 private key
+type PrivateKeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewPrivateKeySigner creates a new signer from a secp256k1 private key
+func NewPrivateKeySigner(key *ecdsa.PrivateKey) *PrivateKeySigner {
+	return &PrivateKeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+// SignerFromHexKey creates a signer from a hex encoded secp256k1 private key, with or without a 0x prefix.
+// Errors never include the key.
+func SignerFromHexKey(hexKey string) (*PrivateKeySigner, error) {
+	hexKey = strings.TrimPrefix(strings.TrimSpace(hexKey), "0x")
+
+	keyBytes, err := hex.DecodeString(hexKey)
+	if err != nil || len(keyBytes) != 32 {
+		return nil, fmt.Errorf("invalid private key: expected 32 hex encoded bytes")
+	}
+	key, err := crypto.ToECDSA(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: not a valid secp256k1 key")
+	}
+
+	return NewPrivateKeySigner(key), nil
+}
+
+// SignerFromKeystore creates a signer from an encrypted go-ethereum (Web3 Secret Storage) keystore file.
+// It returns an error wrapping ErrWrongPassphrase if the passphrase is incorrect.
+func SignerFromKeystore(path, passphrase string) (*PrivateKeySigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if errors.Is(err, keystore.ErrDecrypt) {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, ErrWrongPassphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, err)
+	}
+
+	return NewPrivateKeySigner(key.PrivateKey), nil
+}
+
+// Address returns the address derived from the private key
+func (s *PrivateKeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignDigest signs the digest with the private key, returning V as 27 or 28
+func (s *PrivateKeySigner) SignDigest(digest [32]byte) ([]byte, error) {
+	signature, err := crypto.Sign(digest[:], s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign digest: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return signature, nil
+}
@@ -0,0 +1,69 @@
+package exactevm_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+)
+
+const testHexKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func TestSignerFromHexKey(t *testing.T) {
+	key, err := crypto.HexToECDSA(testHexKey)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	want := crypto.PubkeyToAddress(key.PublicKey)
+
+	for _, input := range []string{testHexKey, "0x" + testHexKey, " 0x" + testHexKey + "\n"} {
+		signer, err := exactevm.SignerFromHexKey(input)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if signer.Address() != want {
+			t.Errorf("Expected address %s, got: %s", want.Hex(), signer.Address().Hex())
+		}
+	}
+
+	secret := "zz" + testHexKey[2:]
+	for _, input := range []string{"", secret, testHexKey[:62], strings.Repeat("0", 64)} {
+		_, err := exactevm.SignerFromHexKey(input)
+		if err == nil {
+			t.Errorf("Expected error for invalid key %q, got err == nil", input)
+			continue
+		}
+		if input != "" && strings.Contains(err.Error(), input[2:10]) {
+			t.Errorf("Expected the error not to include the key, got: %v", err)
+		}
+	}
+}
+
+func TestSignerFromKeystore(t *testing.T) {
+	account, err := keystore.StoreKey(t.TempDir(), "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("Failed to create keystore: %v", err)
+	}
+
+	signer, err := exactevm.SignerFromKeystore(account.URL.Path, "correct horse")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if signer.Address() != account.Address {
+		t.Errorf("Expected address %s, got: %s", account.Address.Hex(), signer.Address().Hex())
+	}
+
+	_, err = exactevm.SignerFromKeystore(account.URL.Path, "wrong")
+	if !errors.Is(err, exactevm.ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got: %v", err)
+	}
+
+	if _, err := exactevm.SignerFromKeystore(filepath.Join(t.TempDir(), "missing.json"), ""); err == nil {
+		t.Error("Expected error for a missing keystore, got err == nil")
+	}
+}
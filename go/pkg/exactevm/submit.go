@@ -0,0 +1,220 @@
+package exactevm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// transferWithAuthorizationABI declares both ERC-3009 transferWithAuthorization overloads: with the signature
+// split into v, r and s, and with it as bytes, which USDC v2.2 added for ERC-1271 wallets
+const transferWithAuthorizationABI = `[
+	{"name":"transferWithAuthorization","type":"function","inputs":[
+		{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},
+		{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},
+		{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+	{"name":"transferWithAuthorization","type":"function","inputs":[
+		{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},
+		{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},
+		{"name":"signature","type":"bytes"}]}
+]`
+
+var transferWithAuthorizationMethods = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(transferWithAuthorizationABI))
+	if err != nil {
+		panic(fmt.Sprintf("invalid transferWithAuthorization ABI: %v", err))
+	}
+	return parsed
+}()
+
+// RPCClient broadcasts signed transactions, e.g. an *ethclient.Client
+type RPCClient interface {
+	SendTransaction(ctx context.Context, tx *gethtypes.Transaction) error
+}
+
+// SubmitOptions bounds the gas a prepared settlement transaction may spend from the signer's balance.
+// All bounds must be set.
+type SubmitOptions struct {
+	// MaxGas is the highest gas limit signed
+	MaxGas uint64
+	// MaxFeePerGas is the highest fee cap signed, in wei per gas
+	MaxFeePerGas *big.Int
+	// MaxPriorityFeePerGas is the highest tip cap signed, in wei per gas. The tip of an access list
+	// transaction is its whole gas price.
+	MaxPriorityFeePerGas *big.Int
+}
+
+// SubmitSettlement signs a settlement transaction prepared by the facilitator and broadcasts it,
+// for facilitators that prepare settlements without submitting them (see types.SettleResponse.NeedsSubmission).
+// The signer pays the gas, so the prepared transaction is only signed if it is a dynamic fee or access list
+// transaction for the requirements' network, within the gas bounds of opts, that sends no ether and only calls
+// transferWithAuthorization on the requirements' asset with the payment's own authorization and signature.
+// It returns the transaction hash.
+func SubmitSettlement(ctx context.Context, rpc RPCClient, signer Signer, payload *types.PaymentPayload, requirements *types.PaymentRequirements, prepared *types.PreparedTransaction, opts SubmitOptions) (string, error) {
+	if prepared == nil || prepared.Transaction == "" {
+		return "", fmt.Errorf("no prepared transaction to submit")
+	}
+	if opts.MaxGas == 0 || opts.MaxFeePerGas == nil || opts.MaxPriorityFeePerGas == nil {
+		return "", fmt.Errorf("submit options must bound the gas limit, fee cap and tip cap")
+	}
+	if prepared.Network != requirements.Network {
+		return "", fmt.Errorf("prepared transaction is for network %s, not %s", prepared.Network, requirements.Network)
+	}
+
+	chainID, err := types.GetChainID(prepared.Network)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := hexutil.Decode(prepared.Transaction)
+	if err != nil {
+		return "", fmt.Errorf("invalid prepared transaction encoding: %w", err)
+	}
+	var tx gethtypes.Transaction
+	if err := tx.UnmarshalBinary(encoded); err != nil {
+		return "", fmt.Errorf("invalid prepared transaction: %w", err)
+	}
+	// Unsigned legacy transactions carry no chain ID, so they could be replayed on another chain once signed,
+	// and blob and set code transactions spend or delegate more than the settlement needs
+	if tx.Type() != gethtypes.DynamicFeeTxType && tx.Type() != gethtypes.AccessListTxType {
+		return "", fmt.Errorf("prepared transaction must be a dynamic fee or access list transaction, got type %d", tx.Type())
+	}
+	if tx.ChainId().Cmp(big.NewInt(chainID)) != 0 {
+		return "", fmt.Errorf("prepared transaction is for chain ID %s, not network %s", tx.ChainId(), prepared.Network)
+	}
+	if err := checkPreparedGas(&tx, opts); err != nil {
+		return "", err
+	}
+	if err := checkPreparedSettlement(&tx, payload, requirements); err != nil {
+		return "", err
+	}
+
+	txSigner := gethtypes.LatestSignerForChainID(big.NewInt(chainID))
+	signature, err := signer.SignDigest(txSigner.Hash(&tx))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign prepared transaction: %w", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return "", fmt.Errorf("invalid signature length: expected %d bytes, got %d", crypto.SignatureLength, len(signature))
+	}
+	// Transactions carry V as the 0/1 recovery ID
+	signature = append([]byte(nil), signature...)
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+
+	signed, err := tx.WithSignature(txSigner, signature)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign prepared transaction: %w", err)
+	}
+	if sender, err := gethtypes.Sender(txSigner, signed); err != nil || sender != signer.Address() {
+		return "", fmt.Errorf("signed transaction does not recover to the signer %s", signer.Address().Hex())
+	}
+
+	if err := rpc.SendTransaction(ctx, signed); err != nil {
+		return "", fmt.Errorf("failed to submit settlement transaction: %w", err)
+	}
+
+	return signed.Hash().Hex(), nil
+}
+
+// checkPreparedGas checks the prepared transaction can't spend more gas than the bounds of opts
+func checkPreparedGas(tx *gethtypes.Transaction, opts SubmitOptions) error {
+	if tx.Gas() > opts.MaxGas {
+		return fmt.Errorf("prepared transaction gas limit %d exceeds %d", tx.Gas(), opts.MaxGas)
+	}
+	if tx.GasFeeCap().Cmp(opts.MaxFeePerGas) > 0 {
+		return fmt.Errorf("prepared transaction fee cap %s exceeds %s wei", tx.GasFeeCap(), opts.MaxFeePerGas)
+	}
+	if tx.GasTipCap().Cmp(opts.MaxPriorityFeePerGas) > 0 {
+		return fmt.Errorf("prepared transaction tip cap %s exceeds %s wei", tx.GasTipCap(), opts.MaxPriorityFeePerGas)
+	}
+
+	return nil
+}
+
+// checkPreparedSettlement checks the prepared transaction only settles the payment: a call without ether
+// to the requirements' asset, of transferWithAuthorization with the payment's authorization and signature
+func checkPreparedSettlement(tx *gethtypes.Transaction, payload *types.PaymentPayload, requirements *types.PaymentRequirements) error {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return fmt.Errorf("payment payload is missing its authorization")
+	}
+	if tx.To() == nil || !common.IsHexAddress(requirements.Asset) || *tx.To() != common.HexToAddress(requirements.Asset) {
+		return fmt.Errorf("prepared transaction does not call the asset contract %s", requirements.Asset)
+	}
+	if tx.Value().Sign() != 0 {
+		return fmt.Errorf("prepared transaction sends %s wei", tx.Value())
+	}
+
+	data := tx.Data()
+	if len(data) < 4 {
+		return fmt.Errorf("prepared transaction is not a transferWithAuthorization call")
+	}
+	method, err := transferWithAuthorizationMethods.MethodById(data[:4])
+	if err != nil {
+		return fmt.Errorf("prepared transaction is not a transferWithAuthorization call")
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return fmt.Errorf("invalid transferWithAuthorization call: %w", err)
+	}
+	// Re-encoding rejects trailing or non-canonical calldata that the decoder ignored
+	if repacked, err := method.Inputs.Pack(args...); err != nil || !bytes.Equal(repacked, data[4:]) {
+		return fmt.Errorf("invalid transferWithAuthorization call: non-canonical encoding")
+	}
+
+	authorization := payload.Payload.Authorization
+	nonce, err := hexutil.Decode(authorization.Nonce)
+	if err != nil || len(nonce) != 32 {
+		return fmt.Errorf("invalid nonce: must be 32 hex encoded bytes")
+	}
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	mismatch := func(field string) error {
+		return fmt.Errorf("prepared transaction %s does not match the payment authorization", field)
+	}
+	if !strings.EqualFold(args[0].(common.Address).Hex(), authorization.From) {
+		return mismatch("from")
+	}
+	if !strings.EqualFold(args[1].(common.Address).Hex(), authorization.To) {
+		return mismatch("to")
+	}
+	for i, field := range []struct{ name, value string }{
+		{"value", authorization.Value},
+		{"validAfter", authorization.ValidAfter},
+		{"validBefore", authorization.ValidBefore},
+	} {
+		if args[2+i].(*big.Int).String() != field.value {
+			return mismatch(field.name)
+		}
+	}
+	if calldataNonce := args[5].([32]byte); !bytes.Equal(calldataNonce[:], nonce) {
+		return mismatch("nonce")
+	}
+
+	var calldataSignature []byte
+	if len(args) == 9 {
+		r, s := args[7].([32]byte), args[8].([32]byte)
+		calldataSignature = append(append(r[:], s[:]...), args[6].(uint8))
+	} else {
+		calldataSignature = args[6].([]byte)
+	}
+	if !bytes.Equal(normalizeRecoveryID(calldataSignature), normalizeRecoveryID(signature)) {
+		return mismatch("signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package exactevm_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const erc3009ABI = `[
+	{"name":"transferWithAuthorization","type":"function","inputs":[
+		{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},
+		{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},
+		{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+	{"name":"transferWithAuthorization","type":"function","inputs":[
+		{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},
+		{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},
+		{"name":"signature","type":"bytes"}]},
+	{"name":"approve","type":"function","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}]}
+]`
+
+// recordingRPC records the transactions it is asked to broadcast
+type recordingRPC struct {
+	sent []*gethtypes.Transaction
+}
+
+func (r *recordingRPC) SendTransaction(ctx context.Context, tx *gethtypes.Transaction) error {
+	r.sent = append(r.sent, tx)
+	return nil
+}
+
+// settlementCalldata encodes the transferWithAuthorization call settling the payload,
+// with the signature as bytes or split into v, r and s
+func settlementCalldata(t *testing.T, payload *types.PaymentPayload, bytesSignature bool) []byte {
+	t.Helper()
+
+	parsed, err := abi.JSON(strings.NewReader(erc3009ABI))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	authorization := payload.Payload.Authorization
+	uint256 := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 10)
+		return n
+	}
+	var nonce [32]byte
+	copy(nonce[:], hexutil.MustDecode(authorization.Nonce))
+	signature := hexutil.MustDecode(payload.Payload.Signature)
+
+	args := []any{
+		common.HexToAddress(authorization.From), common.HexToAddress(authorization.To), uint256(authorization.Value),
+		uint256(authorization.ValidAfter), uint256(authorization.ValidBefore), nonce,
+	}
+	method := "transferWithAuthorization0"
+	if bytesSignature {
+		args = append(args, signature)
+	} else {
+		var r, s [32]byte
+		copy(r[:], signature[:32])
+		copy(s[:], signature[32:64])
+		args = append(args, signature[64], r, s)
+		method = "transferWithAuthorization"
+	}
+
+	data, err := parsed.Pack(method, args...)
+	if err != nil {
+		t.Fatalf("Failed to pack calldata: %v", err)
+	}
+	return data
+}
+
+func newPreparedTransaction(t *testing.T, tx *gethtypes.Transaction) *types.PreparedTransaction {
+	t.Helper()
+
+	encoded, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode transaction: %v", err)
+	}
+
+	return &types.PreparedTransaction{Network: types.NetworkBaseSepolia, Transaction: hexutil.Encode(encoded)}
+}
+
+// testSubmitOptions bounds the gas of submitted settlements above that of newSettlementTx
+var testSubmitOptions = exactevm.SubmitOptions{
+	MaxGas:               200000,
+	MaxFeePerGas:         big.NewInt(1000),
+	MaxPriorityFeePerGas: big.NewInt(10),
+}
+
+func newSettlementTx(asset common.Address, value *big.Int, data []byte) *gethtypes.Transaction {
+	return gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(84532),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       100000,
+		To:        &asset,
+		Value:     value,
+		Data:      data,
+	})
+}
+
+func TestSubmitSettlement(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	asset := common.HexToAddress(requirements.Asset)
+
+	for name, bytesSignature := range map[string]bool{"split signature": false, "bytes signature": true} {
+		t.Run(name, func(t *testing.T) {
+			signer := middlewaretest.NewTestSigner(t)
+			payload, err := exactevm.CreatePayment(signer, requirements)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			tx := newSettlementTx(asset, nil, settlementCalldata(t, payload, bytesSignature))
+			rpc := &recordingRPC{}
+
+			txHash, err := exactevm.SubmitSettlement(context.Background(), rpc, signer, payload, requirements, newPreparedTransaction(t, tx), testSubmitOptions)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(rpc.sent) != 1 || rpc.sent[0].Hash().Hex() != txHash {
+				t.Fatalf("Expected the signed transaction %s to be sent, got: %v", txHash, rpc.sent)
+			}
+
+			sender, err := gethtypes.Sender(gethtypes.LatestSignerForChainID(big.NewInt(84532)), rpc.sent[0])
+			if err != nil || sender != signer.Address() {
+				t.Errorf("Expected the transaction to be signed by %s, got: %s (%v)", signer.Address().Hex(), sender.Hex(), err)
+			}
+		})
+	}
+}
+
+func TestSubmitSettlementGasBounds(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	asset := common.HexToAddress(requirements.Asset)
+	signer := middlewaretest.NewTestSigner(t)
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	calldata := settlementCalldata(t, payload, false)
+
+	// An access list transaction's tip is its gas price
+	accessList := gethtypes.NewTx(&gethtypes.AccessListTx{
+		ChainID: big.NewInt(84532), To: &asset, GasPrice: big.NewInt(10), Gas: 100000, Data: calldata,
+	})
+	if _, err := exactevm.SubmitSettlement(context.Background(), &recordingRPC{}, signer, payload, requirements, newPreparedTransaction(t, accessList), testSubmitOptions); err != nil {
+		t.Errorf("Expected an access list transaction within the bounds to be submitted, got: %v", err)
+	}
+	accessList = gethtypes.NewTx(&gethtypes.AccessListTx{
+		ChainID: big.NewInt(84532), To: &asset, GasPrice: big.NewInt(11), Gas: 100000, Data: calldata,
+	})
+	if _, err := exactevm.SubmitSettlement(context.Background(), &recordingRPC{}, signer, payload, requirements, newPreparedTransaction(t, accessList), testSubmitOptions); err == nil {
+		t.Error("Expected an access list transaction priced above the tip bound to be rejected")
+	}
+
+	rpc := &recordingRPC{}
+	tx := newSettlementTx(asset, nil, calldata)
+	if _, err := exactevm.SubmitSettlement(context.Background(), rpc, signer, payload, requirements, newPreparedTransaction(t, tx), exactevm.SubmitOptions{}); err == nil {
+		t.Error("Expected submitting without gas bounds to be rejected")
+	}
+	if len(rpc.sent) != 0 {
+		t.Errorf("Expected nothing to be sent")
+	}
+}
+
+func TestSubmitSettlementRejectsOtherTransactions(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	asset := common.HexToAddress(requirements.Asset)
+	signer := middlewaretest.NewTestSigner(t)
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	calldata := settlementCalldata(t, payload, false)
+
+	parsed, err := abi.JSON(strings.NewReader(erc3009ABI))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	approve, err := parsed.Pack("approve", common.HexToAddress("0xbad"), new(big.Int).Lsh(big.NewInt(1), 255))
+	if err != nil {
+		t.Fatalf("Failed to pack calldata: %v", err)
+	}
+
+	otherPayload := *payload
+	otherAuthorization := *payload.Payload.Authorization
+	otherAuthorization.Value = "1"
+	otherPayload.Payload = &types.ExactEvmPayload{Signature: payload.Payload.Signature, Authorization: &otherAuthorization}
+
+	testCases := map[string]*gethtypes.Transaction{
+		"other contract":    newSettlementTx(common.HexToAddress("0xbad"), nil, calldata),
+		"sends ether":       newSettlementTx(asset, big.NewInt(1), calldata),
+		"approve":           newSettlementTx(asset, nil, approve),
+		"other value":       newSettlementTx(asset, nil, settlementCalldata(t, &otherPayload, false)),
+		"trailing calldata": newSettlementTx(asset, nil, append(append([]byte(nil), calldata...), 0x01)),
+		"other chain": gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+			ChainID: big.NewInt(8453), To: &asset, GasFeeCap: big.NewInt(1), GasTipCap: big.NewInt(1), Data: calldata,
+		}),
+		"legacy": gethtypes.NewTx(&gethtypes.LegacyTx{To: &asset, GasPrice: big.NewInt(1), Gas: 100000, Data: calldata}),
+		"blob": gethtypes.NewTx(&gethtypes.BlobTx{
+			ChainID: uint256.NewInt(84532), To: asset, GasFeeCap: uint256.NewInt(100), GasTipCap: uint256.NewInt(1),
+			Gas: 100000, BlobFeeCap: uint256.NewInt(1), Data: calldata,
+		}),
+		"inflated tip": gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+			ChainID: big.NewInt(84532), To: &asset, GasFeeCap: big.NewInt(1e18), GasTipCap: big.NewInt(1e18), Gas: 100000, Data: calldata,
+		}),
+		"inflated gas limit": gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+			ChainID: big.NewInt(84532), To: &asset, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(1), Gas: 30000000, Data: calldata,
+		}),
+	}
+
+	for name, tx := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rpc := &recordingRPC{}
+
+			if _, err := exactevm.SubmitSettlement(context.Background(), rpc, signer, payload, requirements, newPreparedTransaction(t, tx), testSubmitOptions); err == nil {
+				t.Error("Expected the prepared transaction to be rejected")
+			}
+			if len(rpc.sent) != 0 {
+				t.Errorf("Expected nothing to be sent")
+			}
+		})
+	}
+}
+
+func TestSettleResponseNeedsSubmission(t *testing.T) {
+	var submitted, prepared types.SettleResponse
+	if err := json.Unmarshal([]byte(`{"success":true,"transaction":"0xtx","network":"base"}`), &submitted); err != nil {
+		t.Fatalf("Failed to unmarshal settle response: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"success":true,"network":"base","preparedTransaction":{"network":"base","transaction":"0x02"}}`), &prepared); err != nil {
+		t.Fatalf("Failed to unmarshal settle response: %v", err)
+	}
+
+	if submitted.NeedsSubmission() {
+		t.Error("Expected a submitted settlement not to need submission")
+	}
+	if !prepared.NeedsSubmission() {
+		t.Error("Expected a prepared settlement to need submission")
+	}
+}
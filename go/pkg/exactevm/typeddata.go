@@ -0,0 +1,78 @@
+package exactevm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// TypedDataField is a field of an EIP-712 struct type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataDomain is the domain of EIP-712 typed data
+type TypedDataDomain struct {
+	Name              string `json:"name"`
+	Version           string `json:"version"`
+	ChainID           int64  `json:"chainId"`
+	VerifyingContract string `json:"verifyingContract"`
+}
+
+// TypedData is EIP-712 typed data in the JSON form used by eth_signTypedData_v4, MetaMask and ethers
+type TypedData struct {
+	Types       map[string][]TypedDataField        `json:"types"`
+	PrimaryType string                             `json:"primaryType"`
+	Domain      TypedDataDomain                    `json:"domain"`
+	Message     types.ExactEvmPayloadAuthorization `json:"message"`
+}
+
+// ExactTypedData returns the EIP-712 typed data of the TransferWithAuthorization message that is signed for
+// the authorization under the requirements' domain, with checksummed addresses. Its digest is ExactSigningDigest;
+// it exists to compare what is signed against other implementations or to show it in a wallet.
+func ExactTypedData(requirements *types.PaymentRequirements, authorization *types.ExactEvmPayloadAuthorization) (*TypedData, error) {
+	domain, err := DomainForRequirements(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the message encodes, so the typed data is never returned for an authorization that can't be signed
+	if _, err := hashAuthorization(authorization); err != nil {
+		return nil, err
+	}
+
+	return &TypedData{
+		Types: map[string][]TypedDataField{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"TransferWithAuthorization": {
+				{Name: "from", Type: "address"},
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "validAfter", Type: "uint256"},
+				{Name: "validBefore", Type: "uint256"},
+				{Name: "nonce", Type: "bytes32"},
+			},
+		},
+		PrimaryType: "TransferWithAuthorization",
+		Domain: TypedDataDomain{
+			Name:              domain.Name,
+			Version:           domain.Version,
+			ChainID:           domain.ChainID.Int64(),
+			VerifyingContract: domain.VerifyingContract.Hex(),
+		},
+		Message: types.ExactEvmPayloadAuthorization{
+			From:        common.HexToAddress(authorization.From).Hex(),
+			To:          common.HexToAddress(authorization.To).Hex(),
+			Value:       authorization.Value,
+			ValidAfter:  authorization.ValidAfter,
+			ValidBefore: authorization.ValidBefore,
+			Nonce:       authorization.Nonce,
+		},
+	}, nil
+}
@@ -0,0 +1,106 @@
+package exactevm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newTypedDataFixture() (*types.PaymentRequirements, *types.ExactEvmPayloadAuthorization) {
+	requirements := &types.PaymentRequirements{
+		Scheme:            exactevm.Scheme,
+		Network:           types.NetworkBaseSepolia,
+		MaxAmountRequired: "10000",
+		PayTo:             "0x209693bc6afc0c5328ba36faf03c514ef312287c",
+		Asset:             "0x036cbd53842c5426634e7929541ec2318f3dcf7e",
+	}
+	authorization := &types.ExactEvmPayloadAuthorization{
+		From:        "0x857b06519e91e3a54538791bdbb0e22373e36b66",
+		To:          "0x209693bc6afc0c5328ba36faf03c514ef312287c",
+		Value:       "10000",
+		ValidAfter:  "1740672089",
+		ValidBefore: "1740672154",
+		Nonce:       "0xf3746613c2d920b5fdabc0856f2aeb2d4f88ee6037b8cc5d04a71a4462f13480",
+	}
+
+	return requirements, authorization
+}
+
+// TestExactTypedDataGolden compares the typed data against the eth_signTypedData_v4 JSON expected for the payment,
+// with checksummed addresses and the EIP712Domain type included. TestExactTypedDataDigest checks it hashes to
+// the signed digest.
+func TestExactTypedDataGolden(t *testing.T) {
+	requirements, authorization := newTypedDataFixture()
+
+	typedData, err := exactevm.ExactTypedData(requirements, authorization)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	actual, err := json.Marshal(typedData)
+	if err != nil {
+		t.Fatalf("Failed to marshal typed data: %v", err)
+	}
+
+	golden, err := os.ReadFile("testdata/exact_typed_data.json")
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+
+	var expectedJSON, actualJSON any
+	if err := json.Unmarshal(golden, &expectedJSON); err != nil {
+		t.Fatalf("Failed to unmarshal golden file: %v", err)
+	}
+	if err := json.Unmarshal(actual, &actualJSON); err != nil {
+		t.Fatalf("Failed to unmarshal typed data: %v", err)
+	}
+	if !reflect.DeepEqual(expectedJSON, actualJSON) {
+		t.Errorf("Expected typed data to match the golden file, got: %s", actual)
+	}
+}
+
+func TestExactTypedDataDigest(t *testing.T) {
+	requirements, authorization := newTypedDataFixture()
+
+	typedData, err := exactevm.ExactTypedData(requirements, authorization)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	encoded, err := json.Marshal(typedData)
+	if err != nil {
+		t.Fatalf("Failed to marshal typed data: %v", err)
+	}
+
+	// Hash the JSON with go-ethereum's independent EIP-712 implementation
+	var gethTypedData apitypes.TypedData
+	if err := json.Unmarshal(encoded, &gethTypedData); err != nil {
+		t.Fatalf("Failed to unmarshal typed data: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(gethTypedData)
+	if err != nil {
+		t.Fatalf("Failed to hash typed data: %v", err)
+	}
+
+	digest, err := exactevm.ExactSigningDigest(requirements, authorization)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !bytes.Equal(hash, digest[:]) {
+		t.Errorf("Expected typed data hash %x, got: %x", digest, hash)
+	}
+}
+
+func TestExactTypedDataInvalidAuthorization(t *testing.T) {
+	requirements, authorization := newTypedDataFixture()
+	authorization.Value = "-1"
+
+	if _, err := exactevm.ExactTypedData(requirements, authorization); err == nil {
+		t.Error("Expected an error for an invalid value")
+	}
+}
@@ -0,0 +1,289 @@
+package exactevm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// Invalid reasons reported by VerifyPayment, matching the facilitator's invalidReason values
+const (
+	ReasonInvalidScheme      = "invalid_scheme"
+	ReasonInvalidNetwork     = "invalid_network"
+	ReasonWrongNetwork       = "wrong_network"
+	ReasonInvalidPayload     = "invalid_payload"
+	ReasonInvalidSignature   = "invalid_exact_evm_payload_signature"
+	ReasonRecipientMismatch  = "invalid_exact_evm_payload_recipient_mismatch"
+	ReasonInsufficientValue  = "invalid_exact_evm_payload_authorization_value"
+	ReasonValueMismatch      = "invalid_exact_evm_payload_authorization_value_mismatch"
+	ReasonNotYetValid        = "invalid_exact_evm_payload_authorization_valid_after"
+	ReasonExpired            = "invalid_exact_evm_payload_authorization_valid_before"
+	ReasonInvalidRequirement = "invalid_payment_requirements"
+)
+
+// ErrPossibleClockSkew is matched by errors.Is when a facilitator rejected a freshly signed authorization as not yet
+// valid or expired, which usually means the payer's and the facilitator's clocks disagree rather than that the payer
+// was too slow
+var ErrPossibleClockSkew = errors.New("clock skew between the payer and the facilitator may be the cause, widen the validity window with WithClockSkewMargin")
+
+// IsValidityWindowReason reports whether an invalid reason rejects the authorization's validity window,
+// ReasonNotYetValid or ReasonExpired
+func IsValidityWindowReason(reason string) bool {
+	return reason == ReasonNotYetValid || reason == ReasonExpired
+}
+
+// VerificationError is returned by VerifyPayment when a payment does not satisfy its requirements
+type VerificationError struct {
+	Reason string
+	Err    error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+func newVerificationError(reason string, format string, args ...any) *VerificationError {
+	return &VerificationError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// RequiredValue returns the value an authorization must carry to satisfy the requirements:
+// maxAmountRequired plus the relayer fee advertised in extra, if any
+func RequiredValue(requirements *types.PaymentRequirements) (*big.Int, error) {
+	value, err := parseUint256("maxAmountRequired", requirements.MaxAmountRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	var extra types.ExactEvmExtra
+	if _, err := requirements.DecodeExtra(&extra); err != nil {
+		return nil, err
+	}
+	if extra.FeeRecipient != "" && !common.IsHexAddress(extra.FeeRecipient) {
+		return nil, fmt.Errorf("invalid fee recipient address: %s", extra.FeeRecipient)
+	}
+	if extra.Fee == "" {
+		return value, nil
+	}
+
+	fee, err := parseUint256("fee", extra.Fee)
+	if err != nil {
+		return nil, err
+	}
+	value.Add(value, fee)
+	if value.BitLen() > 256 {
+		return nil, fmt.Errorf("maxAmountRequired plus fee overflows uint256")
+	}
+
+	return value, nil
+}
+
+// SettlementRecipient returns the address an authorization must transfer to: the settlementRecipient
+// advertised in extra if any, otherwise payTo
+func SettlementRecipient(requirements *types.PaymentRequirements) (string, error) {
+	var extra types.ExactEvmExtra
+	if _, err := requirements.DecodeExtra(&extra); err != nil {
+		return "", err
+	}
+	if extra.SettlementRecipient == "" {
+		return requirements.PayTo, nil
+	}
+	if !common.IsHexAddress(extra.SettlementRecipient) {
+		return "", fmt.Errorf("invalid settlement recipient address: %s", extra.SettlementRecipient)
+	}
+
+	return extra.SettlementRecipient, nil
+}
+
+// CheckValue checks that the authorized value covers the required value, maxAmountRequired plus any relayer fee,
+// reporting ReasonInsufficientValue if it doesn't. With WithExactAmount it must equal the required value,
+// and overpayments are reported as ReasonValueMismatch.
+func CheckValue(payload *types.PaymentPayload, requirements *types.PaymentRequirements, opts ...Options) error {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return newVerificationError(ReasonInvalidPayload, "payment payload is missing its authorization")
+	}
+
+	required, err := RequiredValue(requirements)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+	value, err := parseUint256("value", payload.Payload.Authorization.Value)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidPayload, Err: err}
+	}
+	if value.Cmp(required) < 0 {
+		return newVerificationError(ReasonInsufficientValue, "value %s does not cover the required %s", value, required)
+	}
+	if newPaymentOptions(opts).ExactAmount && value.Cmp(required) != 0 {
+		return newVerificationError(ReasonValueMismatch, "value %s does not equal the required %s", value, required)
+	}
+
+	return nil
+}
+
+// CheckNetwork checks that the payload targets the chain required by the requirements.
+// Unknown networks are reported as ReasonInvalidNetwork and chain ID mismatches as ReasonWrongNetwork.
+func CheckNetwork(payload *types.PaymentPayload, requirements *types.PaymentRequirements) error {
+	requiredChainID, err := types.GetChainID(requirements.Network)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidNetwork, Err: err}
+	}
+	payloadChainID, err := types.GetChainID(payload.Network)
+	if err != nil {
+		return &VerificationError{Reason: ReasonInvalidNetwork, Err: err}
+	}
+	if payloadChainID != requiredChainID {
+		return newVerificationError(ReasonWrongNetwork, "payload network %s (chain ID %d) does not match required network %s (chain ID %d)",
+			payload.Network, payloadChainID, requirements.Network, requiredChainID)
+	}
+
+	return nil
+}
+
+// signedChainID reports the chain ID of another known network whose EIP-712 domain the signature
+// was produced under, which identifies a client that signed for the wrong chain
+func signedChainID(requirements *types.PaymentRequirements, authorization *types.ExactEvmPayloadAuthorization, signature []byte) (*big.Int, bool) {
+	domain, err := DomainForRequirements(requirements)
+	if err != nil {
+		return nil, false
+	}
+	from := common.HexToAddress(authorization.From)
+
+	for _, chainID := range types.EvmNetworkToChainID {
+		if domain.ChainID.Int64() == chainID {
+			continue
+		}
+
+		candidate := *domain
+		candidate.ChainID = big.NewInt(chainID)
+		digest, err := candidate.digest(authorization)
+		if err != nil {
+			return nil, false
+		}
+		if signer, err := RecoverAddress(digest, signature); err == nil && signer == from {
+			return candidate.ChainID, true
+		}
+	}
+
+	return nil, false
+}
+
+// VerifyPayment checks a payment payload against its requirements without contacting a facilitator:
+// the scheme and network, the recipient, that the value covers the required amount and any fee (or equals it,
+// with WithExactAmount), the validity window, and that the signature was produced by the authorization's from address.
+// It does not check the payer's balance or whether the nonce has already been used on chain.
+// The validity window is checked against the system time unless a clock is set with WithClock.
+// On success it returns the payer address; otherwise the error is a *VerificationError.
+func VerifyPayment(payload *types.PaymentPayload, requirements *types.PaymentRequirements, opts ...Options) (common.Address, error) {
+	digest, err := verifyAuthorization(payload, requirements, opts)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	authorization := payload.Payload.Authorization
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		return common.Address{}, newVerificationError(ReasonInvalidSignature, "invalid signature encoding: %v", err)
+	}
+	if len(signature) > crypto.SignatureLength && len(signature)%crypto.SignatureLength == 0 {
+		return common.Address{}, newVerificationError(ReasonInvalidSignature, "payload carries %d signatures; verify multisig payers with VerifyMultisigPayment", len(signature)/crypto.SignatureLength)
+	}
+	signer, err := RecoverAddress(digest, signature)
+	if err != nil {
+		return common.Address{}, &VerificationError{Reason: ReasonInvalidSignature, Err: err}
+	}
+	if signer != common.HexToAddress(authorization.From) {
+		if chainID, ok := signedChainID(requirements, authorization, signature); ok {
+			return common.Address{}, newVerificationError(ReasonWrongNetwork, "authorization was signed for chain ID %s, not for network %s", chainID, requirements.Network)
+		}
+		return common.Address{}, newVerificationError(ReasonInvalidSignature, "signature was produced by %s, not %s", signer.Hex(), authorization.From)
+	}
+
+	return signer, nil
+}
+
+// RecoverPayer returns the address that signed the payment's authorization under the requirements' EIP-712 domain,
+// e.g. to rate limit callers by identity before paying for a full verification.
+// It only identifies the signer: the amount, recipient, validity window and network are not checked,
+// so the payment may still be invalid, and the signer may differ from the authorization's from address.
+// Use VerifyPayment before trusting the payment.
+func RecoverPayer(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (string, error) {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return "", fmt.Errorf("payment payload is missing its authorization")
+	}
+
+	digest, err := ExactSigningDigest(requirements, payload.Payload.Authorization)
+	if err != nil {
+		return "", err
+	}
+	signature, err := hexutil.Decode(payload.Payload.Signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	signer, err := RecoverAddress(digest, signature)
+	if err != nil {
+		return "", err
+	}
+
+	return signer.Hex(), nil
+}
+
+// verifyAuthorization runs every VerifyPayment check except the signature's,
+// and returns the digest to verify the signature against
+func verifyAuthorization(payload *types.PaymentPayload, requirements *types.PaymentRequirements, opts []Options) ([32]byte, error) {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return [32]byte{}, newVerificationError(ReasonInvalidPayload, "payment payload is missing its authorization")
+	}
+	if payload.Scheme != Scheme || requirements.Scheme != Scheme {
+		return [32]byte{}, newVerificationError(ReasonInvalidScheme, "unsupported scheme: %s", payload.Scheme)
+	}
+	if err := CheckNetwork(payload, requirements); err != nil {
+		return [32]byte{}, err
+	}
+
+	recipient, err := SettlementRecipient(requirements)
+	if err != nil {
+		return [32]byte{}, &VerificationError{Reason: ReasonInvalidRequirement, Err: err}
+	}
+	authorization := payload.Payload.Authorization
+	if !common.IsHexAddress(authorization.To) || !strings.EqualFold(authorization.To, recipient) {
+		return [32]byte{}, newVerificationError(ReasonRecipientMismatch, "authorization recipient %s does not match settlement recipient %s", authorization.To, recipient)
+	}
+
+	if err := CheckValue(payload, requirements, opts...); err != nil {
+		return [32]byte{}, err
+	}
+
+	validAfter, err := parseUint256("validAfter", authorization.ValidAfter)
+	if err != nil {
+		return [32]byte{}, &VerificationError{Reason: ReasonInvalidPayload, Err: err}
+	}
+	validBefore, err := parseUint256("validBefore", authorization.ValidBefore)
+	if err != nil {
+		return [32]byte{}, &VerificationError{Reason: ReasonInvalidPayload, Err: err}
+	}
+	now := big.NewInt(newPaymentOptions(opts).Clock.Now().Unix())
+	if now.Cmp(validAfter) < 0 {
+		return [32]byte{}, newVerificationError(ReasonNotYetValid, "authorization is not valid until %s", validAfter)
+	}
+	if now.Cmp(validBefore) >= 0 {
+		return [32]byte{}, newVerificationError(ReasonExpired, "authorization expired at %s", validBefore)
+	}
+
+	digest, err := ExactSigningDigest(requirements, authorization)
+	if err != nil {
+		return [32]byte{}, &VerificationError{Reason: ReasonInvalidPayload, Err: err}
+	}
+
+	return digest, nil
+}
@@ -0,0 +1,281 @@
+package exactevm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/middlewaretest/testclock"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newTestRequirements(t *testing.T, extra string) *types.PaymentRequirements {
+	t.Helper()
+
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal([]byte(testRequirementsJSON), &requirements); err != nil {
+		t.Fatalf("Failed to unmarshal requirements: %v", err)
+	}
+	if extra != "" {
+		raw := json.RawMessage(extra)
+		requirements.Extra = &raw
+	}
+
+	return &requirements
+}
+
+func TestVerifyPayment(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	payer, err := exactevm.VerifyPayment(payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payer != signer.Address() {
+		t.Errorf("Expected payer %s, got: %s", signer.Address().Hex(), payer.Hex())
+	}
+
+	// Tampering with the signed value must invalidate the signature
+	tampered := *payload.Payload.Authorization
+	tampered.Value = "20000"
+	payload.Payload.Authorization = &tampered
+	_, err = exactevm.VerifyPayment(payload, requirements)
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonInvalidSignature {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonInvalidSignature, err)
+	}
+}
+
+func TestVerifyPaymentValidityWindow(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	clock := testclock.New(time.Unix(1745323800, 0))
+
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), requirements, exactevm.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements, exactevm.WithClock(clock)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	clock.Advance(time.Duration(requirements.MaxTimeoutSeconds) * time.Second)
+	_, err = exactevm.VerifyPayment(payload, requirements, exactevm.WithClock(clock))
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonExpired {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonExpired, err)
+	}
+
+	clock.Set(time.Unix(1745323800, 0).Add(-2 * time.Minute))
+	_, err = exactevm.VerifyPayment(payload, requirements, exactevm.WithClock(clock))
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonNotYetValid {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonNotYetValid, err)
+	}
+}
+
+func TestVerifyPaymentWithFee(t *testing.T) {
+	requirements := newTestRequirements(t, `{"name":"USDC","version":"2","fee":"500","feeRecipient":"0x1111111111111111111111111111111111111111"}`)
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.Payload.Authorization.Value != "10500" {
+		t.Errorf("Expected value to include the fee, got: %s", payload.Payload.Authorization.Value)
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	// A payment covering only maxAmountRequired does not cover the fee
+	noFeeRequirements := newTestRequirements(t, `{"name":"USDC","version":"2"}`)
+	underpaid, err := exactevm.CreatePayment(signer, noFeeRequirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, err = exactevm.VerifyPayment(underpaid, requirements)
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonInsufficientValue {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonInsufficientValue, err)
+	}
+}
+
+func TestVerifyPaymentExactAmount(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements, exactevm.WithExactAmount()); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	// An overpayment is accepted by default, and rejected in exact amount mode
+	overpaid, err := exactevm.CreatePayment(signer, newTestRequirements(t, `{"name":"Bridged USDC","version":"1","fee":"500","feeRecipient":"0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := exactevm.VerifyPayment(overpaid, requirements); err != nil {
+		t.Errorf("Expected an overpayment to be accepted, got: %v", err)
+	}
+	_, err = exactevm.VerifyPayment(overpaid, requirements, exactevm.WithExactAmount())
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonValueMismatch {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonValueMismatch, err)
+	}
+}
+
+func TestVerifyPaymentZeroAmount(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	requirements.MaxAmountRequired = "0"
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	payer, err := exactevm.VerifyPayment(payload, requirements, exactevm.WithExactAmount())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payer != signer.Address() {
+		t.Errorf("Expected payer %s, got: %s", signer.Address().Hex(), payer.Hex())
+	}
+
+	// The signature is still checked
+	payload.Payload.Authorization.Nonce = "0x" + strings.Repeat("00", 32)
+	if _, err := exactevm.VerifyPayment(payload, requirements); err == nil {
+		t.Error("Expected a tampered zero amount payment to fail verification")
+	}
+}
+
+func TestRecoverPayer(t *testing.T) {
+	clock := testclock.New(time.Now())
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements, exactevm.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The signer is recovered even once the payment has expired and no longer verifies
+	clock.Advance(time.Hour)
+	if _, err := exactevm.VerifyPayment(payload, requirements, exactevm.WithClock(clock)); err == nil {
+		t.Fatal("Expected the expired payment not to verify")
+	}
+	payer, err := exactevm.RecoverPayer(payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payer != signer.Address().Hex() {
+		t.Errorf("Expected payer %s, got: %s", signer.Address().Hex(), payer)
+	}
+
+	payload.Payload.Signature = "0x1234"
+	if _, err := exactevm.RecoverPayer(payload, requirements); err == nil {
+		t.Error("Expected error for a malformed signature, got err == nil")
+	}
+}
+
+func TestRequiredValueInvalidFee(t *testing.T) {
+	tests := []string{
+		`{"name":"USDC","version":"2","fee":"-1"}`,
+		`{"name":"USDC","version":"2","fee":"abc"}`,
+		`{"name":"USDC","version":"2","feeRecipient":"not-an-address"}`,
+	}
+
+	for _, extra := range tests {
+		if _, err := exactevm.RequiredValue(newTestRequirements(t, extra)); err == nil {
+			t.Errorf("Expected error for extra %s, got err == nil", extra)
+		}
+	}
+}
+
+func TestVerifyPaymentRecipientMismatch(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	otherRequirements := *requirements
+	otherRequirements.PayTo = "0x2222222222222222222222222222222222222222"
+	_, err = exactevm.VerifyPayment(payload, &otherRequirements)
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonRecipientMismatch {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonRecipientMismatch, err)
+	}
+}
+
+func TestVerifyPaymentSettlementRecipient(t *testing.T) {
+	const escrow = "0x3333333333333333333333333333333333333333"
+	requirements := newTestRequirements(t, `{"name":"USDC","version":"2","settlementRecipient":"`+escrow+`"}`)
+	signer := middlewaretest.NewTestSigner(t)
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.Payload.Authorization.To != escrow {
+		t.Errorf("Expected transfer to settlement recipient %s, got: %s", escrow, payload.Payload.Authorization.To)
+	}
+	if _, err := exactevm.VerifyPayment(payload, requirements); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	// A payment to the advertised payTo does not reach the settlement recipient
+	direct, err := exactevm.CreatePayment(signer, newTestRequirements(t, `{"name":"USDC","version":"2"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, err = exactevm.VerifyPayment(direct, requirements)
+	var verificationErr *exactevm.VerificationError
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonRecipientMismatch {
+		t.Errorf("Expected %s, got: %v", exactevm.ReasonRecipientMismatch, err)
+	}
+}
+
+func TestVerifyPaymentWrongNetwork(t *testing.T) {
+	requirements := newTestRequirements(t, "")
+	signer := middlewaretest.NewTestSigner(t)
+
+	// Sign for base (8453) while the requirements declare base-sepolia (84532)
+	mainnetRequirements := *requirements
+	mainnetRequirements.Network = types.NetworkBase
+	payload, err := exactevm.CreatePayment(signer, &mainnetRequirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var verificationErr *exactevm.VerificationError
+	_, err = exactevm.VerifyPayment(payload, requirements)
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonWrongNetwork {
+		t.Errorf("Expected %s for mismatched payload network, got: %v", exactevm.ReasonWrongNetwork, err)
+	}
+
+	// Relabelling the payload does not hide the chain ID the signature was produced for
+	payload.Network = requirements.Network
+	_, err = exactevm.VerifyPayment(payload, requirements)
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonWrongNetwork {
+		t.Errorf("Expected %s for signature over another chain ID, got: %v", exactevm.ReasonWrongNetwork, err)
+	}
+
+	payload.Network = "unknown-network"
+	err = exactevm.CheckNetwork(payload, requirements)
+	if !errors.As(err, &verificationErr) || verificationErr.Reason != exactevm.ReasonInvalidNetwork {
+		t.Errorf("Expected %s for unknown network, got: %v", exactevm.ReasonInvalidNetwork, err)
+	}
+}
@@ -0,0 +1,79 @@
+package facilitatorclient
+
+import (
+	"context"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// DefaultMaxConcurrentSettlements is the default number of settlements SettleAsync runs at once
+const DefaultMaxConcurrentSettlements = 16
+
+// SettleResult is the outcome of an asynchronous settlement
+type SettleResult struct {
+	Response *types.SettleResponse
+	Err      error
+}
+
+// SettleAsync settles the payment in the background and delivers the result on the returned channel.
+// At most MaxConcurrentSettlements settlements run at once; when all slots are busy SettleAsync blocks
+// until one frees up or the context is done, so a settlement backlog applies backpressure to the caller
+// instead of spawning unbounded goroutines. Exactly one result is delivered on the channel.
+func (c *FacilitatorClient) SettleAsync(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) <-chan SettleResult {
+	results := make(chan SettleResult, 1)
+	slots := c.getSettleSlots()
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		results <- SettleResult{Err: ctx.Err()}
+		close(results)
+		return results
+	}
+
+	go func() {
+		defer func() { <-slots }()
+		defer close(results)
+
+		resp, err := c.SettleWithContext(ctx, payload, requirements)
+		results <- SettleResult{Response: resp, Err: err}
+	}()
+
+	return results
+}
+
+// SettleItem is a verified payment to settle with SettleBatch
+type SettleItem struct {
+	Payload      *types.PaymentPayload
+	Requirements *types.PaymentRequirements
+}
+
+// SettleBatch settles the payments concurrently, sharing the SettleAsync concurrency limit, and returns one result
+// per item in input order. A failed item doesn't abort the others: its error, or its settle response reporting
+// the failure, is in its result. The returned error is only set if the context is done, in which case items not
+// yet settled carry the context error.
+func (c *FacilitatorClient) SettleBatch(ctx context.Context, items []SettleItem) ([]SettleResult, error) {
+	pending := make([]<-chan SettleResult, len(items))
+	for i, item := range items {
+		pending[i] = c.SettleAsync(ctx, item.Payload, item.Requirements)
+	}
+
+	results := make([]SettleResult, len(items))
+	for i, result := range pending {
+		results[i] = <-result
+	}
+
+	return results, ctx.Err()
+}
+
+func (c *FacilitatorClient) getSettleSlots() chan struct{} {
+	c.settleSlotsOnce.Do(func() {
+		n := c.maxConcurrentSettlements
+		if n <= 0 {
+			n = DefaultMaxConcurrentSettlements
+		}
+		c.settleSlots = make(chan struct{}, n)
+	})
+
+	return c.settleSlots
+}
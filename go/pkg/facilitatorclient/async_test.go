@@ -0,0 +1,171 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestSettleAsync(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xvalidTransaction"})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+
+	result := <-client.SettleAsync(context.Background(), newTestPayload(), &types.PaymentRequirements{})
+	if result.Err != nil {
+		t.Fatalf("Expected no error, got: %v", result.Err)
+	}
+	if result.Response == nil || result.Response.Transaction != "0xvalidTransaction" {
+		t.Errorf("Expected settle response, got: %+v", result.Response)
+	}
+}
+
+func TestSettleAsyncError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+
+	result := <-client.SettleAsync(context.Background(), newTestPayload(), &types.PaymentRequirements{})
+	if result.Err == nil {
+		t.Error("Expected error, got err == nil")
+	}
+}
+
+func TestSettleAsyncBoundedConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			observed := maxInFlight.Load()
+			if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		<-release
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithMaxConcurrentSettlements(2))
+
+	var results []<-chan facilitatorclient.SettleResult
+	results = append(results, client.SettleAsync(context.Background(), newTestPayload(), &types.PaymentRequirements{}))
+	results = append(results, client.SettleAsync(context.Background(), newTestPayload(), &types.PaymentRequirements{}))
+
+	// The pool is full, so a third settlement blocks until the context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	blocked := <-client.SettleAsync(ctx, newTestPayload(), &types.PaymentRequirements{})
+	if blocked.Err != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline exceeded error, got: %v", blocked.Err)
+	}
+
+	close(release)
+	for _, result := range results {
+		if r := <-result; r.Err != nil {
+			t.Errorf("Expected no error, got: %v", r.Err)
+		}
+	}
+
+	if maxInFlight.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent settlements, got: %d", maxInFlight.Load())
+	}
+}
+
+func TestSettleBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			PaymentRequirements types.PaymentRequirements `json:"paymentRequirements"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch payTo := body.PaymentRequirements.PayTo; payTo {
+		case "0xunavailable":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "0xrejected":
+			reason := "insufficient_funds"
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: false, ErrorReason: &reason})
+		default:
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtx" + payTo})
+		}
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithMaxConcurrentSettlements(2))
+
+	var items []facilitatorclient.SettleItem
+	for _, payTo := range []string{"0xa", "0xunavailable", "0xb", "0xrejected", "0xc"} {
+		items = append(items, facilitatorclient.SettleItem{
+			Payload:      newTestPayload(),
+			Requirements: &types.PaymentRequirements{PayTo: payTo},
+		})
+	}
+
+	results, err := client.SettleBatch(context.Background(), items)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d results, got: %d", len(items), len(results))
+	}
+	for i, payTo := range []string{"0xa", "", "0xb", "", "0xc"} {
+		if payTo == "" {
+			continue
+		}
+		if results[i].Err != nil || results[i].Response.Transaction != "0xtx"+payTo {
+			t.Errorf("Expected item %d to settle, got: %+v", i, results[i])
+		}
+	}
+	if results[1].Err == nil {
+		t.Error("Expected the unavailable settlement to fail, got err == nil")
+	}
+	if results[3].Err != nil || results[3].Response.Success {
+		t.Errorf("Expected the rejected settlement response, got: %+v", results[3])
+	}
+}
+
+func TestSettleBatchCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	items := []facilitatorclient.SettleItem{{Payload: newTestPayload(), Requirements: &types.PaymentRequirements{}}}
+	results, err := client.SettleBatch(ctx, items)
+	if err != context.Canceled {
+		t.Errorf("Expected context canceled error, got: %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Errorf("Expected the unsettled item to carry an error, got: %+v", results)
+	}
+}
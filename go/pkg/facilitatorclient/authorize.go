@@ -0,0 +1,83 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+var (
+	// ErrPaymentInvalid is matched by errors.Is when Authorize is refused because the facilitator found the payment invalid
+	ErrPaymentInvalid = errors.New("payment is invalid")
+	// ErrAuthorizationExpired is matched by errors.Is when Capture is called after the payment's authorization expired
+	ErrAuthorizationExpired = errors.New("payment authorization has expired")
+)
+
+// AuthorizationToken is a verified payment reserved by Authorize, to be settled later with Capture.
+// It carries everything Capture needs, so it can be persisted with encoding/json between the two steps.
+type AuthorizationToken struct {
+	Payload      *types.PaymentPayload      `json:"payload"`
+	Requirements *types.PaymentRequirements `json:"requirements"`
+	// Payer is the payer reported by the facilitator when the payment was verified
+	Payer string `json:"payer,omitempty"`
+	// AuthorizedAt is when the payment was verified
+	AuthorizedAt time.Time `json:"authorizedAt"`
+}
+
+// Authorize verifies the payment without settling it and returns a token for settling it later with Capture,
+// for two-phase flows that reserve a payment before the goods are delivered.
+// An invalid payment is returned as an error matching ErrPaymentInvalid, and also exactevm.ErrPossibleClockSkew
+// if it was refused for its validity window.
+// Verifying only checks the payment at the time: the payer can still spend the funds before capture,
+// and the authorization can't be captured once its validBefore has passed.
+func (c *FacilitatorClient) Authorize(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*AuthorizationToken, error) {
+	verifyResp, err := c.VerifyWithContext(ctx, payload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResp.IsValid {
+		reason := "unknown reason"
+		if verifyResp.InvalidReason != nil {
+			reason = *verifyResp.InvalidReason
+		}
+		if exactevm.IsValidityWindowReason(reason) {
+			return nil, fmt.Errorf("%w: %s: %w", ErrPaymentInvalid, reason, exactevm.ErrPossibleClockSkew)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPaymentInvalid, reason)
+	}
+
+	token := &AuthorizationToken{
+		Payload:      payload,
+		Requirements: requirements,
+		AuthorizedAt: time.Now(),
+	}
+	if verifyResp.Payer != nil {
+		token.Payer = *verifyResp.Payer
+	}
+
+	return token, nil
+}
+
+// Capture settles a payment reserved by Authorize.
+// It fails with an error matching ErrAuthorizationExpired, without contacting the facilitator,
+// if the authorization has expired since.
+func (c *FacilitatorClient) Capture(ctx context.Context, token *AuthorizationToken) (*types.SettleResponse, error) {
+	if token == nil {
+		return nil, fmt.Errorf("authorization token is nil")
+	}
+	if err := checkPayment(token.Payload, token.Requirements); err != nil {
+		return nil, err
+	}
+	if token.Payload.Payload == nil || token.Payload.Payload.Authorization == nil {
+		return nil, fmt.Errorf("%w: missing authorization", ErrIncompletePayload)
+	}
+	if err := checkNotExpired(token.Payload, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthorizationExpired, err)
+	}
+
+	return c.SettleWithContext(ctx, token.Payload, token.Requirements)
+}
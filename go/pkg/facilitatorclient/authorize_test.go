@@ -0,0 +1,95 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestAuthorizeAndCapture(t *testing.T) {
+	var valid atomic.Bool
+	valid.Store(true)
+	var settleCalls atomic.Int32
+	var invalidReason atomic.Value
+	invalidReason.Store("insufficient_funds")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payer := "0xvalidPayer"
+		switch r.URL.Path {
+		case "/verify":
+			reason := invalidReason.Load().(string)
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: valid.Load(), InvalidReason: &reason, Payer: &payer})
+		case "/settle":
+			settleCalls.Add(1)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xvalidTransaction", Network: "base-sepolia", Payer: &payer})
+		}
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	token, err := client.Authorize(context.Background(), newSchedulerTestPayload(time.Now().Add(time.Minute)), requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if token.Payer != "0xvalidPayer" {
+		t.Errorf("Expected payer 0xvalidPayer, got: %s", token.Payer)
+	}
+	if settleCalls.Load() != 0 {
+		t.Errorf("Expected Authorize not to settle, got %d settle requests", settleCalls.Load())
+	}
+
+	// The token survives being persisted between the two steps
+	encoded, err := json.Marshal(token)
+	if err != nil {
+		t.Fatalf("Failed to marshal token: %v", err)
+	}
+	var restored facilitatorclient.AuthorizationToken
+	if err := json.Unmarshal(encoded, &restored); err != nil {
+		t.Fatalf("Failed to unmarshal token: %v", err)
+	}
+
+	settleResp, err := client.Capture(context.Background(), &restored)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !settleResp.Success || settleResp.Transaction != "0xvalidTransaction" {
+		t.Errorf("Expected a successful settlement, got: %+v", settleResp)
+	}
+
+	expired, err := client.Authorize(context.Background(), newSchedulerTestPayload(time.Now().Add(time.Second)), requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expired.Payload.Payload.Authorization.ValidBefore = "1745323800"
+	if _, err := client.Capture(context.Background(), expired); !errors.Is(err, facilitatorclient.ErrAuthorizationExpired) {
+		t.Errorf("Expected %v, got: %v", facilitatorclient.ErrAuthorizationExpired, err)
+	}
+	if settleCalls.Load() != 1 {
+		t.Errorf("Expected the expired authorization not to be settled, got %d settle requests", settleCalls.Load())
+	}
+
+	valid.Store(false)
+	_, err = client.Authorize(context.Background(), newSchedulerTestPayload(time.Now().Add(time.Minute)), requirements)
+	if !errors.Is(err, facilitatorclient.ErrPaymentInvalid) {
+		t.Fatalf("Expected %v, got: %v", facilitatorclient.ErrPaymentInvalid, err)
+	}
+	if err.Error() != "payment is invalid: insufficient_funds" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+
+	invalidReason.Store(exactevm.ReasonExpired)
+	_, err = client.Authorize(context.Background(), newSchedulerTestPayload(time.Now().Add(time.Minute)), requirements)
+	if !errors.Is(err, facilitatorclient.ErrPaymentInvalid) || !errors.Is(err, exactevm.ErrPossibleClockSkew) {
+		t.Errorf("Expected %v hinting at clock skew, got: %v", facilitatorclient.ErrPaymentInvalid, err)
+	}
+}
@@ -0,0 +1,128 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the facilitator while the circuit breaker is open
+var ErrCircuitOpen = errors.New("facilitator circuit breaker is open")
+
+// cooldownJitter is the maximum fraction by which the cooldown is randomly extended,
+// so clients that tripped together don't all probe the facilitator at the same instant
+const cooldownJitter = 0.2
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker stops sending requests to a failing facilitator.
+// It opens after threshold consecutive availability failures, rejects requests during the cooldown,
+// then lets a single probe request through to decide whether to close again.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	state     circuitState
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow returns ErrCircuitOpen if the request must not be sent to the facilitator
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Now().Before(b.openUntil) {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// A probe is already in flight
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// record updates the breaker with the outcome of a request that allow let through, sent with ctx
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isAvailabilityFailure(ctx, err) {
+		if isFacilitatorAnswer(err) {
+			b.state = circuitClosed
+			b.failures = 0
+		} else if b.state == circuitHalfOpen {
+			// A cancelled or locally failed probe says nothing about the facilitator, so the next request probes again
+			b.state = circuitOpen
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.state = circuitOpen
+		jitter := time.Duration(rand.Float64() * cooldownJitter * float64(b.cooldown))
+		b.openUntil = time.Now().Add(b.cooldown + jitter)
+	}
+}
+
+// isAvailabilityFailure reports whether err means the facilitator is unavailable: the request failed in transit,
+// or the facilitator answered with a 5xx status other than 501 or a 429 status. Definitive answers such as an invalid payment or a rejected
+// request, cancellations, the expiry of the caller's own ctx deadline and errors building the request are not availability failures.
+func isAvailabilityFailure(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	// A deadline the caller set says nothing about the facilitator, unlike the client's own timeout
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		return false
+	}
+
+	var facilitatorErr *FacilitatorError
+	if errors.As(err, &facilitatorErr) {
+		// 501 is the answer of a facilitator without the endpoint, e.g. refunds, not a sign it is down
+		return (facilitatorErr.StatusCode >= http.StatusInternalServerError && facilitatorErr.StatusCode != http.StatusNotImplemented) ||
+			facilitatorErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// isFacilitatorAnswer reports whether err, which is not an availability failure, is an answer from the facilitator,
+// showing it is reachable
+func isFacilitatorAnswer(err error) bool {
+	var facilitatorErr *FacilitatorError
+	return err == nil || errors.As(err, &facilitatorErr)
+}
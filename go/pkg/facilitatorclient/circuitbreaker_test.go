@@ -0,0 +1,176 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	var (
+		requests atomic.Int32
+		healthy  atomic.Bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	cooldown := 50 * time.Millisecond
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithCircuitBreaker(2, cooldown))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+			t.Fatalf("Expected circuit to be closed on request %d", i)
+		}
+	}
+
+	// The circuit is open: requests fail fast without reaching the facilitator
+	_, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if !errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests to reach the facilitator, got: %d", requests.Load())
+	}
+
+	// After the cooldown a probe is let through and closes the circuit on success
+	healthy.Store(true)
+	time.Sleep(2 * cooldown)
+
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected probe to succeed, got: %v", err)
+	}
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected circuit to be closed, got: %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresDefinitiveFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_payload"}`))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithCircuitBreaker(1, time.Minute))
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Settle(newTestPayload(), &types.PaymentRequirements{})
+		if errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+			t.Fatalf("Expected 4xx responses not to trip the breaker")
+		}
+	}
+}
+
+func TestCircuitBreakerIgnoresCancellations(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cooldown := 50 * time.Millisecond
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithCircuitBreaker(3, cooldown))
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A cancellation between failures doesn't reset the failure count
+	client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	client.VerifyWithContext(cancelled, newTestPayload(), &types.PaymentRequirements{})
+	client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); !errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got: %v", err)
+	}
+
+	// A cancelled probe doesn't close the circuit, so the next failing probe reopens it at once
+	time.Sleep(2 * cooldown)
+	client.VerifyWithContext(cancelled, newTestPayload(), &types.PaymentRequirements{})
+	client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); !errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after the probe failed, got: %v", err)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("Expected 4 requests to reach the facilitator, got: %d", requests.Load())
+	}
+}
+
+func TestCircuitBreakerIgnoresCallerDeadlines(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithCircuitBreaker(2, time.Minute))
+
+	// Callers giving up on their own deadline don't trip the breaker
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := client.VerifyWithContext(ctx, newTestPayload(), &types.PaymentRequirements{})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded on request %d, got: %v", i, err)
+		}
+	}
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests to reach the facilitator, got: %d", requests.Load())
+	}
+
+	// The client's own timeout still does
+	timingOut := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithTimeout(10*time.Millisecond), facilitatorclient.WithCircuitBreaker(2, time.Minute))
+	for i := 0; i < 2; i++ {
+		timingOut.Verify(newTestPayload(), &types.PaymentRequirements{})
+	}
+	if _, err := timingOut.Verify(newTestPayload(), &types.PaymentRequirements{}); !errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after client timeouts, got: %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresLocalFailures(t *testing.T) {
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: "http://facilitator.test",
+		CreateAuthHeaders: func() (map[string]map[string]string, error) {
+			return nil, errors.New("no credentials")
+		},
+	}, facilitatorclient.WithCircuitBreaker(1, time.Minute))
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+		if errors.Is(err, facilitatorclient.ErrCircuitOpen) {
+			t.Fatalf("Expected auth header failures not to trip the breaker")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ReasonSettleDeadlineExceeded is the errorReason of facilitators that gave up settling at the requested deadline
+const ReasonSettleDeadlineExceeded = "settle_deadline_exceeded"
+
+// settleDeadlineGrace is how long past the deadline SettleWithDeadline waits for the facilitator's answer,
+// so a facilitator giving up at the deadline can still report it
+const settleDeadlineGrace = 5 * time.Second
+
+// ErrSettleDeadlineExceeded is matched by errors.Is when SettleWithDeadline gave up because the deadline passed
+var ErrSettleDeadlineExceeded = errors.New("settlement deadline exceeded")
+
+// SettleWithDeadline settles the payment, asking the facilitator to give up submitting it on chain after deadline,
+// e.g. to fail fast during congestion and retry later while the authorization is still valid.
+// The deadline is sent as the "deadline" field of the settle request, in Unix seconds.
+// If the facilitator reports it gave up, the unsuccessful settle response is returned with an error matching
+// ErrSettleDeadlineExceeded, which tells a timeout apart from a failed settlement, returned with no error.
+// Facilitators that don't support the field ignore it, so the client also stops waiting shortly after the deadline
+// and returns an error matching ErrSettleDeadlineExceeded; the settlement may then still land, which
+// SettleStatus can tell where the facilitator supports it.
+func (c *FacilitatorClient) SettleWithDeadline(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, deadline time.Time) (*types.SettleResponse, error) {
+	if err := checkPayment(payload, requirements); err != nil {
+		return nil, err
+	}
+	if !deadline.After(time.Now()) {
+		return nil, fmt.Errorf("%w: deadline %s has already passed", ErrSettleDeadlineExceeded, deadline.Format(time.RFC3339))
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	settleCtx, cancel := context.WithDeadline(ctx, deadline.Add(settleDeadlineGrace))
+	defer cancel()
+
+	settleResp, err := c.settle(settleCtx, payload, requirements, map[string]any{"deadline": deadline.Unix()})
+	// Giving up at the chosen deadline is the caller's decision, not a sign the facilitator is down
+	c.breaker.record(settleCtx, err)
+
+	if err != nil && ctx.Err() == nil && errors.Is(settleCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: no answer from the facilitator by %s: %v", ErrSettleDeadlineExceeded, deadline.Format(time.RFC3339), err)
+	}
+	if err == nil && !settleResp.Success && settleResp.ErrorReason != nil && *settleResp.ErrorReason == ReasonSettleDeadlineExceeded {
+		return settleResp, ErrSettleDeadlineExceeded
+	}
+
+	return settleResp, err
+}
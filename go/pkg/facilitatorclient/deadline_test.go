@@ -0,0 +1,91 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestSettleWithDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).Truncate(time.Second)
+	reason := facilitatorclient.ReasonSettleDeadlineExceeded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Deadline int64 `json:"deadline"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if body.Deadline != deadline.Unix() {
+			t.Errorf("Expected deadline %d, got: %d", deadline.Unix(), body.Deadline)
+		}
+
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: false, ErrorReason: &reason, Network: "base-sepolia"})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	settleResp, err := client.SettleWithDeadline(context.Background(), newTestPayload(), requirements, deadline)
+	if !errors.Is(err, facilitatorclient.ErrSettleDeadlineExceeded) {
+		t.Fatalf("Expected %v, got: %v", facilitatorclient.ErrSettleDeadlineExceeded, err)
+	}
+	if settleResp == nil || settleResp.Success {
+		t.Errorf("Expected the unsuccessful settle response, got: %+v", settleResp)
+	}
+
+	// Other failures are reported as before
+	reason = "insufficient_funds"
+	settleResp, err = client.SettleWithDeadline(context.Background(), newTestPayload(), requirements, deadline)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if settleResp.Success || *settleResp.ErrorReason != "insufficient_funds" {
+		t.Errorf("Expected a failed settlement, got: %+v", settleResp)
+	}
+
+	_, err = client.SettleWithDeadline(context.Background(), newTestPayload(), requirements, time.Now().Add(-time.Second))
+	if !errors.Is(err, facilitatorclient.ErrSettleDeadlineExceeded) {
+		t.Errorf("Expected a past deadline to fail with %v, got: %v", facilitatorclient.ErrSettleDeadlineExceeded, err)
+	}
+}
+
+func TestSettleWithDeadlineCircuitBreaker(t *testing.T) {
+	var answering atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if !answering.Load() {
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Network: "base-sepolia"})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithNoTimeout(), facilitatorclient.WithCircuitBreaker(1, time.Minute))
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	// The facilitator doesn't answer by the deadline and its grace period
+	_, err := client.SettleWithDeadline(context.Background(), newTestPayload(), requirements, time.Now().Add(10*time.Millisecond))
+	if !errors.Is(err, facilitatorclient.ErrSettleDeadlineExceeded) {
+		t.Fatalf("Expected %v, got: %v", facilitatorclient.ErrSettleDeadlineExceeded, err)
+	}
+
+	// Giving up at the deadline doesn't trip the breaker for other requests
+	answering.Store(true)
+	if _, err := client.Settle(newTestPayload(), requirements); err != nil {
+		t.Errorf("Expected the circuit to stay closed, got: %v", err)
+	}
+}
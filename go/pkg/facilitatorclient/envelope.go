@@ -0,0 +1,99 @@
+package facilitatorclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// RequestEncoder builds the body of a facilitator request from the fields the client sends for op,
+// e.g. "paymentPayload" and "paymentRequirements" for "verify". Implement it to adapt to facilitators
+// expecting a different envelope, such as fields nested under "data".
+type RequestEncoder interface {
+	EncodeRequest(op string, fields map[string]any) ([]byte, error)
+}
+
+// RequestEncoderFunc adapts a function to the RequestEncoder interface
+type RequestEncoderFunc func(op string, fields map[string]any) ([]byte, error)
+
+// EncodeRequest calls f(op, fields)
+func (f RequestEncoderFunc) EncodeRequest(op string, fields map[string]any) ([]byte, error) {
+	return f(op, fields)
+}
+
+// ResponseDecoder decodes a successful facilitator response for op into v.
+// Error responses are still decoded by the client into a FacilitatorError.
+type ResponseDecoder interface {
+	DecodeResponse(op string, resp *http.Response, v any) error
+}
+
+// ResponseDecoderFunc adapts a function to the ResponseDecoder interface
+type ResponseDecoderFunc func(op string, resp *http.Response, v any) error
+
+// DecodeResponse calls f(op, resp, v)
+func (f ResponseDecoderFunc) DecodeResponse(op string, resp *http.Response, v any) error {
+	return f(op, resp, v)
+}
+
+// DefaultRequestEncoder sends the fields as a flat JSON object, the envelope of the x402 facilitator API
+var DefaultRequestEncoder RequestEncoder = flatRequestEncoder{}
+
+// flatRequestEncoder is the DefaultRequestEncoder. It has its own type so the client can recognize it
+// and marshal verify requests from a typed body instead of a map.
+type flatRequestEncoder struct{}
+
+func (flatRequestEncoder) EncodeRequest(op string, fields map[string]any) ([]byte, error) {
+	return json.Marshal(fields)
+}
+
+// DefaultResponseDecoder decodes the response body as JSON. Content types that cannot hold JSON
+// and malformed bodies fail with an error matching ErrDecode.
+var DefaultResponseDecoder ResponseDecoder = ResponseDecoderFunc(decodeResponse)
+
+// OrderedRequestEncoder returns a RequestEncoder sending the fields as a flat JSON object like
+// DefaultRequestEncoder, but in a stable order: the fields named in order first, in that order,
+// followed by the others sorted by name. This is for facilitators signing or HMACing the exact request bytes.
+// Only the top-level fields are ordered; nested objects such as the payment payload keep their struct field order.
+func OrderedRequestEncoder(order []string) RequestEncoder {
+	order = slices.Clone(order)
+
+	return RequestEncoderFunc(func(op string, fields map[string]any) ([]byte, error) {
+		keys := make([]string, 0, len(fields))
+		for _, key := range order {
+			if _, ok := fields[key]; ok && !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+		rest := make([]string, 0, len(fields)-len(keys))
+		for key := range fields {
+			if !slices.Contains(keys, key) {
+				rest = append(rest, key)
+			}
+		}
+		slices.Sort(rest)
+		keys = append(keys, rest...)
+
+		var body bytes.Buffer
+		body.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				body.WriteByte(',')
+			}
+			name, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := json.Marshal(fields[key])
+			if err != nil {
+				return nil, err
+			}
+			body.Write(name)
+			body.WriteByte(':')
+			body.Write(value)
+		}
+		body.WriteByte('}')
+
+		return body.Bytes(), nil
+	})
+}
@@ -0,0 +1,96 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestCustomEnvelope(t *testing.T) {
+	var received map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"success":true,"transaction":"0xtx","network":"base"}}`))
+	}))
+	defer server.Close()
+
+	encoder := facilitatorclient.RequestEncoderFunc(func(op string, fields map[string]any) ([]byte, error) {
+		return json.Marshal(map[string]any{"version": "2025-01", "op": op, "data": fields})
+	})
+	decoder := facilitatorclient.ResponseDecoderFunc(func(op string, resp *http.Response, v any) error {
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+			return err
+		}
+		return json.Unmarshal(envelope.Data, v)
+	})
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithRequestEncoder(encoder), facilitatorclient.WithResponseDecoder(decoder))
+	resp, err := client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !resp.Success || resp.Transaction != "0xtx" {
+		t.Errorf("Expected the enveloped settle response, got: %+v", resp)
+	}
+
+	if string(received["op"]) != `"settle"` || string(received["version"]) != `"2025-01"` {
+		t.Errorf("Expected the custom envelope, got: %v", received)
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(received["data"], &data); err != nil || data["paymentPayload"] == nil {
+		t.Errorf("Expected the payment nested under data, got: %s", received["data"])
+	}
+}
+
+func TestDefaultEnvelope(t *testing.T) {
+	body, err := facilitatorclient.DefaultRequestEncoder.EncodeRequest("verify", map[string]any{"x402Version": 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if string(body) != `{"x402Version":1}` {
+		t.Errorf("Expected a flat JSON object, got: %s", body)
+	}
+}
+
+func TestBodyFieldOrder(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtx", Network: "base-sepolia"})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithBodyFieldOrder([]string{"x402Version", "paymentRequirements", "unknown", "paymentPayload"}))
+	payload := newTestPayload()
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: types.NetworkBaseSepolia}
+
+	if _, err := client.SettleWithMetadata(context.Background(), payload, requirements, map[string]any{"orderId": "42", "cart": "7"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	requirementsJSON, _ := json.Marshal(requirements)
+	payloadJSON, _ := json.Marshal(payload)
+	expected := `{"x402Version":1,"paymentRequirements":` + string(requirementsJSON) + `,"paymentPayload":` + string(payloadJSON) + `,"cart":"7","orderId":"42"}`
+	if string(received) != expected {
+		t.Errorf("Expected %s, got: %s", expected, received)
+	}
+
+	if _, err := client.Verify(payload, requirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.HasPrefix(string(received), `{"x402Version":1,"paymentRequirements":`) {
+		t.Errorf("Expected verify requests to be ordered too, got: %s", received)
+	}
+}
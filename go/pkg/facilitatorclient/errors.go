@@ -0,0 +1,162 @@
+package facilitatorclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// maxErrorBodySize bounds how much of a non-200 response body is read when decoding an error
+const maxErrorBodySize = 64 << 10
+
+var (
+	// ErrNilPayload is returned without contacting the facilitator when the payment payload is nil
+	ErrNilPayload = errors.New("payment payload is nil")
+	// ErrNilRequirements is returned without contacting the facilitator when the payment requirements are nil
+	ErrNilRequirements = errors.New("payment requirements are nil")
+	// ErrIncompletePayload is matched by errors.Is when the payment payload lacks its scheme or network,
+	// and is returned without contacting the facilitator
+	ErrIncompletePayload = errors.New("payment payload is incomplete")
+)
+
+// ErrDecode is matched by errors.Is when a facilitator response cannot be decoded
+var ErrDecode = errors.New("failed to decode facilitator response")
+
+// DecodeError is returned when a facilitator response has an unexpected content type or malformed body
+type DecodeError struct {
+	// Op is the facilitator operation whose response could not be decoded
+	Op          string
+	ContentType string
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s response (content type %q): %v", e.Op, e.ContentType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// decodeResponse decodes a successful facilitator response into v, rejecting content types that cannot hold JSON.
+// text/plain is accepted because that is what servers sniff for a JSON body written without a Content-Type.
+func decodeResponse(op string, resp *http.Response, v any) error {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return &DecodeError{Op: op, ContentType: contentType, Err: err}
+		}
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") && mediaType != "text/plain" {
+			return &DecodeError{Op: op, ContentType: contentType, Err: fmt.Errorf("unexpected content type %s", mediaType)}
+		}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &DecodeError{Op: op, ContentType: contentType, Err: err}
+	}
+
+	return nil
+}
+
+// checkPayment returns an error if the payload or requirements are missing, or the payload lacks a field every
+// facilitator needs, including the signature and authorization fields of EVM payloads, so programming errors
+// fail immediately instead of as a confusing facilitator 400
+func checkPayment(payload *types.PaymentPayload, requirements *types.PaymentRequirements) error {
+	switch {
+	case payload == nil:
+		return ErrNilPayload
+	case requirements == nil:
+		return ErrNilRequirements
+	case payload.Scheme == "":
+		return fmt.Errorf("%w: missing scheme", ErrIncompletePayload)
+	case payload.Network == "":
+		return fmt.Errorf("%w: missing network", ErrIncompletePayload)
+	case types.IsSvmNetwork(payload.Network):
+		// Solana payloads are kept raw, for the facilitator to check
+		return nil
+	case payload.Payload == nil:
+		return fmt.Errorf("%w: missing payload", ErrIncompletePayload)
+	case payload.Payload.Signature == "":
+		return fmt.Errorf("%w: missing signature", ErrIncompletePayload)
+	case payload.Payload.Authorization == nil:
+		return fmt.Errorf("%w: missing authorization", ErrIncompletePayload)
+	}
+
+	authorization := payload.Payload.Authorization
+	for _, field := range []struct{ name, value string }{
+		{"from", authorization.From},
+		{"to", authorization.To},
+		{"value", authorization.Value},
+		{"validAfter", authorization.ValidAfter},
+		{"validBefore", authorization.ValidBefore},
+		{"nonce", authorization.Nonce},
+	} {
+		if field.value == "" {
+			return fmt.Errorf("%w: missing authorization %s", ErrIncompletePayload, field.name)
+		}
+	}
+
+	return nil
+}
+
+// FacilitatorError is returned when the facilitator responds with a non-200 status code
+type FacilitatorError struct {
+	// Op is the facilitator operation that failed (e.g. "verify", "settle", "supported", "status" or "list")
+	Op         string
+	StatusCode int
+	Status     string
+
+	// Response is the decoded x402 error body, or nil if the body did not match the standard shape
+	Response *types.ErrorResponse
+}
+
+func (e *FacilitatorError) Error() string {
+	action := e.Op + " payment"
+	switch e.Op {
+	case "supported":
+		action = "fetch supported payment kinds"
+	case "status":
+		action = "fetch settlement status"
+	case "list":
+		action = "list discovery resources"
+	}
+
+	if e.Response != nil {
+		return fmt.Sprintf("failed to %s: %s: %s", action, e.Status, e.Response.Error)
+	}
+	return fmt.Sprintf("failed to %s: %s", action, e.Status)
+}
+
+// newFacilitatorError builds a FacilitatorError from a non-200 response, decoding the body
+// into a types.ErrorResponse when it matches the standard x402 error shape
+func newFacilitatorError(op string, resp *http.Response) *FacilitatorError {
+	facilitatorErr := &FacilitatorError{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return facilitatorErr
+	}
+
+	var errorResp types.ErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err != nil || errorResp.Error == "" {
+		return facilitatorErr
+	}
+	facilitatorErr.Response = &errorResp
+
+	return facilitatorErr
+}
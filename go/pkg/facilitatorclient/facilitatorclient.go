@@ -2,9 +2,19 @@ package facilitatorclient
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/sync/singleflight"
 
 	"github.com/coinbase/x402/go/pkg/types"
 )
@@ -12,51 +22,411 @@ import (
 // DefaultFacilitatorURL is the default URL for the x402 facilitator service
 const DefaultFacilitatorURL = "https://x402.org/facilitator"
 
+// DefaultTimeout is the default timeout for requests to the facilitator
+const DefaultTimeout = 30 * time.Second
+
+// DefaultAccept is the default Accept header sent to the facilitator
+const DefaultAccept = "application/json"
+
+// hexAddressPattern matches a 0x-prefixed 20-byte hex address
+var hexAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// FacilitatorClientOptions is the options for the FacilitatorClient.
+type FacilitatorClientOptions struct {
+	Timeout                  time.Duration
+	NoTimeout                bool
+	MaxConcurrentSettlements int
+	CircuitBreakerThreshold  int
+	CircuitBreakerCooldown   time.Duration
+	HTTP1Only                bool
+	ProxyURL                 string
+	ResponseHeaderTimeout    time.Duration
+	VerifyCacheTTL           time.Duration
+	VerifyCacheStore         VerifyCacheStore
+	Accept                   string
+	SettleConfirmations      int
+	FeePayer                 string
+	SettlementAccount        string
+	Singleflight             bool
+	RequestEncoder           RequestEncoder
+	ResponseDecoder          ResponseDecoder
+}
+
+// Options is the type for the options for the FacilitatorClient.
+type Options func(*FacilitatorClientOptions)
+
+// WithTimeout is an option for the FacilitatorClient to set the request timeout.
+// A zero duration uses DefaultTimeout; use WithNoTimeout to disable the timeout.
+func WithTimeout(timeout time.Duration) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.Timeout = timeout
+		options.NoTimeout = false
+	}
+}
+
+// WithNoTimeout is an option for the FacilitatorClient to disable the request timeout.
+// Requests to an unresponsive facilitator will then block until their context is cancelled.
+func WithNoTimeout() Options {
+	return func(options *FacilitatorClientOptions) {
+		options.Timeout = 0
+		options.NoTimeout = true
+	}
+}
+
+// WithMaxConcurrentSettlements is an option for the FacilitatorClient to bound the number of
+// settlements SettleAsync runs at once. Defaults to DefaultMaxConcurrentSettlements.
+func WithMaxConcurrentSettlements(n int) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.MaxConcurrentSettlements = n
+	}
+}
+
+// WithCircuitBreaker is an option for the FacilitatorClient to stop contacting a failing facilitator.
+// After threshold consecutive availability failures (network errors, timeouts, 5xx and 429 responses)
+// requests fail fast with ErrCircuitOpen for the cooldown, after which a single probe request decides
+// whether to resume. Definitive answers such as invalid payments or 4xx responses don't trip the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.CircuitBreakerThreshold = threshold
+		options.CircuitBreakerCooldown = cooldown
+	}
+}
+
+// WithHTTP1Only is an option for the FacilitatorClient to disable HTTP/2, for facilitators that misbehave on h2.
+// By default HTTP/2 is negotiated over TLS.
+func WithHTTP1Only() Options {
+	return func(options *FacilitatorClientOptions) {
+		options.HTTP1Only = true
+	}
+}
+
+// WithProxy is an option for the FacilitatorClient to send requests through the given proxy URL.
+// By default the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.ProxyURL = proxyURL
+	}
+}
+
+// WithResponseHeaderTimeout is an option for the FacilitatorClient to fail a request when the facilitator
+// hasn't sent the response headers within timeout after the request was written, so a facilitator that accepts
+// connections but never answers fails faster than the overall request timeout. A slow body is still bounded
+// only by the request timeout.
+func WithResponseHeaderTimeout(timeout time.Duration) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithVerifyCache is an option for the FacilitatorClient to cache successful verifications for ttl,
+// so retries carrying an identical payment skip the facilitator round-trip.
+// Failed verifications are never cached, and entries expire no later than the authorization's validBefore.
+// If store is nil, an in-memory store is used.
+func WithVerifyCache(ttl time.Duration, store VerifyCacheStore) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.VerifyCacheTTL = ttl
+		options.VerifyCacheStore = store
+	}
+}
+
+// WithAccept is an option for the FacilitatorClient to set the Accept header sent with every request,
+// e.g. to negotiate a versioned JSON media type. Responses must still be JSON; any other content type
+// fails with an error matching ErrDecode.
+func WithAccept(accept string) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.Accept = accept
+	}
+}
+
+// WithSettleConfirmations is an option for the FacilitatorClient to ask the facilitator to wait for
+// n block confirmations before responding to a settle request, trading latency for finality.
+// Without it, or with n <= 0, the facilitator's default confirmation behavior applies.
+func WithSettleConfirmations(n int) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.SettleConfirmations = n
+	}
+}
+
+// WithFeePayer is an option for the FacilitatorClient to ask the facilitator to have the given relayer
+// address pay the gas of settlements, e.g. to account for sponsored gas separately. The facilitator reports
+// who paid in SettleResponse.FeePayer. Without it the facilitator chooses the fee payer.
+// NewFacilitatorClient panics if feePayer is not a hex address.
+func WithFeePayer(feePayer string) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.FeePayer = feePayer
+	}
+}
+
+// WithSettlementAccount is an option for the FacilitatorClient to have a multi-tenant facilitator settle through
+// the given settling account or label, so funds are routed to and accounted for the right tenant. It is sent as
+// settlementAccount in settle requests; without it the field is omitted and the facilitator uses its only account.
+// ValidateRequirements checks it against the format the facilitator advertises, if any.
+// NewFacilitatorClient panics if account contains whitespace.
+func WithSettlementAccount(account string) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.SettlementAccount = account
+	}
+}
+
+// WithSingleflight is an option for the FacilitatorClient to share one facilitator round-trip between
+// concurrent verifications of an identical payment, e.g. a burst of retries carrying the same X-PAYMENT.
+// Each caller receives its own copy of the result. The shared request isn't cancelled with the context of
+// the caller that started it: it runs until it completes or the client timeout expires, while each caller
+// stops waiting when its own context is done.
+func WithSingleflight() Options {
+	return func(options *FacilitatorClientOptions) {
+		options.Singleflight = true
+	}
+}
+
+// WithRequestEncoder is an option for the FacilitatorClient to build request bodies with encoder,
+// for facilitators expecting a different envelope. Defaults to DefaultRequestEncoder.
+func WithRequestEncoder(encoder RequestEncoder) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.RequestEncoder = encoder
+	}
+}
+
+// WithBodyFieldOrder is an option for the FacilitatorClient to send the top-level fields of verify and settle
+// request bodies in the given order, followed by any others sorted by name, for facilitators that sign or verify
+// the exact body bytes. It is WithRequestEncoder(OrderedRequestEncoder(order)), so the last of the two applies.
+// By default fields are sent in DefaultRequestEncoder's order, which is sorted by name.
+func WithBodyFieldOrder(order []string) Options {
+	return WithRequestEncoder(OrderedRequestEncoder(order))
+}
+
+// WithResponseDecoder is an option for the FacilitatorClient to decode successful responses with decoder,
+// the counterpart of WithRequestEncoder. Defaults to DefaultResponseDecoder.
+// SettleStream only uses it for responses that are not a stream.
+func WithResponseDecoder(decoder ResponseDecoder) Options {
+	return func(options *FacilitatorClientOptions) {
+		options.ResponseDecoder = decoder
+	}
+}
+
 // FacilitatorClient represents a facilitator client for verifying and settling payments
 type FacilitatorClient struct {
 	URL               string
 	HTTPClient        *http.Client
 	CreateAuthHeaders func() (map[string]map[string]string, error)
+
+	maxConcurrentSettlements int
+	breaker                  *circuitBreaker
+	verifyCache              *verifyCache
+	accept                   string
+	settleConfirmations      int
+	feePayer                 string
+	settlementAccount        string
+	verifyGroup              *singleflight.Group
+	requestEncoder           RequestEncoder
+	responseDecoder          ResponseDecoder
+	settleSlotsOnce          sync.Once
+	settleSlots              chan struct{}
+	supportedMu              sync.Mutex
+	supportedKinds           *types.SupportedResponse
 }
 
-// NewFacilitatorClient creates a new facilitator client
-func NewFacilitatorClient(config *types.FacilitatorConfig) *FacilitatorClient {
+// NewFacilitatorClient creates a new facilitator client.
+// Requests time out after DefaultTimeout unless a timeout is set by the config or the options.
+// It panics if the resulting timeout is negative, or the proxy URL, fee payer or settlement account is invalid.
+func NewFacilitatorClient(config *types.FacilitatorConfig, opts ...Options) *FacilitatorClient {
 	if config == nil {
 		config = &types.FacilitatorConfig{
 			URL: DefaultFacilitatorURL,
 		}
 	}
 
-	httpCli := &http.Client{}
+	options := &FacilitatorClientOptions{
+		Accept:          DefaultAccept,
+		RequestEncoder:  DefaultRequestEncoder,
+		ResponseDecoder: DefaultResponseDecoder,
+	}
 	if config.Timeout != nil {
-		httpCli.Timeout = config.Timeout()
+		options.Timeout = config.Timeout()
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.Timeout < 0 {
+		panic(fmt.Sprintf("facilitatorclient: negative timeout %s", options.Timeout))
+	}
+	if options.FeePayer != "" && !hexAddressPattern.MatchString(options.FeePayer) {
+		panic(fmt.Sprintf("facilitatorclient: invalid fee payer address %q", options.FeePayer))
+	}
+	if strings.ContainsFunc(options.SettlementAccount, unicode.IsSpace) {
+		panic(fmt.Sprintf("facilitatorclient: invalid settlement account %q", options.SettlementAccount))
+	}
+
+	httpCli := &http.Client{
+		Timeout:   options.Timeout,
+		Transport: sharedTransport(options),
+	}
+	if httpCli.Timeout == 0 && !options.NoTimeout {
+		httpCli.Timeout = DefaultTimeout
+	}
+
+	client := &FacilitatorClient{
+		URL:                      config.URL,
+		HTTPClient:               httpCli,
+		CreateAuthHeaders:        config.CreateAuthHeaders,
+		maxConcurrentSettlements: options.MaxConcurrentSettlements,
+		accept:                   options.Accept,
+		settleConfirmations:      options.SettleConfirmations,
+		feePayer:                 options.FeePayer,
+		settlementAccount:        options.SettlementAccount,
+		requestEncoder:           options.RequestEncoder,
+		responseDecoder:          options.ResponseDecoder,
+	}
+	if options.Singleflight {
+		client.verifyGroup = &singleflight.Group{}
+	}
+	if options.CircuitBreakerThreshold > 0 {
+		client.breaker = newCircuitBreaker(options.CircuitBreakerThreshold, options.CircuitBreakerCooldown)
+	}
+	if options.VerifyCacheTTL > 0 {
+		store := options.VerifyCacheStore
+		if store == nil {
+			store = NewMemoryVerifyCacheStore()
+		}
+		client.verifyCache = &verifyCache{ttl: options.VerifyCacheTTL, store: store}
+	}
+
+	return client
+}
+
+// transportKey is the set of options that shape a client transport
+type transportKey struct {
+	proxyURL              string
+	responseHeaderTimeout time.Duration
+	http1Only             bool
+}
+
+// sharedTransports are the transports of all clients, one per transportKey, so clients created per request
+// reuse pooled connections instead of each opening and abandoning their own
+var (
+	sharedTransportsMu sync.Mutex
+	sharedTransports   = make(map[transportKey]*http.Transport)
+)
+
+// sharedTransport returns the transport shared by clients with the same transport options
+func sharedTransport(options *FacilitatorClientOptions) *http.Transport {
+	key := transportKey{
+		proxyURL:              options.ProxyURL,
+		responseHeaderTimeout: options.ResponseHeaderTimeout,
+		http1Only:             options.HTTP1Only,
+	}
+
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+
+	transport, ok := sharedTransports[key]
+	if !ok {
+		transport = newTransport(options)
+		sharedTransports[key] = transport
+	}
+
+	return transport
+}
+
+// newTransport creates a transport from the default transport, which attempts HTTP/2 over TLS
+// and honors the proxy environment variables
+func newTransport(options *FacilitatorClientOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			panic(fmt.Sprintf("facilitatorclient: invalid proxy URL %q", options.ProxyURL))
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
 	}
 
-	return &FacilitatorClient{
-		URL:               config.URL,
-		HTTPClient:        httpCli,
-		CreateAuthHeaders: config.CreateAuthHeaders,
+	if options.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = options.ResponseHeaderTimeout
 	}
+
+	if options.HTTP1Only {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport
 }
 
 // Verify sends a payment verification request to the facilitator
 func (c *FacilitatorClient) Verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
-	reqBody := map[string]any{
-		"x402Version":         1,
-		"paymentPayload":      payload,
-		"paymentRequirements": requirements,
+	return c.VerifyWithContext(context.Background(), payload, requirements)
+}
+
+// VerifyWithContext sends a payment verification request to the facilitator, bound to the given context.
+// Nil payloads or requirements, and payloads without a scheme or network, fail with ErrNilPayload,
+// ErrNilRequirements or ErrIncompletePayload before any request is made; the same applies to settling.
+func (c *FacilitatorClient) VerifyWithContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	if err := checkPayment(payload, requirements); err != nil {
+		return nil, err
+	}
+
+	if cached, ok := c.verifyCache.get(payload, requirements); ok {
+		return cached, nil
+	}
+
+	if c.verifyGroup != nil {
+		if key, ok := verifyCacheKey(payload, requirements); ok {
+			// The shared flight outlives any one caller, bounded by the client timeout, and each caller
+			// stops waiting when its own context is done
+			flight := c.verifyGroup.DoChan(key, func() (any, error) {
+				return c.verifyUncached(context.WithoutCancel(ctx), payload, requirements)
+			})
+			select {
+			case result := <-flight:
+				if result.Err != nil {
+					return nil, result.Err
+				}
+				return cloneVerifyResponse(result.Val.(*types.VerifyResponse)), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return c.verifyUncached(ctx, payload, requirements)
+}
+
+// verifyUncached sends the verify request through the circuit breaker and caches a valid result
+func (c *FacilitatorClient) verifyUncached(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	verifyResp, err := c.verify(ctx, payload, requirements)
+	c.breaker.record(ctx, err)
+	if err == nil {
+		c.verifyCache.set(payload, requirements, verifyResp)
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
+	return verifyResp, err
+}
+
+func (c *FacilitatorClient) verify(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, error) {
+	jsonBody, err := c.encodeVerifyRequest(&verifyRequest{
+		PaymentPayload:      payload,
+		PaymentRequirements: requirements,
+		X402Version:         types.X402Version,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/verify", c.URL), bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/verify", c.URL), bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", c.accept)
 
 	// Add auth headers if available
 	if c.CreateAuthHeaders != nil {
@@ -78,35 +448,138 @@ func (c *FacilitatorClient) Verify(payload *types.PaymentPayload, requirements *
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to verify payment: %s", resp.Status)
+		return nil, newFacilitatorError("verify", resp)
 	}
 
 	var verifyResp types.VerifyResponse
-	if err := json.NewDecoder(resp.Body).Decode(&verifyResp); err != nil {
-		return nil, fmt.Errorf("failed to decode verify response: %w", err)
+	if err := c.responseDecoder.DecodeResponse("verify", resp, &verifyResp); err != nil {
+		return nil, err
 	}
 
 	return &verifyResp, nil
 }
 
+// verifyRequest is the body of a verify request in the x402 facilitator API
+type verifyRequest struct {
+	PaymentPayload      *types.PaymentPayload      `json:"paymentPayload"`
+	PaymentRequirements *types.PaymentRequirements `json:"paymentRequirements"`
+	X402Version         int                        `json:"x402Version"`
+}
+
+// encodeVerifyRequest marshals body directly with the default encoder,
+// and otherwise hands its fields to the configured RequestEncoder
+func (c *FacilitatorClient) encodeVerifyRequest(body *verifyRequest) ([]byte, error) {
+	if _, ok := c.requestEncoder.(flatRequestEncoder); ok {
+		return json.Marshal(body)
+	}
+
+	return c.requestEncoder.EncodeRequest("verify", map[string]any{
+		"x402Version":         body.X402Version,
+		"paymentPayload":      body.PaymentPayload,
+		"paymentRequirements": body.PaymentRequirements,
+	})
+}
+
 // Settle sends a payment settlement request to the facilitator
 func (c *FacilitatorClient) Settle(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return c.SettleWithContext(context.Background(), payload, requirements)
+}
+
+// SettleWithContext sends a payment settlement request to the facilitator, bound to the given context
+func (c *FacilitatorClient) SettleWithContext(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	return c.SettleWithMetadata(ctx, payload, requirements, nil)
+}
+
+// settleRequestFields are the protocol fields of a settle request that metadata cannot overwrite
+var settleRequestFields = map[string]bool{
+	"x402Version":         true,
+	"paymentPayload":      true,
+	"paymentRequirements": true,
+	"confirmations":       true,
+	"feePayer":            true,
+	"deadline":            true,
+	"settlementAccount":   true,
+}
+
+// SettleWithMetadata sends a payment settlement request carrying extra top-level fields, such as an
+// order ID for reconciliation. Facilitators that support metadata may echo it in SettleResponse.Metadata;
+// the facilitator must tolerate unknown fields in the request body, or the settlement will be rejected.
+// Metadata keys that collide with a protocol field return an error instead of overwriting it.
+func (c *FacilitatorClient) SettleWithMetadata(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, meta map[string]any) (*types.SettleResponse, error) {
+	if err := checkPayment(payload, requirements); err != nil {
+		return nil, err
+	}
+	for key := range meta {
+		if settleRequestFields[key] {
+			return nil, fmt.Errorf("metadata key %q collides with a settle request field", key)
+		}
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	settleResp, err := c.settle(ctx, payload, requirements, meta)
+	c.breaker.record(ctx, err)
+
+	return settleResp, err
+}
+
+func (c *FacilitatorClient) settle(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, meta map[string]any) (*types.SettleResponse, error) {
+	req, err := c.newSettleRequest(ctx, payload, requirements, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send settle request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newFacilitatorError("settle", resp)
+	}
+
+	var settleResp types.SettleResponse
+	if err := c.responseDecoder.DecodeResponse("settle", resp, &settleResp); err != nil {
+		return nil, err
+	}
+
+	return &settleResp, nil
+}
+
+// newSettleRequest builds the settle request, merging any metadata into the body
+func (c *FacilitatorClient) newSettleRequest(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, meta map[string]any) (*http.Request, error) {
 	reqBody := map[string]any{
-		"x402Version":         1,
+		"x402Version":         types.X402Version,
 		"paymentPayload":      payload,
 		"paymentRequirements": requirements,
 	}
+	if c.settleConfirmations > 0 {
+		reqBody["confirmations"] = c.settleConfirmations
+	}
+	if c.feePayer != "" {
+		reqBody["feePayer"] = c.feePayer
+	}
+	if c.settlementAccount != "" {
+		reqBody["settlementAccount"] = c.settlementAccount
+	}
+	for key, value := range meta {
+		reqBody[key] = value
+	}
 
-	jsonBody, err := json.Marshal(reqBody)
+	jsonBody, err := c.requestEncoder.EncodeRequest("settle", reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/settle", c.URL), bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/settle", c.URL), bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", c.accept)
 
 	// Add auth headers if available
 	if c.CreateAuthHeaders != nil {
@@ -121,20 +594,14 @@ func (c *FacilitatorClient) Settle(payload *types.PaymentPayload, requirements *
 		}
 	}
 
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send settle request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to settle payment: %s", resp.Status)
-	}
-
-	var settleResp types.SettleResponse
-	if err := json.NewDecoder(resp.Body).Decode(&settleResp); err != nil {
-		return nil, fmt.Errorf("failed to decode settle response: %w", err)
-	}
+	return req, nil
+}
 
-	return &settleResp, nil
+// VerifyAndSettle verifies the payment and, if it is valid, settles it.
+// A single context deadline covers both legs: if verification consumes it, settlement is not attempted
+// and the context error is returned alongside the verified result.
+// If the payment is invalid, the result has a nil settle response and there is no error.
+// The result carries both responses, the payer and the duration of each leg; it is never nil.
+func (c *FacilitatorClient) VerifyAndSettle(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.PaymentResult, error) {
+	return c.ProcessPayment(ctx, payload, requirements, ProcessHooks{})
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,6 +16,27 @@ import (
 	"github.com/coinbase/x402/go/pkg/types"
 )
 
+// newTestPayload returns a payload carrying the fields checked before contacting the facilitator,
+// for tests whose facilitator ignores the payment
+func newTestPayload() *types.PaymentPayload {
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      "exact",
+		Network:     types.NetworkBaseSepolia,
+		Payload: &types.ExactEvmPayload{
+			Signature: "0xsignature",
+			Authorization: &types.ExactEvmPayloadAuthorization{
+				From:        "0xfrom",
+				To:          "0xto",
+				Value:       "1000",
+				ValidAfter:  "0",
+				ValidBefore: "9999999999",
+				Nonce:       "0xnonce",
+			},
+		},
+	}
+}
+
 func TestVerify(t *testing.T) {
 	// Create test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -166,7 +190,7 @@ func TestTimeout(t *testing.T) {
 	client := facilitatorclient.NewFacilitatorClient(config)
 
 	// Test data
-	paymentPayload := &types.PaymentPayload{}
+	paymentPayload := newTestPayload()
 	paymentRequirements := &types.PaymentRequirements{}
 
 	// Test verify with timeout
@@ -319,3 +343,637 @@ func TestSettleWithAuthHeaders(t *testing.T) {
 		t.Errorf("Expected auth header '%s', got: '%s'", expectedAuthHeader, capturedAuthHeader)
 	}
 }
+
+func TestVerifyErrorResponse(t *testing.T) {
+	// Create test server that returns a standard x402 error body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_payload","details":{"field":"signature"}}`))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+
+	_, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if err == nil {
+		t.Fatal("Expected error, got err == nil")
+	}
+
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if !errors.As(err, &facilitatorErr) {
+		t.Fatalf("Expected FacilitatorError, got: %T", err)
+	}
+	if facilitatorErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got: %d", http.StatusBadRequest, facilitatorErr.StatusCode)
+	}
+	if facilitatorErr.Response == nil {
+		t.Fatal("Expected decoded error response, got nil")
+	}
+	if facilitatorErr.Response.Error != "invalid_payload" {
+		t.Errorf("Expected error 'invalid_payload', got: %s", facilitatorErr.Response.Error)
+	}
+	if facilitatorErr.Response.Details == nil || string(*facilitatorErr.Response.Details) != `{"field":"signature"}` {
+		t.Errorf("Expected details to be preserved, got: %v", facilitatorErr.Response.Details)
+	}
+}
+
+func TestSettleErrorResponseFallback(t *testing.T) {
+	// Create test server that returns a body that doesn't match the error shape
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream unavailable"))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+
+	_, err := client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if err == nil {
+		t.Fatal("Expected error, got err == nil")
+	}
+
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if !errors.As(err, &facilitatorErr) {
+		t.Fatalf("Expected FacilitatorError, got: %T", err)
+	}
+	if facilitatorErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected status code %d, got: %d", http.StatusBadGateway, facilitatorErr.StatusCode)
+	}
+	if facilitatorErr.Response != nil {
+		t.Errorf("Expected no decoded error response, got: %+v", facilitatorErr.Response)
+	}
+	if err.Error() != "failed to settle payment: 502 Bad Gateway" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+func TestTimeoutOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   *types.FacilitatorConfig
+		opts     []facilitatorclient.Options
+		expected time.Duration
+	}{
+		{
+			name:     "default",
+			config:   &types.FacilitatorConfig{},
+			expected: facilitatorclient.DefaultTimeout,
+		},
+		{
+			name:     "zero timeout uses default",
+			config:   &types.FacilitatorConfig{},
+			opts:     []facilitatorclient.Options{facilitatorclient.WithTimeout(0)},
+			expected: facilitatorclient.DefaultTimeout,
+		},
+		{
+			name: "zero config timeout uses default",
+			config: &types.FacilitatorConfig{
+				Timeout: func() time.Duration { return 0 },
+			},
+			expected: facilitatorclient.DefaultTimeout,
+		},
+		{
+			name:     "explicit timeout",
+			config:   &types.FacilitatorConfig{},
+			opts:     []facilitatorclient.Options{facilitatorclient.WithTimeout(5 * time.Second)},
+			expected: 5 * time.Second,
+		},
+		{
+			name: "option overrides config",
+			config: &types.FacilitatorConfig{
+				Timeout: func() time.Duration { return time.Second },
+			},
+			opts:     []facilitatorclient.Options{facilitatorclient.WithTimeout(2 * time.Second)},
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "no timeout",
+			config:   &types.FacilitatorConfig{},
+			opts:     []facilitatorclient.Options{facilitatorclient.WithNoTimeout()},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := facilitatorclient.NewFacilitatorClient(tc.config, tc.opts...)
+			if client.HTTPClient.Timeout != tc.expected {
+				t.Errorf("Expected timeout %s, got: %s", tc.expected, client.HTTPClient.Timeout)
+			}
+		})
+	}
+}
+
+func TestNegativeTimeoutPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for negative timeout")
+		}
+	}()
+
+	facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{}, facilitatorclient.WithTimeout(-time.Second))
+}
+
+func TestVerifyFromJSON(t *testing.T) {
+	var capturedBody map[string]json.RawMessage
+
+	// Create test server that captures the request body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&capturedBody)
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+
+	payloadJSON := []byte(`{"x402Version":1,"scheme":"exact","network":"base-sepolia","payload":{"signature":"0xvalidSignature","authorization":{"from":"0xfrom","to":"0xto","value":"1000000","validAfter":"0","validBefore":"9999999999","nonce":"0xnonce"}}}`)
+	requirementsJSON := []byte(`{"scheme":"exact","network":"base-sepolia","maxAmountRequired":"1000000"}`)
+
+	resp, err := facilitatorclient.VerifyFromJSON(client, payloadJSON, requirementsJSON)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !resp.IsValid {
+		t.Errorf("Expected valid response, got invalid")
+	}
+	if _, ok := capturedBody["paymentRequirements"]; !ok {
+		t.Errorf("Expected paymentRequirements to be sent, got: %v", capturedBody)
+	}
+}
+
+func TestSettleFromJSONParseError(t *testing.T) {
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: "http://127.0.0.1:0",
+	})
+
+	_, err := facilitatorclient.SettleFromJSON(client, []byte(`{"x402Version":1}`), []byte(`not json`))
+
+	var parseErr *facilitatorclient.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected ParseError, got: %v", err)
+	}
+	if parseErr.Field != "paymentRequirements" {
+		t.Errorf("Expected field 'paymentRequirements', got: %s", parseErr.Field)
+	}
+
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if errors.As(err, &facilitatorErr) {
+		t.Errorf("Expected parse error not to be a FacilitatorError")
+	}
+}
+
+// slowVerifyTransport answers /verify successfully only after the request context has expired,
+// simulating a verify leg that consumes the whole deadline
+type slowVerifyTransport struct {
+	settleCalls int
+}
+
+func (t *slowVerifyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	switch req.URL.Path {
+	case "/verify":
+		<-req.Context().Done()
+		json.NewEncoder(recorder).Encode(types.VerifyResponse{IsValid: true})
+	case "/settle":
+		t.settleCalls++
+		json.NewEncoder(recorder).Encode(types.SettleResponse{Success: true})
+	}
+
+	return recorder.Result(), nil
+}
+
+func TestVerifyAndSettleSharedDeadline(t *testing.T) {
+	transport := &slowVerifyTransport{}
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: "http://facilitator.test",
+	})
+	client.HTTPClient.Transport = transport
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	result, err := client.VerifyAndSettle(ctx, newTestPayload(), &types.PaymentRequirements{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context deadline exceeded error, got: %v", err)
+	}
+	if result.Verify == nil || !result.Verify.IsValid {
+		t.Errorf("Expected verify leg to complete, got: %+v", result.Verify)
+	}
+	if result.Settle != nil || result.Settled() || result.SettleDuration != 0 {
+		t.Errorf("Expected no settle response, got: %+v", result.Settle)
+	}
+	if transport.settleCalls != 0 {
+		t.Errorf("Expected settle to be skipped, got %d calls", transport.settleCalls)
+	}
+}
+
+func TestVerifyAndSettle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			payer := "0xvalidPayer"
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: &payer})
+		case "/settle":
+			time.Sleep(time.Millisecond)
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xvalidTransaction"})
+		}
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+	result, err := client.VerifyAndSettle(ctx, newTestPayload(), requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Settled() || result.Settle.Transaction != "0xvalidTransaction" {
+		t.Errorf("Expected settle response, got: %+v", result.Settle)
+	}
+	if result.Requirements != requirements {
+		t.Errorf("Expected the requirements in the result, got: %+v", result.Requirements)
+	}
+	if result.Payer != "0xvalidPayer" {
+		t.Errorf("Expected payer 0xvalidPayer, got: %s", result.Payer)
+	}
+	if result.VerifyDuration <= 0 || result.SettleDuration < time.Millisecond {
+		t.Errorf("Expected the duration of both legs, got: %s and %s", result.VerifyDuration, result.SettleDuration)
+	}
+}
+
+func TestAcceptHeader(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/vnd.x402.v1+json")
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if accept != facilitatorclient.DefaultAccept {
+		t.Errorf("Expected Accept %s, got: %s", facilitatorclient.DefaultAccept, accept)
+	}
+
+	client = facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithAccept("application/vnd.x402.v1+json"))
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if accept != "application/vnd.x402.v1+json" {
+		t.Errorf("Expected Accept application/vnd.x402.v1+json, got: %s", accept)
+	}
+}
+
+func TestUnexpectedContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	_, err := client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if !errors.Is(err, facilitatorclient.ErrDecode) {
+		t.Fatalf("Expected ErrDecode, got: %v", err)
+	}
+
+	var decodeErr *facilitatorclient.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected a DecodeError, got: %v", err)
+	}
+	if decodeErr.Op != "settle" || decodeErr.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("Expected settle with text/html content type, got: %s %q", decodeErr.Op, decodeErr.ContentType)
+	}
+}
+
+func TestMalformedResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{not json"))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); !errors.Is(err, facilitatorclient.ErrDecode) {
+		t.Errorf("Expected ErrDecode, got: %v", err)
+	}
+}
+
+func TestSettleConfirmations(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		resp := types.SettleResponse{Success: true, Transaction: "0xtesthash"}
+		if confirmations, ok := body["confirmations"].(float64); ok {
+			resp.Confirmations = int(confirmations)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := body["confirmations"]; ok {
+		t.Errorf("Expected no confirmations by default, got: %v", body["confirmations"])
+	}
+	if resp.Confirmations != 0 {
+		t.Errorf("Expected 0 confirmations, got: %d", resp.Confirmations)
+	}
+
+	client = facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithSettleConfirmations(3))
+	resp, err = client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if body["confirmations"] != float64(3) {
+		t.Errorf("Expected 3 confirmations in the request, got: %v", body["confirmations"])
+	}
+	if resp.Confirmations != 3 {
+		t.Errorf("Expected 3 confirmations, got: %d", resp.Confirmations)
+	}
+}
+
+func TestSettleFeePayer(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		feePayer, ok := body["feePayer"].(string)
+		if !ok {
+			feePayer = "0x00000000000000000000000000000000000fac17"
+		}
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtesthash", FeePayer: feePayer})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := body["feePayer"]; ok {
+		t.Errorf("Expected the facilitator to choose the fee payer by default, got: %v", body["feePayer"])
+	}
+	if resp.FeePayer != "0x00000000000000000000000000000000000fac17" {
+		t.Errorf("Expected the facilitator's fee payer, got: %q", resp.FeePayer)
+	}
+
+	relayer := "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	client = facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithFeePayer(relayer))
+	resp, err = client.Settle(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if body["feePayer"] != relayer || resp.FeePayer != relayer {
+		t.Errorf("Expected fee payer %s, got: %v in the request and %q in the response", relayer, body["feePayer"], resp.FeePayer)
+	}
+
+	if _, err := client.SettleWithMetadata(context.Background(), newTestPayload(), &types.PaymentRequirements{}, map[string]any{"feePayer": "0xother"}); err == nil {
+		t.Error("Expected metadata not to overwrite the fee payer, got err == nil")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an invalid fee payer")
+		}
+	}()
+	facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL}, facilitatorclient.WithFeePayer("relayer.eth"))
+}
+
+func TestSettleWithSettlementAccount(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/supported" {
+			w.Write([]byte(`{"kinds": [
+				{"x402Version": 1, "scheme": "exact", "network": "base", "extra": {"settlementAccountPattern": "tenant-[0-9]+"}},
+				{"x402Version": 1, "scheme": "exact", "network": "base-sepolia"}
+			]}`))
+			return
+		}
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtesthash"})
+	}))
+	defer server.Close()
+
+	// Single-account facilitators get no settlement account
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	if _, err := client.Settle(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := body["settlementAccount"]; ok {
+		t.Errorf("Expected no settlement account by default, got: %v", body["settlementAccount"])
+	}
+
+	client = facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithSettlementAccount("tenant-42"))
+	if _, err := client.Settle(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if body["settlementAccount"] != "tenant-42" {
+		t.Errorf("Expected settlement account tenant-42, got: %v", body["settlementAccount"])
+	}
+	if _, err := client.SettleWithMetadata(context.Background(), newTestPayload(), &types.PaymentRequirements{}, map[string]any{"settlementAccount": "tenant-1"}); err == nil {
+		t.Error("Expected metadata not to overwrite the settlement account, got err == nil")
+	}
+
+	// The account is checked against the format the facilitator advertises for the kind
+	for _, network := range []string{"base", "base-sepolia"} {
+		if err := client.ValidateRequirements(context.Background(), &types.PaymentRequirements{Scheme: "exact", Network: network}); err != nil {
+			t.Errorf("%s: expected no error, got: %v", network, err)
+		}
+	}
+	other := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithSettlementAccount("acme"))
+	err := other.ValidateRequirements(context.Background(), &types.PaymentRequirements{Scheme: "exact", Network: "base"})
+	if !errors.Is(err, facilitatorclient.ErrInvalidSettlementAccount) {
+		t.Errorf("Expected %v, got: %v", facilitatorclient.ErrInvalidSettlementAccount, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an invalid settlement account")
+		}
+	}()
+	facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL}, facilitatorclient.WithSettlementAccount("tenant 42"))
+}
+
+func TestSettleWithMetadata(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		json.NewEncoder(w).Encode(types.SettleResponse{
+			Success:  true,
+			Metadata: map[string]any{"orderId": body["orderId"]},
+		})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.SettleWithMetadata(context.Background(), newTestPayload(), &types.PaymentRequirements{},
+		map[string]any{"orderId": "order-42"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if body["orderId"] != "order-42" || body["paymentPayload"] == nil || body["x402Version"] != float64(types.X402Version) {
+		t.Errorf("Expected metadata merged next to the protocol fields, got: %v", body)
+	}
+	if resp.Metadata["orderId"] != "order-42" {
+		t.Errorf("Expected echoed metadata, got: %v", resp.Metadata)
+	}
+
+	body = nil
+	_, err = client.SettleWithMetadata(context.Background(), newTestPayload(), &types.PaymentRequirements{},
+		map[string]any{"paymentPayload": "clobbered"})
+	if err == nil {
+		t.Error("Expected error for metadata colliding with a protocol field, got err == nil")
+	}
+	if body != nil {
+		t.Error("Expected no request to be sent for colliding metadata")
+	}
+}
+
+func TestVerifyPayerBalance(t *testing.T) {
+	body := `{"isValid":false,"invalidReason":"insufficient_funds","payer":"0xpayer","payerBalance":"4000","shortfall":"6000"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.PayerBalance != "4000" || resp.Shortfall != "6000" {
+		t.Errorf("Expected balance 4000 and shortfall 6000, got: %q and %q", resp.PayerBalance, resp.Shortfall)
+	}
+
+	body = `{"isValid":true}`
+	resp, err = client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.PayerBalance != "" || resp.Shortfall != "" {
+		t.Errorf("Expected no balance or shortfall, got: %q and %q", resp.PayerBalance, resp.Shortfall)
+	}
+}
+
+func TestInvalidPaymentArguments(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	testCases := map[string]struct {
+		payload      *types.PaymentPayload
+		requirements *types.PaymentRequirements
+		expected     error
+	}{
+		"nil payload":      {nil, &types.PaymentRequirements{}, facilitatorclient.ErrNilPayload},
+		"nil requirements": {newTestPayload(), nil, facilitatorclient.ErrNilRequirements},
+		"empty payload":    {&types.PaymentPayload{}, &types.PaymentRequirements{}, facilitatorclient.ErrIncompletePayload},
+		"missing network":  {&types.PaymentPayload{Scheme: "exact"}, &types.PaymentRequirements{}, facilitatorclient.ErrIncompletePayload},
+		"missing payload":  {&types.PaymentPayload{Scheme: "exact", Network: types.NetworkBaseSepolia}, &types.PaymentRequirements{}, facilitatorclient.ErrIncompletePayload},
+		"missing signature": {func() *types.PaymentPayload {
+			payload := newTestPayload()
+			payload.Payload.Signature = ""
+			return payload
+		}(), &types.PaymentRequirements{}, facilitatorclient.ErrIncompletePayload},
+		"missing authorization": {&types.PaymentPayload{Scheme: "exact", Network: types.NetworkBaseSepolia, Payload: &types.ExactEvmPayload{Signature: "0xsignature"}}, &types.PaymentRequirements{}, facilitatorclient.ErrIncompletePayload},
+		"empty authorization":   {&types.PaymentPayload{Scheme: "exact", Network: types.NetworkBaseSepolia, Payload: &types.ExactEvmPayload{Signature: "0xsignature", Authorization: &types.ExactEvmPayloadAuthorization{}}}, &types.PaymentRequirements{}, facilitatorclient.ErrIncompletePayload},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := client.Verify(tc.payload, tc.requirements); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected verify to fail with %v, got: %v", tc.expected, err)
+			}
+			if _, err := client.Settle(tc.payload, tc.requirements); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected settle to fail with %v, got: %v", tc.expected, err)
+			}
+			if _, err := client.SettleStream(context.Background(), tc.payload, tc.requirements); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected settle stream to fail with %v, got: %v", tc.expected, err)
+			}
+		})
+	}
+
+	if _, _, err := client.VerifyAny(context.Background(), newTestPayload(), []*types.PaymentRequirements{nil}, 1); !errors.Is(err, facilitatorclient.ErrNilRequirements) {
+		t.Errorf("Expected %v, got: %v", facilitatorclient.ErrNilRequirements, err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no requests to the facilitator, got: %d", n)
+	}
+}
+
+// benchmarkTransport answers every request with a valid verify response without touching the network
+type benchmarkTransport struct{}
+
+func (benchmarkTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"isValid": true, "payer": "0x857b06519E91e3A54538791bDbb0E22373e36b66"}`)),
+		Request:    r,
+	}, nil
+}
+
+func BenchmarkVerify(b *testing.B) {
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: "http://facilitator.test"})
+	client.HTTPClient = &http.Client{Transport: benchmarkTransport{}}
+
+	extra := json.RawMessage(`{"name": "USDC", "version": "2"}`)
+	payload := newSchedulerTestPayload(time.Now().Add(time.Hour))
+	requirements := &types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           types.NetworkBaseSepolia,
+		MaxAmountRequired: "1000000",
+		Resource:          "https://example.com/resource",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Extra:             &extra,
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Verify(payload, requirements); err != nil {
+			b.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package facilitatorclient
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ParseError is returned when a raw JSON payment payload or payment requirements can't be parsed
+type ParseError struct {
+	// Field is the input that failed to parse ("paymentPayload" or "paymentRequirements")
+	Field string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s: %v", e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyFromJSON parses a raw JSON payment payload and payment requirements and verifies them with the facilitator.
+// Parse failures are returned as *ParseError, facilitator failures as returned by Verify.
+func VerifyFromJSON(client *FacilitatorClient, payloadJSON, requirementsJSON []byte) (*types.VerifyResponse, error) {
+	payload, requirements, err := parsePaymentJSON(payloadJSON, requirementsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Verify(payload, requirements)
+}
+
+// SettleFromJSON parses a raw JSON payment payload and payment requirements and settles them with the facilitator.
+// Parse failures are returned as *ParseError, facilitator failures as returned by Settle.
+func SettleFromJSON(client *FacilitatorClient, payloadJSON, requirementsJSON []byte) (*types.SettleResponse, error) {
+	payload, requirements, err := parsePaymentJSON(payloadJSON, requirementsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Settle(payload, requirements)
+}
+
+func parsePaymentJSON(payloadJSON, requirementsJSON []byte) (*types.PaymentPayload, *types.PaymentRequirements, error) {
+	var payload types.PaymentPayload
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		return nil, nil, &ParseError{Field: "paymentPayload", Err: err}
+	}
+
+	var requirements types.PaymentRequirements
+	if err := json.Unmarshal(requirementsJSON, &requirements); err != nil {
+		return nil, nil, &ParseError{Field: "paymentRequirements", Err: err}
+	}
+
+	return &payload, &requirements, nil
+}
@@ -0,0 +1,114 @@
+package facilitatorclient
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ListFilter selects the resources listed by the facilitator's discovery endpoint
+type ListFilter struct {
+	// Type restricts the listing to resources of one type, e.g. "http". If empty, all types are listed.
+	Type string
+	// Limit is the number of resources per page. If zero, the facilitator's default is used.
+	Limit int
+	// Offset is the number of resources to skip
+	Offset int
+}
+
+// List fetches one page of the paid resources listed by the facilitator's discovery endpoint.
+// Page through the listing by advancing the filter's Offset, or use ListAll.
+func (c *FacilitatorClient) List(ctx context.Context, filter ListFilter) (*types.ListResponse, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	listResp, err := c.list(ctx, filter)
+	c.breaker.record(ctx, err)
+
+	return listResp, err
+}
+
+// ListAll iterates over every resource listed by the facilitator's discovery endpoint from the filter's Offset,
+// fetching pages with List as the loop consumes them. A failed page, including one cut short by the context,
+// is yielded as an error and ends the iteration.
+func (c *FacilitatorClient) ListAll(ctx context.Context, filter ListFilter) iter.Seq2[*types.Resource, error] {
+	return func(yield func(*types.Resource, error) bool) {
+		for {
+			listResp, err := c.List(ctx, filter)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for i := range listResp.Items {
+				if !yield(&listResp.Items[i], nil) {
+					return
+				}
+			}
+
+			filter.Offset += len(listResp.Items)
+			if len(listResp.Items) == 0 || filter.Offset >= listResp.Pagination.Total {
+				return
+			}
+		}
+	}
+}
+
+func (c *FacilitatorClient) list(ctx context.Context, filter ListFilter) (*types.ListResponse, error) {
+	query := url.Values{}
+	if filter.Type != "" {
+		query.Set("type", filter.Type)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	endpoint := fmt.Sprintf("%s/discovery/resources", c.URL)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", c.accept)
+
+	// Add auth headers if available
+	if c.CreateAuthHeaders != nil {
+		headers, err := c.CreateAuthHeaders()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth headers: %w", err)
+		}
+		if listHeaders, ok := headers["list"]; ok {
+			for key, value := range listHeaders {
+				req.Header.Set(key, value)
+			}
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send list request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newFacilitatorError("list", resp)
+	}
+
+	var listResp types.ListResponse
+	if err := c.responseDecoder.DecodeResponse("list", resp, &listResp); err != nil {
+		return nil, err
+	}
+
+	return &listResp, nil
+}
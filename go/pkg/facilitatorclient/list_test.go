@@ -0,0 +1,121 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// newDiscoveryServer creates a facilitator listing total resources in pages of at most limit,
+// failing the page at failOffset if it is positive
+func newDiscoveryServer(t *testing.T, total, limit, failOffset int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/discovery/resources" {
+			t.Errorf("Expected GET /discovery/resources, got: %s %s", r.Method, r.URL.Path)
+		}
+		if r.URL.Query().Get("type") != "http" {
+			t.Errorf("Expected type http, got: %s", r.URL.Query().Get("type"))
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if failOffset > 0 && offset == failOffset {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		listResp := types.ListResponse{X402Version: 1, Pagination: types.Pagination{Limit: limit, Offset: offset, Total: total}}
+		for i := offset; i < min(offset+limit, total); i++ {
+			listResp.Items = append(listResp.Items, types.Resource{Resource: fmt.Sprintf("https://example.com/%d", i), Type: "http", X402Version: 1})
+		}
+		json.NewEncoder(w).Encode(listResp)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestList(t *testing.T) {
+	server := newDiscoveryServer(t, 5, 2, 0)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	listResp, err := client.List(context.Background(), facilitatorclient.ListFilter{Type: "http", Offset: 4})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(listResp.Items) != 1 || listResp.Items[0].Resource != "https://example.com/4" {
+		t.Errorf("Expected the last resource, got: %+v", listResp.Items)
+	}
+	if listResp.Pagination.Total != 5 {
+		t.Errorf("Expected a total of 5, got: %d", listResp.Pagination.Total)
+	}
+}
+
+func TestListAll(t *testing.T) {
+	server := newDiscoveryServer(t, 5, 2, 0)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	var resources []string
+	for resource, err := range client.ListAll(context.Background(), facilitatorclient.ListFilter{Type: "http"}) {
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		resources = append(resources, resource.Resource)
+	}
+	if len(resources) != 5 || resources[0] != "https://example.com/0" || resources[4] != "https://example.com/4" {
+		t.Errorf("Expected all 5 resources in order, got: %v", resources)
+	}
+
+	// Breaking out of the loop stops fetching pages
+	count := 0
+	for range client.ListAll(context.Background(), facilitatorclient.ListFilter{Type: "http"}) {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("Expected to stop after 3 resources, got: %d", count)
+	}
+}
+
+func TestListAllError(t *testing.T) {
+	server := newDiscoveryServer(t, 5, 2, 2)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	var resources int
+	var errs []error
+	for resource, err := range client.ListAll(context.Background(), facilitatorclient.ListFilter{Type: "http"}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if resource == nil {
+			t.Fatal("Expected a resource alongside a nil error")
+		}
+		resources++
+	}
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if len(errs) != 1 || !errors.As(errs[0], &facilitatorErr) || facilitatorErr.Op != "list" {
+		t.Fatalf("Expected a single list FacilitatorError, got: %v", errs)
+	}
+	if resources != 2 {
+		t.Errorf("Expected the first page before the error, got %d resources", resources)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range client.ListAll(ctx, facilitatorclient.ListFilter{Type: "http"}) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected %v, got: %v", context.Canceled, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package facilitatorclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ProcessHooks are the extension points of ProcessPayment. Nil hooks are skipped,
+// so the zero value runs a plain verify then settle.
+type ProcessHooks struct {
+	// OnVerified runs after the payment is verified as valid and before it is settled,
+	// e.g. to do the paid work or check a usage quota. Returning an error skips settlement.
+	OnVerified func(ctx context.Context, payload *types.PaymentPayload) error
+	// OnSettled runs with the facilitator's settle response, whether or not settlement succeeded
+	OnSettled func(ctx context.Context, resp *types.SettleResponse)
+}
+
+// ProcessPayment verifies the payment and, if it is valid and hooks.OnVerified allows it, settles it.
+// A single context deadline covers both legs, as with VerifyAndSettle.
+// If the payment is invalid, the result has a nil settle response and there is no error.
+// If OnVerified fails, its error is returned alongside the verified result and nothing is settled.
+// The result is never nil, and facilitator errors are returned alongside the outcome so far.
+func (c *FacilitatorClient) ProcessPayment(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements, hooks ProcessHooks) (*types.PaymentResult, error) {
+	result := &types.PaymentResult{
+		Payload:      payload,
+		Requirements: requirements,
+		Payer:        payerOf(payload, nil),
+	}
+
+	start := time.Now()
+	verifyResp, err := c.VerifyWithContext(ctx, payload, requirements)
+	result.VerifyDuration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	result.Verify = verifyResp
+
+	if !verifyResp.IsValid {
+		return result, nil
+	}
+	result.Payer = payerOf(payload, verifyResp)
+
+	if hooks.OnVerified != nil {
+		if err := hooks.OnVerified(ctx, payload); err != nil {
+			return result, fmt.Errorf("skipping settlement: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("skipping settlement: %w", err)
+	}
+
+	start = time.Now()
+	settleResp, err := c.SettleWithContext(ctx, payload, requirements)
+	result.SettleDuration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	result.Settle = settleResp
+
+	if hooks.OnSettled != nil {
+		hooks.OnSettled(ctx, settleResp)
+	}
+
+	return result, nil
+}
+
+// payerOf returns the payer reported by the facilitator, if any, falling back to the authorization's from address
+func payerOf(payload *types.PaymentPayload, response *types.VerifyResponse) string {
+	if response != nil && response.Payer != nil && *response.Payer != "" {
+		return *response.Payer
+	}
+	if payload != nil && payload.Payload != nil && payload.Payload.Authorization != nil {
+		return payload.Payload.Authorization.From
+	}
+
+	return ""
+}
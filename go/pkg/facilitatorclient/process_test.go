@@ -0,0 +1,107 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newProcessTestServer(t *testing.T, valid bool, settled *int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: valid})
+		case "/settle":
+			*settled++
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xvalidTransaction"})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProcessPaymentHooks(t *testing.T) {
+	var settled int
+	server := newProcessTestServer(t, true, &settled)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	var calls []string
+	hooks := facilitatorclient.ProcessHooks{
+		OnVerified: func(ctx context.Context, payload *types.PaymentPayload) error {
+			calls = append(calls, "verified")
+			return nil
+		},
+		OnSettled: func(ctx context.Context, resp *types.SettleResponse) {
+			calls = append(calls, "settled:"+resp.Transaction)
+		},
+	}
+
+	result, err := client.ProcessPayment(context.Background(), newTestPayload(), &types.PaymentRequirements{}, hooks)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Settle == nil || result.Settle.Transaction != "0xvalidTransaction" {
+		t.Errorf("Expected settle response, got: %+v", result.Settle)
+	}
+	if len(calls) != 2 || calls[0] != "verified" || calls[1] != "settled:0xvalidTransaction" {
+		t.Errorf("Expected OnVerified then OnSettled, got: %v", calls)
+	}
+}
+
+func TestProcessPaymentOnVerifiedError(t *testing.T) {
+	var settled int
+	server := newProcessTestServer(t, true, &settled)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	errQuota := errors.New("quota exceeded")
+	onSettledCalled := false
+	hooks := facilitatorclient.ProcessHooks{
+		OnVerified: func(ctx context.Context, payload *types.PaymentPayload) error {
+			return errQuota
+		},
+		OnSettled: func(ctx context.Context, resp *types.SettleResponse) {
+			onSettledCalled = true
+		},
+	}
+
+	result, err := client.ProcessPayment(context.Background(), newTestPayload(), &types.PaymentRequirements{}, hooks)
+	if !errors.Is(err, errQuota) {
+		t.Errorf("Expected the OnVerified error, got: %v", err)
+	}
+	if result.Verify == nil || !result.Verify.IsValid {
+		t.Errorf("Expected the verify response, got: %+v", result.Verify)
+	}
+	if result.Settle != nil || settled != 0 || onSettledCalled {
+		t.Errorf("Expected settlement to be skipped, got %d settle requests", settled)
+	}
+}
+
+func TestProcessPaymentInvalid(t *testing.T) {
+	var settled int
+	server := newProcessTestServer(t, false, &settled)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	onVerifiedCalled := false
+	hooks := facilitatorclient.ProcessHooks{
+		OnVerified: func(ctx context.Context, payload *types.PaymentPayload) error {
+			onVerifiedCalled = true
+			return nil
+		},
+	}
+
+	result, err := client.ProcessPayment(context.Background(), newTestPayload(), &types.PaymentRequirements{}, hooks)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Settle != nil || result.Settled() || settled != 0 || onVerifiedCalled {
+		t.Errorf("Expected no hooks or settlement for an invalid payment")
+	}
+}
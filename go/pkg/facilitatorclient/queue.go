@@ -0,0 +1,154 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrSettlementQueued is matched by errors.Is when a QueuingClient couldn't reach the facilitator
+// and queued the settlement for Flush
+var ErrSettlementQueued = errors.New("facilitator unreachable, settlement queued")
+
+// Queue holds the settlements a QueuingClient couldn't deliver until they are flushed.
+// MemoryQueue keeps them in memory; implementations backed by a file or database keep them across restarts.
+// Implementations must be safe for concurrent use.
+type Queue interface {
+	// Push appends a settlement to the queue
+	Push(settlement DeferredSettlement) error
+	// Drain removes and returns all queued settlements, in the order they were pushed
+	Drain() ([]DeferredSettlement, error)
+}
+
+// MemoryQueue is an in-memory Queue
+type MemoryQueue struct {
+	mu    sync.Mutex
+	items []DeferredSettlement
+}
+
+// NewMemoryQueue creates a new in-memory queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{}
+}
+
+// Push appends a settlement to the queue
+func (q *MemoryQueue) Push(settlement DeferredSettlement) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.items = append(q.items, settlement)
+	return nil
+}
+
+// Drain removes and returns all queued settlements, in the order they were pushed
+func (q *MemoryQueue) Drain() ([]DeferredSettlement, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := q.items
+	q.items = nil
+	return items, nil
+}
+
+// Len returns the number of queued settlements
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// QueuingClient settles verified payments through a FacilitatorClient, queueing those it can't deliver
+// while the facilitator is unreachable, for intermittently connected deployments such as edge devices.
+// Queued settlements are retried by Flush, e.g. when connectivity returns, and are dropped once their
+// authorization reaches its validBefore, after which they can no longer be settled.
+type QueuingClient struct {
+	client  *FacilitatorClient
+	queue   Queue
+	flushMu sync.Mutex
+}
+
+// NewQueuingClient creates a queuing client settling through client. If queue is nil, a MemoryQueue is used.
+func NewQueuingClient(client *FacilitatorClient, queue Queue) *QueuingClient {
+	if queue == nil {
+		queue = NewMemoryQueue()
+	}
+
+	return &QueuingClient{
+		client: client,
+		queue:  queue,
+	}
+}
+
+// Settle settles the payment. If the facilitator can't be reached, because the request failed in transit,
+// it answered 5xx or 429, or the circuit breaker is open, the payment is queued and the error matches
+// ErrSettlementQueued, unless its authorization has already expired.
+func (q *QueuingClient) Settle(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.SettleResponse, error) {
+	resp, err := q.client.SettleWithContext(ctx, payload, requirements)
+	if !isUnreachable(ctx, err) {
+		return resp, err
+	}
+
+	return nil, q.requeue(DeferredSettlement{Payload: payload, Requirements: requirements}, err)
+}
+
+// Flush retries the queued settlements and returns one result per settlement, in the order they were queued.
+// Settlements whose authorization expired are dropped with an error result, and those the facilitator still
+// can't be reached for go back in the queue, their result error matching ErrSettlementQueued.
+// If ctx is done, the settlements not yet retried go back in the queue and ctx.Err() is returned.
+func (q *QueuingClient) Flush(ctx context.Context) ([]SettlementResult, error) {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	queued, err := q.queue.Drain()
+	if err != nil {
+		return nil, fmt.Errorf("failed to drain settlement queue: %w", err)
+	}
+
+	results := make([]SettlementResult, 0, len(queued))
+	for i, settlement := range queued {
+		if ctx.Err() != nil {
+			for _, remaining := range queued[i:] {
+				if err := q.queue.Push(remaining); err != nil {
+					return results, fmt.Errorf("failed to requeue settlement: %w", err)
+				}
+			}
+			return results, ctx.Err()
+		}
+
+		result := SettlementResult{Settlement: settlement}
+		if err := checkNotExpired(settlement.Payload, time.Now()); err != nil {
+			result.Err = err
+		} else {
+			result.Response, result.Err = q.client.SettleWithContext(ctx, settlement.Payload, settlement.Requirements)
+			// A settlement interrupted by ctx wasn't answered either, so it is kept for the next flush
+			if isUnreachable(ctx, result.Err) || (result.Err != nil && ctx.Err() != nil) {
+				result.Response, result.Err = nil, q.requeue(settlement, result.Err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// requeue queues a settlement that failed with err, unless its authorization has expired
+func (q *QueuingClient) requeue(settlement DeferredSettlement, err error) error {
+	if expired := checkNotExpired(settlement.Payload, time.Now()); expired != nil {
+		return fmt.Errorf("%w; not queued: %v", err, expired)
+	}
+	if pushErr := q.queue.Push(settlement); pushErr != nil {
+		return fmt.Errorf("%w; failed to queue settlement: %v", err, pushErr)
+	}
+
+	return fmt.Errorf("%w: %v", ErrSettlementQueued, err)
+}
+
+// isUnreachable reports whether err, returned for a settlement sent with ctx, means it never got an answer from the facilitator
+func isUnreachable(ctx context.Context, err error) bool {
+	return isAvailabilityFailure(ctx, err) || errors.Is(err, ErrCircuitOpen)
+}
@@ -0,0 +1,100 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestQueuingClient(t *testing.T) {
+	var online atomic.Bool
+	var settleCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		settleCalls.Add(1)
+		if !online.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xvalidTransaction", Network: "base-sepolia"})
+	}))
+	defer server.Close()
+
+	queue := facilitatorclient.NewMemoryQueue()
+	client := facilitatorclient.NewQueuingClient(facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL}), queue)
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	// While offline, payments are queued unless already expired
+	if _, err := client.Settle(context.Background(), newSchedulerTestPayload(time.Now().Add(time.Minute)), requirements); !errors.Is(err, facilitatorclient.ErrSettlementQueued) {
+		t.Fatalf("Expected %v, got: %v", facilitatorclient.ErrSettlementQueued, err)
+	}
+	if _, err := client.Settle(context.Background(), newSchedulerTestPayload(time.Now().Add(-time.Minute)), requirements); err == nil || errors.Is(err, facilitatorclient.ErrSettlementQueued) {
+		t.Errorf("Expected an expired payment not to be queued, got: %v", err)
+	}
+	if queue.Len() != 1 {
+		t.Fatalf("Expected 1 queued settlement, got: %d", queue.Len())
+	}
+
+	// Still offline: the settlement stays queued
+	results, err := client.Flush(context.Background())
+	if err != nil || len(results) != 1 || !errors.Is(results[0].Err, facilitatorclient.ErrSettlementQueued) {
+		t.Fatalf("Expected the settlement to be queued again, got: %v, %v", results, err)
+	}
+
+	// A settlement that expired while queued is dropped without contacting the facilitator
+	expiring := newSchedulerTestPayload(time.Now().Add(time.Second))
+	if _, err := client.Settle(context.Background(), expiring, requirements); !errors.Is(err, facilitatorclient.ErrSettlementQueued) {
+		t.Fatalf("Expected %v, got: %v", facilitatorclient.ErrSettlementQueued, err)
+	}
+	expiring.Payload.Authorization.ValidBefore = "1"
+
+	online.Store(true)
+	calls := settleCalls.Load()
+	results, err = client.Flush(context.Background())
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Expected 2 results, got: %v, %v", results, err)
+	}
+	if results[0].Err != nil || !results[0].Response.Success {
+		t.Errorf("Expected the queued settlement to succeed, got: %v", results[0].Err)
+	}
+	if results[1].Err == nil || errors.Is(results[1].Err, facilitatorclient.ErrSettlementQueued) {
+		t.Errorf("Expected the expired settlement to be dropped, got: %v", results[1].Err)
+	}
+	if n := settleCalls.Load() - calls; n != 1 {
+		t.Errorf("Expected 1 settle request, got: %d", n)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("Expected an empty queue, got: %d", queue.Len())
+	}
+}
+
+func TestQueuingClientFlushCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	queue := facilitatorclient.NewMemoryQueue()
+	client := facilitatorclient.NewQueuingClient(facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL}), queue)
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+	for range 3 {
+		client.Settle(context.Background(), newSchedulerTestPayload(time.Now().Add(time.Minute)), requirements)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := client.Flush(ctx)
+	if !errors.Is(err, context.Canceled) || len(results) != 0 {
+		t.Errorf("Expected the flush to stop, got: %v, %v", results, err)
+	}
+	if queue.Len() != 3 {
+		t.Errorf("Expected the settlements to stay queued, got: %d", queue.Len())
+	}
+}
@@ -0,0 +1,88 @@
+package facilitatorclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrRefundNotSupported is returned by Refund when the facilitator has no refund endpoint
+var ErrRefundNotSupported = errors.New("facilitator does not support refunds")
+
+// Refund asks the facilitator to return the funds of a settled payment to its payer, e.g. when the
+// handler fails after the payment was settled. Refunds are a best-effort reconciliation tool: they
+// are only possible where both the facilitator and the payment scheme support them, and a
+// successful settlement cannot otherwise be reversed on chain.
+func (c *FacilitatorClient) Refund(ctx context.Context, settlement *types.SettleResponse, reason string) (*types.RefundResponse, error) {
+	if settlement == nil || !settlement.Success || settlement.Transaction == "" {
+		return nil, fmt.Errorf("only successful settlements can be refunded")
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	refundResp, err := c.refund(ctx, settlement, reason)
+	c.breaker.record(ctx, err)
+
+	return refundResp, err
+}
+
+func (c *FacilitatorClient) refund(ctx context.Context, settlement *types.SettleResponse, reason string) (*types.RefundResponse, error) {
+	reqBody := map[string]any{
+		"x402Version": types.X402Version,
+		"transaction": settlement.Transaction,
+		"network":     settlement.Network,
+		"payer":       settlement.Payer,
+		"reason":      reason,
+	}
+
+	jsonBody, err := c.requestEncoder.EncodeRequest("refund", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/refund", c.URL), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", c.accept)
+
+	// Add auth headers if available
+	if c.CreateAuthHeaders != nil {
+		headers, err := c.CreateAuthHeaders()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth headers: %w", err)
+		}
+		if refundHeaders, ok := headers["refund"]; ok {
+			for key, value := range refundHeaders {
+				req.Header.Set(key, value)
+			}
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send refund request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		return nil, fmt.Errorf("%w: %w", ErrRefundNotSupported, newFacilitatorError("refund", resp))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newFacilitatorError("refund", resp)
+	}
+
+	var refundResp types.RefundResponse
+	if err := c.responseDecoder.DecodeResponse("refund", resp, &refundResp); err != nil {
+		return nil, err
+	}
+
+	return &refundResp, nil
+}
@@ -0,0 +1,97 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestRefund(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/refund" {
+			t.Errorf("Expected to request '/refund', got: %s", r.URL.Path)
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if body["transaction"] != "0xsettled" || body["reason"] != "handler failed" {
+			t.Errorf("Unexpected refund request: %v", body)
+		}
+
+		json.NewEncoder(w).Encode(types.RefundResponse{
+			Success:     true,
+			Transaction: "0xrefund",
+			Network:     "base-sepolia",
+		})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Refund(context.Background(), &types.SettleResponse{
+		Success:     true,
+		Transaction: "0xsettled",
+		Network:     "base-sepolia",
+	}, "handler failed")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !resp.Success || resp.Transaction != "0xrefund" {
+		t.Errorf("Expected successful refund 0xrefund, got: %+v", resp)
+	}
+}
+
+func TestRefundNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	_, err := client.Refund(context.Background(), &types.SettleResponse{Success: true, Transaction: "0xsettled"}, "")
+	if !errors.Is(err, facilitatorclient.ErrRefundNotSupported) {
+		t.Errorf("Expected ErrRefundNotSupported, got: %v", err)
+	}
+
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if !errors.As(err, &facilitatorErr) || facilitatorErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 FacilitatorError, got: %v", err)
+	}
+}
+
+func TestRefundNotSupportedKeepsCircuitClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/refund" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithCircuitBreaker(1, time.Minute))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Refund(context.Background(), &types.SettleResponse{Success: true, Transaction: "0xsettled"}, "")
+		if !errors.Is(err, facilitatorclient.ErrRefundNotSupported) {
+			t.Fatalf("Expected ErrRefundNotSupported, got: %v", err)
+		}
+	}
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Errorf("Expected an unsupported refund endpoint not to open the circuit, got: %v", err)
+	}
+}
+
+func TestRefundUnsettledPayment(t *testing.T) {
+	client := facilitatorclient.NewFacilitatorClient(nil)
+	if _, err := client.Refund(context.Background(), &types.SettleResponse{Success: false}, ""); err == nil {
+		t.Error("Expected error for unsuccessful settlement, got err == nil")
+	}
+}
@@ -0,0 +1,118 @@
+package facilitatorclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// DeferredSettlement is a verified payment waiting to be settled
+type DeferredSettlement struct {
+	Payload      *types.PaymentPayload
+	Requirements *types.PaymentRequirements
+}
+
+// SettlementResult is the outcome of settling a DeferredSettlement
+type SettlementResult struct {
+	Settlement DeferredSettlement
+	Response   *types.SettleResponse
+	Err        error
+}
+
+// SettlementScheduler collects verified payments and settles them in batches.
+//
+// Deferred settlement carries a risk window: an authorization that reaches its validBefore
+// before the batch runs can no longer be settled, and the resource will have been served
+// for free. Keep the flush interval well below the MaxTimeoutSeconds of the requirements.
+type SettlementScheduler struct {
+	client  *FacilitatorClient
+	mu      sync.Mutex
+	pending []DeferredSettlement
+}
+
+// NewSettlementScheduler creates a new settlement scheduler that settles through the given client
+func NewSettlementScheduler(client *FacilitatorClient) *SettlementScheduler {
+	return &SettlementScheduler{
+		client: client,
+	}
+}
+
+// Schedule queues a verified payment for settlement on the next flush
+func (s *SettlementScheduler) Schedule(payload *types.PaymentPayload, requirements *types.PaymentRequirements) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, DeferredSettlement{
+		Payload:      payload,
+		Requirements: requirements,
+	})
+}
+
+// Pending returns the number of payments waiting to be settled
+func (s *SettlementScheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending)
+}
+
+// Flush settles all queued payments and returns one result per payment, in the order they were scheduled.
+// Payments whose authorization has already expired are not sent to the facilitator.
+func (s *SettlementScheduler) Flush() []SettlementResult {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	results := make([]SettlementResult, 0, len(pending))
+	for _, settlement := range pending {
+		result := SettlementResult{Settlement: settlement}
+		if err := checkNotExpired(settlement.Payload, time.Now()); err != nil {
+			result.Err = err
+		} else {
+			result.Response, result.Err = s.client.Settle(settlement.Payload, settlement.Requirements)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Run flushes the queue every interval until the context is cancelled, passing each batch of results to onResults
+func (s *SettlementScheduler) Run(ctx context.Context, interval time.Duration, onResults func([]SettlementResult)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			results := s.Flush()
+			if onResults != nil && len(results) > 0 {
+				onResults(results)
+			}
+		}
+	}
+}
+
+// checkNotExpired returns an error if the payload's authorization is no longer valid at now
+func checkNotExpired(payload *types.PaymentPayload, now time.Time) error {
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return fmt.Errorf("payment payload is missing its authorization")
+	}
+
+	validBefore, err := strconv.ParseInt(payload.Payload.Authorization.ValidBefore, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid validBefore: %w", err)
+	}
+	if now.Unix() >= validBefore {
+		return fmt.Errorf("authorization expired at %d before it could be settled", validBefore)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package facilitatorclient_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newSchedulerTestPayload(validBefore time.Time) *types.PaymentPayload {
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      "exact",
+		Network:     "base-sepolia",
+		Payload: &types.ExactEvmPayload{
+			Signature: "0xvalidSignature",
+			Authorization: &types.ExactEvmPayloadAuthorization{
+				From:        "0xvalidFrom",
+				To:          "0xvalidTo",
+				Value:       "1000000",
+				ValidAfter:  "1745323800",
+				ValidBefore: strconv.FormatInt(validBefore.Unix(), 10),
+				Nonce:       "0xvalidNonce",
+			},
+		},
+	}
+}
+
+func TestSettlementSchedulerFlush(t *testing.T) {
+	var settleCalls atomic.Int32
+
+	// Create test server that counts settle requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		settleCalls.Add(1)
+		json.NewEncoder(w).Encode(types.SettleResponse{
+			Success:     true,
+			Transaction: "0xvalidTransaction",
+			Network:     "base-sepolia",
+		})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	})
+	scheduler := facilitatorclient.NewSettlementScheduler(client)
+
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+	scheduler.Schedule(newSchedulerTestPayload(time.Now().Add(time.Minute)), requirements)
+	scheduler.Schedule(newSchedulerTestPayload(time.Now().Add(-time.Minute)), requirements)
+
+	if scheduler.Pending() != 2 {
+		t.Fatalf("Expected 2 pending settlements, got: %d", scheduler.Pending())
+	}
+
+	results := scheduler.Flush()
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got: %d", len(results))
+	}
+	if results[0].Err != nil || results[0].Response == nil || !results[0].Response.Success {
+		t.Errorf("Expected first settlement to succeed, got: %+v", results[0])
+	}
+	if results[1].Err == nil {
+		t.Error("Expected expired settlement to fail, got err == nil")
+	}
+	if settleCalls.Load() != 1 {
+		t.Errorf("Expected 1 settle request, got: %d", settleCalls.Load())
+	}
+	if scheduler.Pending() != 0 {
+		t.Errorf("Expected queue to be empty after flush, got: %d", scheduler.Pending())
+	}
+}
@@ -0,0 +1,189 @@
+package facilitatorclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// Settlement statuses reported by streaming and asynchronous facilitators
+const (
+	SettleStatusPending   = "pending"
+	SettleStatusSubmitted = "submitted"
+	SettleStatusConfirmed = "confirmed"
+	SettleStatusFailed    = "failed"
+)
+
+// settleStreamAccept advertises the streaming formats SettleStream can decode
+const settleStreamAccept = "application/x-ndjson, text/event-stream, application/json"
+
+// SettleEvent is a settlement status update received from SettleStream
+type SettleEvent struct {
+	// Status is the settlement status, e.g. SettleStatusSubmitted. It is empty for a plain settle response.
+	Status string
+	// Response holds the settlement details known at this point, such as the submitted transaction
+	Response *types.SettleResponse
+	// Final is set on the last event of the stream
+	Final bool
+	// Err is set on a final event when the stream broke off before a terminal status
+	Err error
+}
+
+// SettleStream settles the payment and reports the facilitator's progress, e.g. "submitted" then "confirmed".
+// Newline-delimited JSON and server-sent event responses are decoded event by event until a terminal
+// status; any other response is decoded as a single settle response and delivered as one final event.
+// The channel is closed after the final event. The client timeout also bounds how long the stream may run.
+func (c *FacilitatorClient) SettleStream(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (<-chan SettleEvent, error) {
+	if err := checkPayment(payload, requirements); err != nil {
+		return nil, err
+	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.startSettleStream(ctx, payload, requirements)
+	c.breaker.record(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan SettleEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-ndjson", "application/jsonl":
+			streamSettleEvents(ctx, events, newLineReader(resp.Body))
+		case "text/event-stream":
+			streamSettleEvents(ctx, events, newSSEReader(resp.Body))
+		default:
+			var settleResp types.SettleResponse
+			event := SettleEvent{Final: true}
+			if err := c.responseDecoder.DecodeResponse("settle", resp, &settleResp); err != nil {
+				event.Err = err
+			} else {
+				event.Response = &settleResp
+			}
+			sendSettleEvent(ctx, events, event)
+		}
+	}()
+
+	return events, nil
+}
+
+func (c *FacilitatorClient) startSettleStream(ctx context.Context, payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*http.Response, error) {
+	req, err := c.newSettleRequest(ctx, payload, requirements, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", settleStreamAccept)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send settle request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newFacilitatorError("settle", resp)
+	}
+
+	return resp, nil
+}
+
+// streamSettleEvents decodes each message returned by next into an event until a terminal status
+func streamSettleEvents(ctx context.Context, events chan<- SettleEvent, next func() ([]byte, error)) {
+	for {
+		data, err := next()
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("settlement stream ended before a final status")
+			}
+			sendSettleEvent(ctx, events, SettleEvent{Final: true, Err: &DecodeError{Op: "settle", Err: err}})
+			return
+		}
+
+		var settleResp types.SettleResponse
+		if err := json.Unmarshal(data, &settleResp); err != nil {
+			sendSettleEvent(ctx, events, SettleEvent{Final: true, Err: &DecodeError{Op: "settle", Err: err}})
+			return
+		}
+
+		event := SettleEvent{
+			Status:   settleResp.Status,
+			Response: &settleResp,
+			Final:    isFinalSettleStatus(settleResp.Status),
+		}
+		if !sendSettleEvent(ctx, events, event) || event.Final {
+			return
+		}
+	}
+}
+
+// isFinalSettleStatus reports whether the settlement status is terminal
+func isFinalSettleStatus(status string) bool {
+	return status == SettleStatusConfirmed || status == SettleStatusFailed
+}
+
+// sendSettleEvent delivers the event unless the context is done first
+func sendSettleEvent(ctx context.Context, events chan<- SettleEvent, event SettleEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// newLineReader returns the non-empty lines of a newline-delimited JSON stream
+func newLineReader(r io.Reader) func() ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	return func() ([]byte, error) {
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				return line, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+// newSSEReader returns the data of each server-sent event, joining multi-line data with newlines
+func newSSEReader(r io.Reader) func() ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	return func() ([]byte, error) {
+		var data []string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if len(data) > 0 {
+					return []byte(strings.Join(data, "\n")), nil
+				}
+				continue
+			}
+			if value, ok := strings.CutPrefix(line, "data:"); ok {
+				data = append(data, strings.TrimPrefix(value, " "))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			return []byte(strings.Join(data, "\n")), nil
+		}
+		return nil, io.EOF
+	}
+}
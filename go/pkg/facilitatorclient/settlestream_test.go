@@ -0,0 +1,90 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func collectSettleEvents(t *testing.T, body, contentType string) []facilitatorclient.SettleEvent {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	events, err := client.SettleStream(context.Background(), newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var collected []facilitatorclient.SettleEvent
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestSettleStreamNDJSON(t *testing.T) {
+	events := collectSettleEvents(t,
+		`{"status":"submitted","transaction":"0xtx","network":"base"}`+"\n\n"+
+			`{"status":"confirmed","success":true,"transaction":"0xtx","network":"base"}`+"\n"+
+			`{"status":"ignored"}`+"\n",
+		"application/x-ndjson")
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got: %d", len(events))
+	}
+	if events[0].Status != facilitatorclient.SettleStatusSubmitted || events[0].Final || events[0].Response.Transaction != "0xtx" {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if events[1].Status != facilitatorclient.SettleStatusConfirmed || !events[1].Final || !events[1].Response.Success {
+		t.Errorf("Unexpected final event: %+v", events[1])
+	}
+}
+
+func TestSettleStreamSSE(t *testing.T) {
+	events := collectSettleEvents(t,
+		": keep-alive\n\nevent: progress\ndata: {\"status\":\"submitted\",\ndata: \"transaction\":\"0xtx\"}\n\n"+
+			"data: {\"status\":\"failed\",\"success\":false,\"errorReason\":\"reverted\"}\n\n",
+		"text/event-stream")
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got: %d", len(events))
+	}
+	if events[0].Status != facilitatorclient.SettleStatusSubmitted || events[0].Response.Transaction != "0xtx" {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if !events[1].Final || events[1].Response.Success || *events[1].Response.ErrorReason != "reverted" {
+		t.Errorf("Unexpected final event: %+v", events[1])
+	}
+}
+
+func TestSettleStreamSingleObjectFallback(t *testing.T) {
+	body, _ := json.Marshal(types.SettleResponse{Success: true, Transaction: "0xtx"})
+	events := collectSettleEvents(t, string(body), "application/json")
+
+	if len(events) != 1 || !events[0].Final || events[0].Err != nil || events[0].Response.Transaction != "0xtx" {
+		t.Errorf("Expected one final event for 0xtx, got: %+v", events)
+	}
+}
+
+func TestSettleStreamTruncated(t *testing.T) {
+	events := collectSettleEvents(t, `{"status":"submitted","transaction":"0xtx"}`+"\n", "application/x-ndjson")
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got: %d", len(events))
+	}
+	if !events[1].Final || !errors.Is(events[1].Err, facilitatorclient.ErrDecode) {
+		t.Errorf("Expected a final ErrDecode event, got: %+v", events[1])
+	}
+}
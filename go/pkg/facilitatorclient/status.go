@@ -0,0 +1,77 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrSettleStatusNotSupported is returned by SettleStatus when the facilitator has no settlement status endpoint
+var ErrSettleStatusNotSupported = errors.New("facilitator does not support settlement status queries")
+
+// SettleStatus asks the facilitator for the current state of a settlement, e.g. to learn the outcome of a
+// settlement that was reported as pending or submitted. The response's Status is SettleStatusPending or
+// SettleStatusSubmitted while the transaction is in flight, then SettleStatusConfirmed or SettleStatusFailed.
+func (c *FacilitatorClient) SettleStatus(ctx context.Context, txHash string, network string) (*types.SettleResponse, error) {
+	if txHash == "" {
+		return nil, fmt.Errorf("transaction hash is required")
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	settleResp, err := c.settleStatus(ctx, txHash, network)
+	c.breaker.record(ctx, err)
+
+	return settleResp, err
+}
+
+func (c *FacilitatorClient) settleStatus(ctx context.Context, txHash string, network string) (*types.SettleResponse, error) {
+	query := url.Values{}
+	query.Set("transaction", txHash)
+	query.Set("network", network)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/settle/status?%s", c.URL, query.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", c.accept)
+
+	// Add auth headers if available
+	if c.CreateAuthHeaders != nil {
+		headers, err := c.CreateAuthHeaders()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth headers: %w", err)
+		}
+		if statusHeaders, ok := headers["status"]; ok {
+			for key, value := range statusHeaders {
+				req.Header.Set(key, value)
+			}
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send settlement status request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		return nil, fmt.Errorf("%w: %w", ErrSettleStatusNotSupported, newFacilitatorError("status", resp))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newFacilitatorError("status", resp)
+	}
+
+	var settleResp types.SettleResponse
+	if err := c.responseDecoder.DecodeResponse("status", resp, &settleResp); err != nil {
+		return nil, err
+	}
+
+	return &settleResp, nil
+}
@@ -0,0 +1,91 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestSettleStatus(t *testing.T) {
+	status := facilitatorclient.SettleStatusPending
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/settle/status" {
+			t.Errorf("Expected to request '/settle/status', got: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("transaction") != "0xtx" || r.URL.Query().Get("network") != "base" {
+			t.Errorf("Unexpected status query: %s", r.URL.RawQuery)
+		}
+
+		json.NewEncoder(w).Encode(types.SettleResponse{
+			Success:     status == facilitatorclient.SettleStatusConfirmed,
+			Status:      status,
+			Transaction: "0xtx",
+			Network:     "base",
+		})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.SettleStatus(context.Background(), "0xtx", "base")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Status != facilitatorclient.SettleStatusPending || resp.Success {
+		t.Errorf("Expected a pending settlement, got: %+v", resp)
+	}
+
+	status = facilitatorclient.SettleStatusConfirmed
+	resp, err = client.SettleStatus(context.Background(), "0xtx", "base")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Status != facilitatorclient.SettleStatusConfirmed || !resp.Success {
+		t.Errorf("Expected a confirmed settlement, got: %+v", resp)
+	}
+}
+
+func TestSettleStatusNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	_, err := client.SettleStatus(context.Background(), "0xtx", "base")
+	if !errors.Is(err, facilitatorclient.ErrSettleStatusNotSupported) {
+		t.Errorf("Expected ErrSettleStatusNotSupported, got: %v", err)
+	}
+
+	if _, err := client.SettleStatus(context.Background(), "", "base"); err == nil {
+		t.Error("Expected error for an empty transaction hash, got err == nil")
+	}
+}
+
+func TestSettleStatusNotSupportedKeepsCircuitClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/settle/status" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithCircuitBreaker(1, time.Minute))
+
+	// Polling an unsupported status endpoint must not block verify and settle
+	for i := 0; i < 3; i++ {
+		if _, err := client.SettleStatus(context.Background(), "0xtx", "base"); !errors.Is(err, facilitatorclient.ErrSettleStatusNotSupported) {
+			t.Fatalf("Expected ErrSettleStatusNotSupported, got: %v", err)
+		}
+	}
+	if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+		t.Errorf("Expected an unsupported status endpoint not to open the circuit, got: %v", err)
+	}
+}
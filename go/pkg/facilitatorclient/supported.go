@@ -0,0 +1,172 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+var (
+	// ErrUnsupportedKind is matched by errors.Is when EnsureSupports finds a required payment kind the facilitator doesn't support
+	ErrUnsupportedKind = errors.New("payment kind not supported by the facilitator")
+	// ErrInvalidSettlementAccount is matched by errors.Is when the settlement account doesn't match the format the facilitator advertises
+	ErrInvalidSettlementAccount = errors.New("settlement account does not match the facilitator's format")
+)
+
+// Supported fetches the payment kinds supported by the facilitator
+func (c *FacilitatorClient) Supported() (*types.SupportedResponse, error) {
+	return c.SupportedWithContext(context.Background())
+}
+
+// SupportedWithContext fetches the payment kinds supported by the facilitator, bound to the given context
+func (c *FacilitatorClient) SupportedWithContext(ctx context.Context) (*types.SupportedResponse, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	supportedResp, err := c.supported(ctx)
+	c.breaker.record(ctx, err)
+
+	return supportedResp, err
+}
+
+// EnsureSupports checks the facilitator supports every required scheme and network, e.g. at startup so a
+// facilitator that can't handle the service's payments fails fast rather than at the first payment.
+// A required kind with an x402 version of 0 matches any version, and extras are ignored.
+// The supported kinds are fetched once and cached, so later calls don't query the facilitator again;
+// a failed fetch isn't cached.
+func (c *FacilitatorClient) EnsureSupports(ctx context.Context, required []types.SupportedKind) error {
+	supportedResp, err := c.cachedSupported(ctx)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, kind := range required {
+		if !slices.ContainsFunc(supportedResp.Kinds, func(supported types.SupportedKind) bool {
+			return supported.Scheme == kind.Scheme && supported.Network == kind.Network &&
+				(kind.X402Version == 0 || supported.X402Version == kind.X402Version)
+		}) {
+			missing = append(missing, kind.Scheme+" on "+kind.Network)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupportedKind, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// ValidateRequirements checks each of the requirements against the facilitator's supported kinds, returning
+// an error matching ErrUnsupportedKind if its scheme and network aren't supported, or ErrBelowMinAmount
+// (from the types package) if maxAmountRequired is below the minAmount the kind advertises, or
+// ErrInvalidSettlementAccount if the account set with WithSettlementAccount doesn't match the kind's
+// settlement account pattern. Validating
+// when requirements are built turns a refusal at settlement into a clear local error.
+// The supported kinds are cached as for EnsureSupports.
+func (c *FacilitatorClient) ValidateRequirements(ctx context.Context, requirements ...*types.PaymentRequirements) error {
+	supportedResp, err := c.cachedSupported(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range requirements {
+		if req == nil {
+			return fmt.Errorf("payment requirements are nil")
+		}
+		i := slices.IndexFunc(supportedResp.Kinds, func(supported types.SupportedKind) bool {
+			return supported.Scheme == req.Scheme && supported.Network == req.Network
+		})
+		if i < 0 {
+			return fmt.Errorf("%w: %s on %s", ErrUnsupportedKind, req.Scheme, req.Network)
+		}
+		if err := supportedResp.Kinds[i].Validate(req); err != nil {
+			return err
+		}
+		if err := c.checkSettlementAccount(&supportedResp.Kinds[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSettlementAccount checks the client's settlement account, if any, against the pattern the kind advertises
+func (c *FacilitatorClient) checkSettlementAccount(kind *types.SupportedKind) error {
+	pattern := kind.SettlementAccountPattern()
+	if c.settlementAccount == "" || pattern == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return fmt.Errorf("invalid settlement account pattern advertised for %s on %s: %w", kind.Scheme, kind.Network, err)
+	}
+	if !re.MatchString(c.settlementAccount) {
+		return fmt.Errorf("%w: %q for %s on %s must match %s", ErrInvalidSettlementAccount, c.settlementAccount, kind.Scheme, kind.Network, pattern)
+	}
+
+	return nil
+}
+
+// cachedSupported returns the supported kinds fetched by a previous call, fetching them on the first
+func (c *FacilitatorClient) cachedSupported(ctx context.Context) (*types.SupportedResponse, error) {
+	c.supportedMu.Lock()
+	defer c.supportedMu.Unlock()
+
+	if c.supportedKinds != nil {
+		return c.supportedKinds, nil
+	}
+
+	supportedResp, err := c.SupportedWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.supportedKinds = supportedResp
+
+	return supportedResp, nil
+}
+
+func (c *FacilitatorClient) supported(ctx context.Context) (*types.SupportedResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/supported", c.URL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", c.accept)
+
+	// Add auth headers if available
+	if c.CreateAuthHeaders != nil {
+		headers, err := c.CreateAuthHeaders()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth headers: %w", err)
+		}
+		if supportedHeaders, ok := headers["supported"]; ok {
+			for key, value := range supportedHeaders {
+				req.Header.Set(key, value)
+			}
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send supported request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newFacilitatorError("supported", resp)
+	}
+
+	var supportedResp types.SupportedResponse
+	if err := c.responseDecoder.DecodeResponse("supported", resp, &supportedResp); err != nil {
+		return nil, err
+	}
+
+	return &supportedResp, nil
+}
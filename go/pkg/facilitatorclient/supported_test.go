@@ -0,0 +1,191 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/supported" {
+			t.Errorf("Expected GET /supported, got: %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"kinds": [
+			{"x402Version": 1, "scheme": "exact", "network": "base-sepolia", "extra": {"feePayer": "0x1111111111111111111111111111111111111111"}},
+			{"x402Version": 1, "scheme": "exact", "network": "base", "extra": {"sponsored": false, "feePayer": "0x1111111111111111111111111111111111111111"}},
+			{"x402Version": 1, "scheme": "exact", "network": "avalanche", "extra": {"maxTimeoutSeconds": 600, "minAmount": "1000"}},
+			{"x402Version": 1, "scheme": "exact", "network": "avalanche-fuji", "extra": "unexpected"},
+			{"x402Version": 1, "scheme": "exact", "network": "polygon", "extra": {"minAmount": 10000, "maxTimeoutSeconds": 300, "feePayer": "0x1111111111111111111111111111111111111111"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	resp, err := client.Supported()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(resp.Kinds) != 5 {
+		t.Fatalf("Expected 5 kinds, got: %d", len(resp.Kinds))
+	}
+
+	tests := []struct {
+		network           string
+		feePayer          string
+		sponsored         bool
+		maxTimeoutSeconds int
+		minAmount         string
+	}{
+		{"base-sepolia", "0x1111111111111111111111111111111111111111", true, 0, ""},
+		{"base", "0x1111111111111111111111111111111111111111", false, 0, ""},
+		{"avalanche", "", false, 600, "1000"},
+		{"avalanche-fuji", "", false, 0, ""},
+		// A malformed field doesn't hide the others
+		{"polygon", "0x1111111111111111111111111111111111111111", true, 300, ""},
+	}
+	for i, tt := range tests {
+		kind := resp.Kinds[i]
+		if kind.Network != tt.network {
+			t.Errorf("Expected network %s, got: %s", tt.network, kind.Network)
+		}
+		if kind.FeePayer() != tt.feePayer {
+			t.Errorf("%s: expected fee payer %q, got: %q", tt.network, tt.feePayer, kind.FeePayer())
+		}
+		if kind.Sponsored() != tt.sponsored {
+			t.Errorf("%s: expected sponsored %v, got: %v", tt.network, tt.sponsored, kind.Sponsored())
+		}
+		if kind.MaxTimeoutSeconds() != tt.maxTimeoutSeconds {
+			t.Errorf("%s: expected max timeout %d, got: %d", tt.network, tt.maxTimeoutSeconds, kind.MaxTimeoutSeconds())
+		}
+		if kind.MinAmount() != tt.minAmount {
+			t.Errorf("%s: expected min amount %q, got: %q", tt.network, tt.minAmount, kind.MinAmount())
+		}
+	}
+}
+
+func TestSupportedErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	_, err := client.Supported()
+
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if !errors.As(err, &facilitatorErr) {
+		t.Fatalf("Expected a FacilitatorError, got: %v", err)
+	}
+	if facilitatorErr.Op != "supported" || facilitatorErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected supported 503, got: %s %d", facilitatorErr.Op, facilitatorErr.StatusCode)
+	}
+	if err.Error() != "failed to fetch supported payment kinds: 503 Service Unavailable" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+func TestEnsureSupports(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"kinds": [
+			{"x402Version": 1, "scheme": "exact", "network": "base-sepolia"},
+			{"x402Version": 1, "scheme": "exact", "network": "base"},
+			{"x402Version": 1, "scheme": "exact", "network": "polygon", "extra": {"minAmount": 10000}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	required := []types.SupportedKind{
+		{Scheme: "exact", Network: "base-sepolia"},
+		{X402Version: 1, Scheme: "exact", Network: "base"},
+	}
+
+	// A failed fetch is returned and not cached
+	var facilitatorErr *facilitatorclient.FacilitatorError
+	if err := client.EnsureSupports(context.Background(), required); !errors.As(err, &facilitatorErr) {
+		t.Fatalf("Expected a FacilitatorError, got: %v", err)
+	}
+
+	if err := client.EnsureSupports(context.Background(), required); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	err := client.EnsureSupports(context.Background(), []types.SupportedKind{
+		{Scheme: "exact", Network: "avalanche"},
+		{X402Version: 2, Scheme: "exact", Network: "base"},
+	})
+	if !errors.Is(err, facilitatorclient.ErrUnsupportedKind) {
+		t.Fatalf("Expected %v, got: %v", facilitatorclient.ErrUnsupportedKind, err)
+	}
+	if !strings.Contains(err.Error(), "exact on avalanche, exact on base") {
+		t.Errorf("Expected the error to name the unsupported kinds, got: %v", err)
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Expected the supported kinds to be fetched once after the failure, got %d requests", n)
+	}
+}
+
+func TestValidateRequirements(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"kinds": [
+			{"x402Version": 1, "scheme": "exact", "network": "base-sepolia", "extra": {"minAmount": "1000"}},
+			{"x402Version": 1, "scheme": "exact", "network": "base"},
+			{"x402Version": 1, "scheme": "exact", "network": "polygon", "extra": {"minAmount": 10000}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+
+	tests := []struct {
+		name    string
+		network string
+		amount  string
+		wantErr error
+	}{
+		{"meets minimum", "base-sepolia", "1000", nil},
+		{"below minimum", "base-sepolia", "999", types.ErrBelowMinAmount},
+		{"no minimum advertised", "base", "1", nil},
+		{"unsupported network", "avalanche", "1000", facilitatorclient.ErrUnsupportedKind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := &types.PaymentRequirements{Scheme: "exact", Network: tt.network, MaxAmountRequired: tt.amount}
+			err := client.ValidateRequirements(context.Background(), requirements)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// A minimum advertised as a JSON number can't be checked, so it isn't treated as absent
+	err := client.ValidateRequirements(context.Background(), &types.PaymentRequirements{Scheme: "exact", Network: "polygon", MaxAmountRequired: "1"})
+	if err == nil || errors.Is(err, types.ErrBelowMinAmount) || !strings.Contains(err.Error(), "minAmount") {
+		t.Errorf("Expected an invalid minAmount error, got: %v", err)
+	}
+
+	err = client.ValidateRequirements(context.Background(), &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia", MaxAmountRequired: "10"})
+	if err == nil || err.Error() != "amount is below the facilitator's minimum: maxAmountRequired 10 for exact on base-sepolia is below the facilitator's minimum of 1000" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
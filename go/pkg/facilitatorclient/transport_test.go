@@ -0,0 +1,157 @@
+package facilitatorclient_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestNegotiatedProtocol(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []facilitatorclient.Options
+		expected string
+	}{
+		{
+			name:     "http2 by default",
+			expected: "HTTP/2.0",
+		},
+		{
+			name:     "http1 only",
+			opts:     []facilitatorclient.Options{facilitatorclient.WithHTTP1Only()},
+			expected: "HTTP/1.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var proto string
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proto = r.Proto
+				json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+			}))
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			defer server.Close()
+
+			client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+				URL: server.URL,
+			}, tc.opts...)
+
+			// Trust the test server's certificate, on a copy as the client's transport is shared
+			transport := client.HTTPClient.Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+			transport.TLSClientConfig.NextProtos = nil
+			client.HTTPClient.Transport = transport
+
+			if _, err := client.Verify(newTestPayload(), &types.PaymentRequirements{}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if proto != tc.expected {
+				t.Errorf("Expected protocol %s, got: %s", tc.expected, proto)
+			}
+		})
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	var proxiedHost string
+
+	// Create test proxy that answers on behalf of the facilitator
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer proxy.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: "http://facilitator.test",
+	}, facilitatorclient.WithProxy(proxy.URL))
+
+	resp, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !resp.IsValid {
+		t.Errorf("Expected valid response, got invalid")
+	}
+	if proxiedHost != "facilitator.test" {
+		t.Errorf("Expected request for facilitator.test to go through the proxy, got: %q", proxiedHost)
+	}
+}
+
+func TestWithInvalidProxyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for invalid proxy URL")
+		}
+	}()
+
+	facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{}, facilitatorclient.WithProxy("://bad"))
+}
+
+func TestWithResponseHeaderTimeout(t *testing.T) {
+	var delayHeader atomic.Bool
+	delayHeader.Store(true)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if delayHeader.Load() {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+
+		// Send the headers at once and the body late
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	// Release the delayed handler before Close waits for it
+	defer server.Close()
+	defer close(release)
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithResponseHeaderTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	_, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if err == nil {
+		t.Fatal("Expected an error when the facilitator doesn't send headers")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to fail after the response header timeout, took: %s", elapsed)
+	}
+
+	// A slow body is not limited by the response header timeout
+	delayHeader.Store(false)
+	resp, err := client.Verify(newTestPayload(), &types.PaymentRequirements{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !resp.IsValid {
+		t.Errorf("Expected valid response, got invalid")
+	}
+}
+
+func TestClientsShareTransport(t *testing.T) {
+	config := &types.FacilitatorConfig{URL: "http://facilitator.test"}
+	first := facilitatorclient.NewFacilitatorClient(config, facilitatorclient.WithHTTP1Only())
+	second := facilitatorclient.NewFacilitatorClient(config, facilitatorclient.WithHTTP1Only())
+	other := facilitatorclient.NewFacilitatorClient(config)
+
+	if first.HTTPClient.Transport != second.HTTPClient.Transport {
+		t.Error("Expected clients with the same transport options to share a transport")
+	}
+	if first.HTTPClient.Transport == other.HTTPClient.Transport {
+		t.Error("Expected clients with different transport options not to share a transport")
+	}
+}
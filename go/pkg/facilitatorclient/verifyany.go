@@ -0,0 +1,95 @@
+package facilitatorclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// DefaultMaxConcurrentVerifications is the default number of candidates VerifyAny verifies at once
+const DefaultMaxConcurrentVerifications = 4
+
+// ErrNoMatchingRequirements is returned by VerifyAny when no candidate has the payment's scheme and network
+var ErrNoMatchingRequirements = errors.New("no payment requirements match the payment's scheme and network")
+
+// verifyAnyResult is the verify outcome for the candidate at index
+type verifyAnyResult struct {
+	index int
+	resp  *types.VerifyResponse
+	err   error
+}
+
+// VerifyAny verifies the payment against the candidates with its scheme and network concurrently, and returns
+// the first candidate it is valid for. This is for configurations with overlapping accepts, where the server
+// can't tell which requirements the client paid for; with exact matching, verify the one candidate instead.
+// At most maxConcurrent verifications run at once (DefaultMaxConcurrentVerifications if it is not positive),
+// and the rest are cancelled as soon as one succeeds.
+// If the payment is valid for no candidate, the verify response of the first candidate that answered is
+// returned with nil requirements and no error. If any verification failed with an error, the first error is returned instead,
+// as the payment may have been valid for that candidate.
+func (c *FacilitatorClient) VerifyAny(ctx context.Context, payload *types.PaymentPayload, candidates []*types.PaymentRequirements, maxConcurrent int) (*types.PaymentRequirements, *types.VerifyResponse, error) {
+	var matching []*types.PaymentRequirements
+	for _, requirements := range candidates {
+		if err := checkPayment(payload, requirements); err != nil {
+			return nil, nil, err
+		}
+		if requirements.Scheme == payload.Scheme && requirements.Network == payload.Network {
+			matching = append(matching, requirements)
+		}
+	}
+	if len(matching) == 0 {
+		return nil, nil, ErrNoMatchingRequirements
+	}
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultMaxConcurrentVerifications
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan verifyAnyResult, len(matching))
+	slots := make(chan struct{}, maxConcurrent)
+	go func() {
+		for i, requirements := range matching {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results <- verifyAnyResult{index: i, err: ctx.Err()}
+				continue
+			}
+
+			go func() {
+				defer func() { <-slots }()
+
+				resp, err := c.VerifyWithContext(ctx, payload, requirements)
+				results <- verifyAnyResult{index: i, resp: resp, err: err}
+			}()
+		}
+	}()
+
+	responses := make([]*types.VerifyResponse, len(matching))
+	errs := make([]error, len(matching))
+	for range matching {
+		result := <-results
+		if result.err == nil && result.resp.IsValid {
+			return matching[result.index], result.resp, nil
+		}
+		responses[result.index] = result.resp
+		errs[result.index] = result.err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, resp := range responses {
+		if resp != nil {
+			return nil, resp, nil
+		}
+	}
+
+	return nil, nil, errors.New("facilitator returned no verify response")
+}
@@ -0,0 +1,127 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// newVerifyAnyTestServer accepts payments for requirements paying validPayTo and rejects the rest after a delay,
+// recording the most verifications it served at once
+func newVerifyAnyTestServer(t *testing.T, validPayTo string, maxInFlight *int) *httptest.Server {
+	t.Helper()
+
+	var mu sync.Mutex
+	inFlight := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > *maxInFlight {
+			*maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		var body struct {
+			PaymentRequirements types.PaymentRequirements `json:"paymentRequirements"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.PaymentRequirements.PayTo == validPayTo {
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+			return
+		}
+
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		reason := "invalid_payment_requirements"
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: false, InvalidReason: &reason})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newVerifyAnyCandidates(payTos ...string) []*types.PaymentRequirements {
+	candidates := make([]*types.PaymentRequirements, 0, len(payTos))
+	for _, payTo := range payTos {
+		candidates = append(candidates, &types.PaymentRequirements{Scheme: "exact", Network: types.NetworkBase, PayTo: payTo})
+	}
+	return candidates
+}
+
+func TestVerifyAny(t *testing.T) {
+	var maxInFlight int
+	server := newVerifyAnyTestServer(t, "0xmatch", &maxInFlight)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	payload := newTestPayload()
+	payload.Network = types.NetworkBase
+
+	candidates := newVerifyAnyCandidates("0xa", "0xb", "0xmatch", "0xc", "0xd")
+	// A candidate on another network is never verified
+	candidates = append(candidates, &types.PaymentRequirements{Scheme: "exact", Network: types.NetworkBaseSepolia, PayTo: "0xmatch"})
+
+	requirements, resp, err := client.VerifyAny(context.Background(), payload, candidates, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requirements != candidates[2] || !resp.IsValid {
+		t.Errorf("Expected the matching candidate to be valid, got: %+v, %+v", requirements, resp)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent verifications, got: %d", maxInFlight)
+	}
+}
+
+func TestVerifyAnyNoneValid(t *testing.T) {
+	var maxInFlight int
+	server := newVerifyAnyTestServer(t, "0xmatch", &maxInFlight)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+	payload := newTestPayload()
+	payload.Network = types.NetworkBase
+
+	requirements, resp, err := client.VerifyAny(context.Background(), payload, newVerifyAnyCandidates("0xa", "0xb"), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requirements != nil || resp == nil || resp.IsValid || *resp.InvalidReason != "invalid_payment_requirements" {
+		t.Errorf("Expected an invalid verify response, got: %+v, %+v", requirements, resp)
+	}
+
+	otherNetwork := newTestPayload()
+	_, _, err = client.VerifyAny(context.Background(), otherNetwork, newVerifyAnyCandidates("0xa"), 0)
+	if !errors.Is(err, facilitatorclient.ErrNoMatchingRequirements) {
+		t.Errorf("Expected ErrNoMatchingRequirements, got: %v", err)
+	}
+}
+
+func TestVerifyAnyCancelledCandidatesKeepCircuitClosed(t *testing.T) {
+	var maxInFlight int
+	server := newVerifyAnyTestServer(t, "0xmatch", &maxInFlight)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL},
+		facilitatorclient.WithCircuitBreaker(1, time.Minute))
+	payload := newTestPayload()
+	payload.Network = types.NetworkBase
+
+	if _, _, err := client.VerifyAny(context.Background(), payload, newVerifyAnyCandidates("0xmatch", "0xa", "0xb"), 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Let the cancelled verifications finish
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := client.Verify(payload, newVerifyAnyCandidates("0xmatch")[0]); err != nil {
+		t.Errorf("Expected the cancelled verifications not to trip the breaker, got: %v", err)
+	}
+}
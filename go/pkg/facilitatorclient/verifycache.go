@@ -0,0 +1,159 @@
+package facilitatorclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// VerifyCacheStore stores successful verify responses for WithVerifyCache
+type VerifyCacheStore interface {
+	// Get returns the cached response for key, or false if there is none or it has expired
+	Get(key string) (*types.VerifyResponse, bool)
+	// Set caches the response for key until expiresAt
+	Set(key string, resp *types.VerifyResponse, expiresAt time.Time)
+}
+
+// verifyCacheSweepSize is the number of entries above which the memory store drops expired entries on Set
+const verifyCacheSweepSize = 1024
+
+type verifyCacheEntry struct {
+	resp      types.VerifyResponse
+	expiresAt time.Time
+}
+
+// MemoryVerifyCacheStore is an in-memory VerifyCacheStore
+type MemoryVerifyCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]verifyCacheEntry
+}
+
+// NewMemoryVerifyCacheStore creates a new in-memory verify cache store
+func NewMemoryVerifyCacheStore() *MemoryVerifyCacheStore {
+	return &MemoryVerifyCacheStore{
+		entries: make(map[string]verifyCacheEntry),
+	}
+}
+
+// Get returns the cached response for key, or false if there is none or it has expired
+func (s *MemoryVerifyCacheStore) Get(key string) (*types.VerifyResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil, false
+	}
+
+	resp := entry.resp
+	return &resp, true
+}
+
+// Set caches the response for key until expiresAt
+func (s *MemoryVerifyCacheStore) Set(key string, resp *types.VerifyResponse, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) >= verifyCacheSweepSize {
+		now := time.Now()
+		for k, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	s.entries[key] = verifyCacheEntry{resp: *resp, expiresAt: expiresAt}
+}
+
+// verifyCache skips the facilitator for payloads that were verified as valid within the TTL
+type verifyCache struct {
+	ttl   time.Duration
+	store VerifyCacheStore
+}
+
+// get returns a copy of the cached verify response for the payment
+func (c *verifyCache) get(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (*types.VerifyResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	key, ok := verifyCacheKey(payload, requirements)
+	if !ok {
+		return nil, false
+	}
+
+	resp, ok := c.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	return cloneVerifyResponse(resp), true
+}
+
+// set caches a valid verify response until the TTL elapses or the authorization expires, whichever is first
+func (c *verifyCache) set(payload *types.PaymentPayload, requirements *types.PaymentRequirements, resp *types.VerifyResponse) {
+	if c == nil || resp == nil || !resp.IsValid {
+		return
+	}
+	if payload == nil || payload.Payload == nil || payload.Payload.Authorization == nil {
+		return
+	}
+
+	validBefore, err := strconv.ParseInt(payload.Payload.Authorization.ValidBefore, 10, 64)
+	if err != nil {
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	if authorizationExpiry := time.Unix(validBefore, 0); authorizationExpiry.Before(expiresAt) {
+		expiresAt = authorizationExpiry
+	}
+	if !time.Now().Before(expiresAt) {
+		return
+	}
+
+	key, ok := verifyCacheKey(payload, requirements)
+	if !ok {
+		return
+	}
+
+	c.store.Set(key, resp, expiresAt)
+}
+
+// verifyCacheKey hashes the canonical JSON encoding of the payment payload and requirements
+func verifyCacheKey(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (string, bool) {
+	encoded, err := json.Marshal(struct {
+		PaymentPayload      *types.PaymentPayload      `json:"paymentPayload"`
+		PaymentRequirements *types.PaymentRequirements `json:"paymentRequirements"`
+	}{payload, requirements})
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// cloneVerifyResponse returns a deep copy of resp, so callers sharing a result cannot modify each other's copy
+func cloneVerifyResponse(resp *types.VerifyResponse) *types.VerifyResponse {
+	clone := *resp
+	if resp.InvalidReason != nil {
+		invalidReason := *resp.InvalidReason
+		clone.InvalidReason = &invalidReason
+	}
+	if resp.Payer != nil {
+		payer := *resp.Payer
+		clone.Payer = &payer
+	}
+	return &clone
+}
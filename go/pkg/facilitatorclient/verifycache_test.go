@@ -0,0 +1,195 @@
+package facilitatorclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newVerifyCacheTestServer(t *testing.T, isValid bool, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: isValid})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVerifyCache(t *testing.T) {
+	var calls atomic.Int32
+	server := newVerifyCacheTestServer(t, true, &calls)
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithVerifyCache(time.Minute, nil))
+
+	payload := newSchedulerTestPayload(time.Now().Add(time.Hour))
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia", MaxAmountRequired: "1000000"}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Verify(payload, requirements)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !resp.IsValid {
+			t.Errorf("Expected valid response, got invalid")
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 facilitator request, got: %d", calls.Load())
+	}
+
+	// Different requirements are not served from the cache
+	otherRequirements := *requirements
+	otherRequirements.MaxAmountRequired = "2000000"
+	if _, err := client.Verify(payload, &otherRequirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 facilitator requests, got: %d", calls.Load())
+	}
+}
+
+func TestVerifyCacheSkipsInvalid(t *testing.T) {
+	var calls atomic.Int32
+	server := newVerifyCacheTestServer(t, false, &calls)
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithVerifyCache(time.Minute, nil))
+
+	payload := newSchedulerTestPayload(time.Now().Add(time.Hour))
+	for i := 0; i < 2; i++ {
+		if _, err := client.Verify(payload, &types.PaymentRequirements{}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected invalid verifications not to be cached, got %d requests", calls.Load())
+	}
+}
+
+func TestVerifyCacheExpiresWithAuthorization(t *testing.T) {
+	var calls atomic.Int32
+	server := newVerifyCacheTestServer(t, true, &calls)
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithVerifyCache(time.Hour, facilitatorclient.NewMemoryVerifyCacheStore()))
+
+	// The authorization has already expired, so it must never be served from the cache
+	payload := newSchedulerTestPayload(time.Now().Add(-time.Second))
+	for i := 0; i < 2; i++ {
+		if _, err := client.Verify(payload, &types.PaymentRequirements{}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected expired authorizations not to be cached, got %d requests", calls.Load())
+	}
+}
+
+func TestSingleflight(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		payer := "0xpayer"
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: &payer})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithSingleflight())
+
+	payload := newSchedulerTestPayload(time.Now().Add(time.Hour))
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	const callers = 10
+	responses := make([]*types.VerifyResponse, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Verify(payload, requirements)
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+				return
+			}
+			responses[i] = resp
+		}(i)
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 facilitator request, got: %d", calls.Load())
+	}
+
+	// Every caller gets its own copy of the shared result
+	*responses[0].Payer = "0xmodified"
+	for i := 1; i < callers; i++ {
+		if responses[i] == nil || responses[i] == responses[0] || *responses[i].Payer != "0xpayer" {
+			t.Errorf("Expected an independent copy for caller %d, got: %+v", i, responses[i])
+		}
+	}
+
+	// Once the shared call completes, the next verification goes to the facilitator again
+	if _, err := client.Verify(payload, requirements); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 facilitator requests, got: %d", calls.Load())
+	}
+}
+
+func TestSingleflightCallerCancellation(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+	}))
+	defer server.Close()
+
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{
+		URL: server.URL,
+	}, facilitatorclient.WithSingleflight())
+
+	payload := newSchedulerTestPayload(time.Now().Add(time.Hour))
+	requirements := &types.PaymentRequirements{Scheme: "exact", Network: "base-sepolia"}
+
+	// The caller starting the shared request gives up early
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := client.VerifyWithContext(ctx, payload, requirements)
+		firstErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	resp, err := client.Verify(payload, requirements)
+	if err != nil || !resp.IsValid {
+		t.Errorf("Expected the waiting caller to get the shared result, got: %+v (%v)", resp, err)
+	}
+	if err := <-firstErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the first caller to stop at its deadline, got: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 facilitator request, got: %d", calls.Load())
+	}
+}
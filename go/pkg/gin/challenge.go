@@ -0,0 +1,188 @@
+package gin
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/paymentserver"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ChallengeNonceTTL is how long a challenge nonce issued by WithChallengeNonce can be paid with
+const ChallengeNonceTTL = 5 * time.Minute
+
+// MaxMemoryNonces is the most unexpired nonces a MemoryNonceStore keeps
+const MaxMemoryNonces = 1 << 16
+
+// NonceStore keeps the challenge nonces issued by WithChallengeNonce until they are paid with or expire.
+// Implementations must be safe for concurrent use.
+type NonceStore interface {
+	// Add records an issued nonce that can be consumed until expiresAt
+	Add(nonce string, expiresAt time.Time)
+	// Consume reports whether nonce was issued and hasn't expired, removing it so it can't be consumed again
+	Consume(nonce string) bool
+}
+
+// MemoryNonceStore is an in-memory NonceStore. It keeps at most MaxMemoryNonces nonces: once full, adding a
+// nonce drops the one closest to expiring, whose challenge can then no longer be paid.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces *expirySet
+}
+
+// NewMemoryNonceStore creates a new in-memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces: newExpirySet(),
+	}
+}
+
+// Add records an issued nonce that can be consumed until expiresAt
+func (s *MemoryNonceStore) Add(nonce string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nonces.sweep(time.Now())
+	if _, ok := s.nonces.get(nonce); !ok && s.nonces.len() >= MaxMemoryNonces {
+		s.nonces.evictNext()
+	}
+	s.nonces.set(nonce, expiresAt)
+}
+
+// Consume reports whether nonce was issued and hasn't expired, removing it so it can't be consumed again
+func (s *MemoryNonceStore) Consume(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.nonces.get(nonce)
+	if !ok {
+		return false
+	}
+	s.nonces.remove(nonce)
+
+	return time.Now().Before(expiresAt)
+}
+
+// WithChallengeNonce is an option for the PaymentMiddleware to bind payments to the challenge they answer.
+// Every 402 challenge carries a fresh nonce in the accepts' extra as challengeNonce, recorded in store for
+// ChallengeNonceTTL, and payments are only accepted if their authorization nonce is an unused challenge nonce,
+// so a payment made for another interaction or relayed from elsewhere is refused.
+// The nonce is consumed once the payment has been verified, so a payment whose verification failed, e.g. on a
+// transient facilitator error, can be retried for the same challenge; the cost is that payments answering no
+// challenge are still sent to the facilitator for verification.
+// As the authorization nonce is signed, the binding can't be altered in transit.
+// This is opt-in because clients must cooperate: exactevm.PreparePayment uses the challenge nonce unless
+// given another with exactevm.WithNonce, but other clients may not. If store is nil, an in-memory store is used.
+func WithChallengeNonce(store NonceStore) Options {
+	if store == nil {
+		store = NewMemoryNonceStore()
+	}
+
+	return func(options *PaymentMiddlewareOptions) {
+		options.ChallengeNonces = store
+	}
+}
+
+// issueChallenge returns copies of the accepts carrying a fresh challenge nonce in their extra, recorded in the
+// store of WithChallengeNonce, and the challenge token of WithSignedChallenge signing them, as configured
+func (options *PaymentMiddlewareOptions) issueChallenge(accepts []*types.PaymentRequirements) ([]*types.PaymentRequirements, string, error) {
+	nonce, err := exactevm.CreateNonce()
+	if err != nil {
+		return nil, "", err
+	}
+	challenged, err := withChallengeNonce(accepts, nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var token string
+	if options.ChallengeKey != nil {
+		if token, err = signChallenge(options.ChallengeKey, challenged, nonce, options.now()); err != nil {
+			return nil, "", err
+		}
+	}
+	if options.ChallengeNonces != nil {
+		options.ChallengeNonces.Add(nonce, time.Now().Add(ChallengeNonceTTL))
+	}
+
+	return challenged, token, nil
+}
+
+// withChallengeNonce returns copies of the accepts carrying nonce in their extra
+func withChallengeNonce(accepts []*types.PaymentRequirements, nonce string) ([]*types.PaymentRequirements, error) {
+	challenged := make([]*types.PaymentRequirements, len(accepts))
+	for i, accept := range accepts {
+		requirements := *accept
+		var extra types.ExactEvmExtra
+		if _, err := requirements.DecodeExtra(&extra); err != nil {
+			return nil, err
+		}
+		extra.ChallengeNonce = nonce
+		if err := requirements.SetExtra(extra); err != nil {
+			return nil, fmt.Errorf("failed to set challenge nonce: %w", err)
+		}
+		challenged[i] = &requirements
+	}
+
+	return challenged, nil
+}
+
+// consumeChallengeNonce reports whether the payload's authorization nonce is an unused challenge nonce
+func consumeChallengeNonce(store NonceStore, payload *types.PaymentPayload) bool {
+	if payload.Payload == nil || payload.Payload.Authorization == nil || payload.Payload.Authorization.Nonce == "" {
+		return false
+	}
+
+	return store.Consume(strings.ToLower(payload.Payload.Authorization.Nonce))
+}
+
+// challengeCacheSize is the number of challenge bodies a middleware keeps before dropping them all
+const challengeCacheSize = 1024
+
+// challengeKey identifies the accepts of a request: everything else in them is fixed at construction
+type challengeKey struct {
+	resource    string
+	description string
+	mimeType    string
+}
+
+// challengeCache keeps the marshaled 402 bodies answering requests without a payment,
+// so unpaid traffic isn't re-marshaled on every request
+type challengeCache struct {
+	mu     sync.RWMutex
+	bodies map[challengeKey][]byte
+}
+
+// newChallengeCache creates an empty challenge cache
+func newChallengeCache() *challengeCache {
+	return &challengeCache{
+		bodies: make(map[challengeKey][]byte),
+	}
+}
+
+// body returns the cached challenge body for key, marshaling accepts on a miss
+func (cache *challengeCache) body(key challengeKey, accepts []*types.PaymentRequirements) ([]byte, error) {
+	cache.mu.RLock()
+	body, ok := cache.bodies[key]
+	cache.mu.RUnlock()
+	if ok {
+		return body, nil
+	}
+
+	body, err := paymentserver.ChallengeBody(paymentserver.ErrPaymentRequired.Error(), accepts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.bodies) >= challengeCacheSize {
+		clear(cache.bodies)
+	}
+	cache.bodies[key] = body
+
+	return body, nil
+}
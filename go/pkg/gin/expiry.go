@@ -0,0 +1,109 @@
+package gin
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expirySet is a set of keys each kept until its expiry, for the in-memory stores.
+// Keys are queued in expiry order, so sweeping expired keys costs only the keys it removes
+// rather than a scan of the whole set. It is not safe for concurrent use.
+type expirySet struct {
+	entries map[string]*expiryEntry
+	queue   expiryQueue
+}
+
+// expiryEntry is a key of an expirySet and its position in the expiry queue
+type expiryEntry struct {
+	key       string
+	expiresAt time.Time
+	index     int
+}
+
+// newExpirySet creates an empty expiry set
+func newExpirySet() *expirySet {
+	return &expirySet{
+		entries: make(map[string]*expiryEntry),
+	}
+}
+
+// len returns the number of keys in the set, including expired keys not swept yet
+func (s *expirySet) len() int {
+	return len(s.entries)
+}
+
+// get returns the expiry of key, if it is in the set
+func (s *expirySet) get(key string) (time.Time, bool) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return entry.expiresAt, true
+}
+
+// set adds key until expiresAt, replacing its expiry if it is already in the set
+func (s *expirySet) set(key string, expiresAt time.Time) {
+	if entry, ok := s.entries[key]; ok {
+		entry.expiresAt = expiresAt
+		heap.Fix(&s.queue, entry.index)
+		return
+	}
+
+	entry := &expiryEntry{key: key, expiresAt: expiresAt}
+	heap.Push(&s.queue, entry)
+	s.entries[key] = entry
+}
+
+// remove drops key from the set
+func (s *expirySet) remove(key string) {
+	if entry, ok := s.entries[key]; ok {
+		heap.Remove(&s.queue, entry.index)
+		delete(s.entries, key)
+	}
+}
+
+// sweep drops the keys that have expired by now
+func (s *expirySet) sweep(now time.Time) {
+	for len(s.queue) > 0 && !now.Before(s.queue[0].expiresAt) {
+		s.evictNext()
+	}
+}
+
+// evictNext drops the key expiring first
+func (s *expirySet) evictNext() {
+	entry := heap.Pop(&s.queue).(*expiryEntry)
+	delete(s.entries, entry.key)
+}
+
+// expiryQueue is a heap.Interface of expiry entries, ordered by expiry
+type expiryQueue []*expiryEntry
+
+func (q expiryQueue) Len() int {
+	return len(q)
+}
+
+func (q expiryQueue) Less(i, j int) bool {
+	return q[i].expiresAt.Before(q[j].expiresAt)
+}
+
+func (q expiryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *expiryQueue) Push(x any) {
+	entry := x.(*expiryEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *expiryQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+
+	return entry
+}
@@ -0,0 +1,109 @@
+package gin
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// SettlementLedger tallies successful settlements by network and by asset, as a built-in revenue report for
+// simple setups. It keeps running totals rather than each settlement, so its memory is bounded by the number
+// of networks and assets, and Reset starts a new reporting period.
+// Pass it to WithObserver to have the PaymentMiddleware feed it. It is safe for concurrent use.
+type SettlementLedger struct {
+	mu        sync.Mutex
+	byNetwork map[string]*big.Int
+	byAsset   map[string]*big.Int
+	count     int
+}
+
+// NewSettlementLedger creates an empty settlement ledger
+func NewSettlementLedger() *SettlementLedger {
+	return &SettlementLedger{
+		byNetwork: make(map[string]*big.Int),
+		byAsset:   make(map[string]*big.Int),
+	}
+}
+
+// Record adds a settled amount, in atomic units of the asset, to the totals
+func (l *SettlementLedger) Record(network, asset, amount string) error {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid settled amount: %q", amount)
+	}
+	asset = normalizeAddress(asset)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	addTotal(l.byNetwork, network, value)
+	addTotal(l.byAsset, asset, value)
+	l.count++
+
+	return nil
+}
+
+// ObservePayment records the amount of EventSettled events, ignoring other events
+func (l *SettlementLedger) ObservePayment(event PaymentEvent) {
+	if event.Type != EventSettled {
+		return
+	}
+
+	if err := l.Record(event.Network, event.Asset, event.Amount); err != nil {
+		fmt.Println("failed to record settlement:", err)
+	}
+}
+
+// TotalByNetwork returns the settled totals keyed by network, in atomic units of each network's assets
+func (l *SettlementLedger) TotalByNetwork() map[string]*big.Int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return copyTotals(l.byNetwork)
+}
+
+// TotalByAsset returns the settled totals keyed by checksummed asset address, in atomic units of the asset
+func (l *SettlementLedger) TotalByAsset() map[string]*big.Int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return copyTotals(l.byAsset)
+}
+
+// Count returns the number of settlements recorded
+func (l *SettlementLedger) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.count
+}
+
+// Reset clears the totals
+func (l *SettlementLedger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.byNetwork = make(map[string]*big.Int)
+	l.byAsset = make(map[string]*big.Int)
+	l.count = 0
+}
+
+// addTotal adds value to the total of key
+func addTotal(totals map[string]*big.Int, key string, value *big.Int) {
+	total, ok := totals[key]
+	if !ok {
+		total = new(big.Int)
+		totals[key] = total
+	}
+	total.Add(total, value)
+}
+
+// copyTotals returns a copy of totals that the caller can modify
+func copyTotals(totals map[string]*big.Int) map[string]*big.Int {
+	copied := make(map[string]*big.Int, len(totals))
+	for key, total := range totals {
+		copied[key] = new(big.Int).Set(total)
+	}
+
+	return copied
+}
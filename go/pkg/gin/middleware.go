@@ -1,31 +1,138 @@
 package gin
 
 import (
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/coinbase/x402/go/pkg/exactevm"
 	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/paymentserver"
 	"github.com/coinbase/x402/go/pkg/types"
 )
 
-const x402Version = 1
+const x402Version = types.X402Version
+
+// DeprecatedVersionHeader is set on responses to payments using a deprecated x402 version,
+// warning the client to upgrade to the server's version
+const DeprecatedVersionHeader = "X-Payment-Version-Deprecated"
+
+// paymentPayloadContextKey is the request context key of the verified payment payload
+type paymentPayloadContextKey struct{}
+
+// payerContextKey is the request context key of the verified payer
+type payerContextKey struct{}
+
+// paymentResultContextKey is the request context key of the payment result
+type paymentResultContextKey struct{}
+
+// PaymentFromContext returns the verified payment payload of the request.
+// It accepts the *gin.Context of the handler or the context of its *http.Request,
+// and only reports a payload once the PaymentMiddleware has verified it.
+func PaymentFromContext(ctx context.Context) (*types.PaymentPayload, bool) {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		ctx = c.Request.Context()
+	}
+
+	payload, ok := ctx.Value(paymentPayloadContextKey{}).(*types.PaymentPayload)
+	return payload, ok
+}
+
+// PayerFromContext returns the verified payer of the request, as reported by the facilitator
+// or else the authorization's from address. Like PaymentFromContext, it accepts the *gin.Context
+// of the handler or the context of its *http.Request.
+func PayerFromContext(ctx context.Context) (string, bool) {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		ctx = c.Request.Context()
+	}
+
+	payer, ok := ctx.Value(payerContextKey{}).(string)
+	return payer, ok
+}
+
+// PaymentResultFromContext returns the result of the request's verified payment: the verify response, the
+// requirements it was matched to, the payer and the verification time. The middleware fills in the settle response
+// and duration once it settles, which with the default SettleAfterSuccess is after the handler has returned, so
+// the handler sees them only with SettleBeforeHandler. Like PaymentFromContext, it accepts the *gin.Context of the
+// handler or the context of its *http.Request.
+func PaymentResultFromContext(ctx context.Context) (*types.PaymentResult, bool) {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		ctx = c.Request.Context()
+	}
+
+	result, ok := ctx.Value(paymentResultContextKey{}).(*types.PaymentResult)
+	return result, ok
+}
+
+// PaymentVersionFromContext returns the x402 version of the request's verified payment,
+// which may be older than the server's if the client hasn't upgraded, see DeprecatedVersionHeader.
+// Like PaymentFromContext, it accepts the *gin.Context of the handler or the context of its *http.Request.
+func PaymentVersionFromContext(ctx context.Context) (int, bool) {
+	payload, ok := PaymentFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+
+	return payload.X402Version, true
+}
 
 // PaymentMiddlewareOptions is the options for the PaymentMiddleware.
 type PaymentMiddlewareOptions struct {
-	Description       string
-	MimeType          string
-	MaxTimeoutSeconds int
-	OutputSchema      *json.RawMessage
-	FacilitatorConfig *types.FacilitatorConfig
-	Testnet           bool
-	CustomPaywallHTML string
-	Resource          string
-	ResourceRootURL   string
+	Description         string
+	MimeType            string
+	MaxTimeoutSeconds   int
+	OutputSchema        *json.RawMessage
+	FacilitatorConfig   *types.FacilitatorConfig
+	Testnet             bool
+	CustomPaywallHTML   string
+	Resource            string
+	ResourceRootURL     string
+	VerifyOnly          bool
+	OnVerified          func(*types.PaymentPayload, *types.PaymentRequirements)
+	PayerAllowlist      map[string]struct{}
+	PayerBlocklist      map[string]struct{}
+	SettlementRecipient string
+	Observer            Observer
+	MaxChargeable       *big.Int
+	DescriptionFunc     func(*gin.Context) string
+	MimeTypeFunc        func(*gin.Context) string
+	RedactResource      func(string) string
+	Clock               exactevm.Clock
+	// VerifyAny verifies payments against every accept concurrently, see WithVerifyAny
+	VerifyAny                  bool
+	AlternativeAccepts         []*types.PaymentRequirements
+	MaxConcurrentVerifications int
+	ExactAmount                bool
+	// RequirementsBinding checks locally that payments were signed for the requirements they settle, see WithRequirementsBinding
+	RequirementsBinding bool
+	NameResolver        NameResolver
+	AcceptsOrder        func(a, b *types.PaymentRequirements) int
+	ChallengeNonces     NonceStore
+	// ChallengeKey signs challenges and verifies the ones payments echo, see WithSignedChallenge
+	ChallengeKey ed25519.PrivateKey
+	// Usages records the authorizations honored per resource, see WithUsageStore
+	Usages UsageStore
+	// PaymentRequiredStatus and InvalidPaymentStatus answer unpaid requests and refused payments, see WithStatusCodes
+	PaymentRequiredStatus int
+	InvalidPaymentStatus  int
+	// RequirementsFunc computes the accepts of each request, see DynamicPaymentMiddleware
+	RequirementsFunc func(*http.Request) ([]types.PaymentRequirements, error)
+	SettleMode       SettleMode
+	// FacilitatorClient, if set, is used instead of a client for FacilitatorConfig, see PaymentMiddlewareSingle
+	FacilitatorClient *facilitatorclient.FacilitatorClient
 }
 
 // Options is the type for the options for the PaymentMiddleware.
@@ -45,6 +152,22 @@ func WithMimeType(mimeType string) Options {
 	}
 }
 
+// WithDescriptionFunc is an option for the PaymentMiddleware to derive the description from each request,
+// e.g. from a path parameter naming the item being bought. An empty result falls back to WithDescription.
+func WithDescriptionFunc(description func(*gin.Context) string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.DescriptionFunc = description
+	}
+}
+
+// WithMimeTypeFunc is an option for the PaymentMiddleware to derive the mime type of the paid content
+// from each request. An empty result falls back to WithMimeType.
+func WithMimeTypeFunc(mimeType func(*gin.Context) string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.MimeTypeFunc = mimeType
+	}
+}
+
 // WithMaxDeadlineSeconds is an option for the PaymentMiddleware to set the max timeout seconds.
 func WithMaxTimeoutSeconds(maxTimeoutSeconds int) Options {
 	return func(options *PaymentMiddlewareOptions) {
@@ -93,33 +216,260 @@ func WithResourceRootURL(resourceRootURL string) Options {
 	}
 }
 
+// WithVerifyOnly is an option for the PaymentMiddleware to verify payments without settling them.
+// Once the handler succeeds, onVerified receives the verified payload and the requirements as sent to the
+// facilitator (see WithResourceRedaction) so the payment can be settled later, e.g. by passing
+// facilitatorclient.SettlementScheduler.Schedule.
+// The authorization must still be valid when the deferred settlement runs; if it expires first
+// the payment can no longer be collected.
+func WithVerifyOnly(onVerified func(*types.PaymentPayload, *types.PaymentRequirements)) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.VerifyOnly = true
+		options.OnVerified = onVerified
+	}
+}
+
+// WithPayerAllowlist is an option for the PaymentMiddleware to only accept payments from the given addresses.
+// Verified payments from any other payer are rejected with 403 Forbidden.
+func WithPayerAllowlist(addresses []string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.PayerAllowlist = newAddressSet(addresses)
+	}
+}
+
+// WithPayerBlocklist is an option for the PaymentMiddleware to reject payments from the given addresses.
+// Verified payments from a blocked payer are rejected with 403 Forbidden.
+func WithPayerBlocklist(addresses []string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.PayerBlocklist = newAddressSet(addresses)
+	}
+}
+
+// WithSettlementRecipient is an option for the PaymentMiddleware to have payments transferred to recipient,
+// such as a split contract or escrow, while payTo keeps advertising the merchant address.
+// The recipient is carried in the requirements' extra, so clients sign the transfer to it directly;
+// the facilitator must support settlementRecipient for verification to succeed.
+func WithSettlementRecipient(recipient string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.SettlementRecipient = recipient
+	}
+}
+
+// WithMaxChargeable is an option for the PaymentMiddleware to refuse any payment above amount,
+// in atomic units of the asset, as a last line of defense against a misconfigured price.
+// Payments above the cap are rejected with 500 Internal Server Error before they are verified or settled.
+// It panics if amount is not a non-negative integer.
+func WithMaxChargeable(amount string) Options {
+	maxChargeable, ok := new(big.Int).SetString(amount, 10)
+	if !ok || maxChargeable.Sign() < 0 {
+		panic(fmt.Sprintf("invalid max chargeable amount: %q", amount))
+	}
+
+	return func(options *PaymentMiddlewareOptions) {
+		options.MaxChargeable = maxChargeable
+	}
+}
+
+// WithClock is an option for the PaymentMiddleware to compute Retry-After for not yet valid payments against clock
+// instead of the system time, e.g. the testclock.Clock shared with an inmemoryfacilitator.Facilitator
+func WithClock(clock exactevm.Clock) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Clock = clock
+	}
+}
+
+// WithVerifyAny is an option for the PaymentMiddleware to advertise alternative payment requirements alongside
+// the default ones, and verify each payment against all accepts with its scheme and network concurrently,
+// settling against the first it is valid for (see facilitatorclient.FacilitatorClient.VerifyAny).
+// This is for overlapping accepts, e.g. the same asset on one network paid to different addresses, where the
+// server can't tell which one the client paid for. At most maxConcurrent verifications run at once.
+// An alternative without a resource, description or mime type gets the default requirements' one.
+func WithVerifyAny(maxConcurrent int, alternatives ...*types.PaymentRequirements) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.VerifyAny = true
+		options.AlternativeAccepts = alternatives
+		options.MaxConcurrentVerifications = maxConcurrent
+	}
+}
+
+// WithAcceptsOrder is an option for the PaymentMiddleware to order the advertised accepts with compare, as for
+// slices.SortStableFunc, since clients commonly pay with the first accept they can satisfy.
+// Accepts comparing equal keep their configured order: the default requirements first,
+// then the alternatives of WithVerifyAny in the order given.
+func WithAcceptsOrder(compare func(a, b *types.PaymentRequirements) int) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.AcceptsOrder = compare
+	}
+}
+
+// PreferNetworks orders accepts on the given networks first, in the order given, for WithAcceptsOrder
+func PreferNetworks(networks ...string) func(a, b *types.PaymentRequirements) int {
+	rank := func(requirements *types.PaymentRequirements) int {
+		if i := slices.Index(networks, requirements.Network); i >= 0 {
+			return i
+		}
+		return len(networks)
+	}
+
+	return func(a, b *types.PaymentRequirements) int {
+		return cmp.Compare(rank(a), rank(b))
+	}
+}
+
+// WithRequirementsBinding is an option for the PaymentMiddleware to check locally, once the facilitator verified
+// a payment, that its signed recipient, value and asset are those of the requirements it is settled against, so a
+// payment signed for other requirements isn't charged even if the facilitator, or the accept VerifyAny matched,
+// got it wrong. Mismatches are challenged with exactevm.ReasonRequirementsMismatch.
+// The signature is recovered locally, so payers signing through a smart contract wallet are refused.
+func WithRequirementsBinding() Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.RequirementsBinding = true
+	}
+}
+
+// WithExactAmount is an option for the PaymentMiddleware to refuse payments authorizing more than the required
+// amount. By default, as in the x402 reference facilitator, any authorized value covering it is accepted.
+func WithExactAmount() Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.ExactAmount = true
+	}
+}
+
+// WithResourceRedaction is an option for the PaymentMiddleware to rewrite the resource URL before it is sent
+// to the facilitator, e.g. with StripResourceQuery or HashResource, so sensitive paths or query parameters
+// are not disclosed to a third party. Clients still receive the full resource in the payment requirements.
+// Facilitators that validate or record the resource themselves will only see the redacted value, and may
+// reject payments whose resource isn't a URL.
+func WithResourceRedaction(redact func(resource string) string) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.RedactResource = redact
+	}
+}
+
+// WithStatusCodes is an option for the PaymentMiddleware to answer requests without a payment with paymentRequired
+// and refused payments with invalidPayment, e.g. 403 Forbidden, instead of 402 Payment Required for both.
+// The body is still the x402 challenge advertising the accepts. This is meant for deployments behind proxies or CDNs
+// that mangle 402 responses: x402 clients recognize a challenge by its 402 status, so other statuses only
+// interoperate with clients configured to expect them. A zero status keeps 402; it panics if a status isn't a 4xx.
+func WithStatusCodes(paymentRequired, invalidPayment int) Options {
+	for _, status := range []int{paymentRequired, invalidPayment} {
+		if status != 0 && (status < 400 || status > 499) {
+			panic(fmt.Sprintf("invalid payment status code %d: must be a client error status", status))
+		}
+	}
+
+	return func(options *PaymentMiddlewareOptions) {
+		if paymentRequired != 0 {
+			options.PaymentRequiredStatus = paymentRequired
+		}
+		if invalidPayment != 0 {
+			options.InvalidPaymentStatus = invalidPayment
+		}
+	}
+}
+
+// StripResourceQuery redacts a resource URL down to its scheme, host and path
+func StripResourceQuery(resource string) string {
+	if i := strings.IndexAny(resource, "?#"); i >= 0 {
+		return resource[:i]
+	}
+
+	return resource
+}
+
+// HashResource redacts a resource URL to its hex encoded SHA-256 hash, prefixed with "sha256:"
+func HashResource(resource string) string {
+	sum := sha256.Sum256([]byte(resource))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// DynamicPaymentMiddleware is PaymentMiddleware for routes whose price depends on the request,
+// e.g. on the size of a query. The requirements function is called for every request to compute the accepts
+// of its challenge, which are then verified and settled as with PaymentMiddleware: payments are verified against
+// the first of them, or against all of them with WithVerifyAny. An empty resource, description or mime type
+// is filled in from the options. If the function fails or returns no requirements, the request is answered
+// with 500 Internal Server Error rather than a challenge. WithTestnet and WithSettlementRecipient don't apply
+// to computed requirements.
+func DynamicPaymentMiddleware(requirements func(r *http.Request) ([]types.PaymentRequirements, error), opts ...Options) gin.HandlerFunc {
+	if requirements == nil {
+		panic("DynamicPaymentMiddleware requires a requirements function")
+	}
+
+	withRequirements := func(options *PaymentMiddlewareOptions) {
+		options.RequirementsFunc = requirements
+	}
+	return PaymentMiddleware(new(big.Float), "", append(slices.Clip(opts), withRequirements)...)
+}
+
+// PaymentMiddlewareSingle is the middleware for a single payment requirements paid through client, for callers
+// of APIs taking one types.PaymentRequirements rather than a list of accepts. It wraps requirements in a
+// one-element accepts list for DynamicPaymentMiddleware, so it behaves as a route with fixed requirements.
+// If client is nil, one is created for the WithFacilitatorConfig option.
+//
+// Deprecated: pass the requirements as accepts to DynamicPaymentMiddleware, which advertises any number of
+// them, or use PaymentMiddleware and add accepts with WithVerifyAny.
+func PaymentMiddlewareSingle(requirements types.PaymentRequirements, client *facilitatorclient.FacilitatorClient, opts ...Options) gin.HandlerFunc {
+	accepts := []types.PaymentRequirements{requirements}
+	withClient := func(options *PaymentMiddlewareOptions) {
+		if client != nil {
+			options.FacilitatorClient = client
+		}
+	}
+
+	return DynamicPaymentMiddleware(func(*http.Request) ([]types.PaymentRequirements, error) {
+		return slices.Clone(accepts), nil
+	}, append(slices.Clip(opts), withClient)...)
+}
+
 // PaymentMiddleware is the Gin middleware for the resource server using the x402payment protocol.
 // Amount: the decimal denominated amount to charge (ex: 0.01 for 1 cent)
+// An amount of 0 gates the route on a valid payment signature, proving control of the payer's wallet,
+// without charging: the payment is verified but never settled.
+// The amount and address make the first of the advertised accepts, unless reordered with WithAcceptsOrder;
+// further accepts are added with WithVerifyAny, so single-requirement callers keep working unchanged.
+// Payments are settled once the handler has succeeded, unless WithSettleMode sets another SettleMode.
+// It panics if a name configured with WithNameResolver cannot be resolved.
 func PaymentMiddleware(amount *big.Float, address string, opts ...Options) gin.HandlerFunc {
 	options := &PaymentMiddlewareOptions{
 		FacilitatorConfig: &types.FacilitatorConfig{
 			URL: facilitatorclient.DefaultFacilitatorURL,
 		},
-		MaxTimeoutSeconds: 60,
-		Testnet:           true,
+		MaxTimeoutSeconds:     60,
+		Testnet:               true,
+		PaymentRequiredStatus: http.StatusPaymentRequired,
+		InvalidPaymentStatus:  http.StatusPaymentRequired,
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	return func(c *gin.Context) {
-		var (
-			network              = "base"
-			usdcAddress          = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
-			facilitatorClient    = facilitatorclient.NewFacilitatorClient(options.FacilitatorConfig)
-			maxAmountRequired, _ = new(big.Float).Mul(amount, big.NewFloat(1e6)).Int(nil)
-		)
+	// Fail at setup rather than on the first request if a configured name doesn't resolve
+	address, err := resolveAddress(options.NameResolver, address)
+	if err != nil {
+		panic(fmt.Sprintf("invalid payTo address: %v", err))
+	}
+	options.SettlementRecipient, err = resolveAddress(options.NameResolver, options.SettlementRecipient)
+	if err != nil {
+		panic(fmt.Sprintf("invalid settlement recipient: %v", err))
+	}
 
-		if options.Testnet {
-			network = "base-sepolia"
-			usdcAddress = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
-		}
+	observers := newObserverQueue(options.Observer)
+	facilitatorClient := options.FacilitatorClient
+	if facilitatorClient == nil {
+		facilitatorClient = facilitatorclient.NewFacilitatorClient(options.FacilitatorConfig)
+	}
+
+	// Unpaid traffic is answered from the paywall rendered here and the challenge bodies cached by challenges,
+	// rather than rendering them for every request
+	paywallHTML := []byte(options.CustomPaywallHTML)
+	if len(paywallHTML) == 0 {
+		paywallHTML = []byte(getPaywallHtml(options))
+	}
+	challenges := newChallengeCache()
+
+	return func(c *gin.Context) {
+		start := time.Now()
 
 		fmt.Println("Payment middleware checking request:", c.Request.URL)
 
@@ -133,55 +483,171 @@ func PaymentMiddleware(amount *big.Float, address string, opts ...Options) gin.H
 			resource = options.Resource
 		}
 
-		paymentRequirements := &types.PaymentRequirements{
-			Scheme:            "exact",
-			Network:           network,
-			MaxAmountRequired: maxAmountRequired.String(),
-			Resource:          resource,
-			Description:       options.Description,
-			MimeType:          options.MimeType,
-			PayTo:             address,
-			MaxTimeoutSeconds: options.MaxTimeoutSeconds,
-			Asset:             usdcAddress,
-			OutputSchema:      options.OutputSchema,
-			Extra:             nil,
-		}
-
-		if err := paymentRequirements.SetUSDCInfo(options.Testnet); err != nil {
-			fmt.Println("failed to set USDC info:", err)
+		accepts, err := options.accepts(c, amount, address, resource)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"error":       err.Error(),
 				"x402Version": x402Version,
 			})
 			return
 		}
+		paymentRequirements := accepts[0]
+		for _, alternative := range options.AlternativeAccepts {
+			accepts = append(accepts, withDefaults(alternative, paymentRequirements))
+		}
+		if options.AcceptsOrder != nil {
+			slices.SortStableFunc(accepts, options.AcceptsOrder)
+		}
+
+		// The facilitator sees copies of the requirements with the resource redacted, if configured
+		facilitatorAccepts := accepts
+		if options.RedactResource != nil {
+			facilitatorAccepts = make([]*types.PaymentRequirements, len(accepts))
+			for i, requirements := range accepts {
+				redacted := *requirements
+				redacted.Resource = options.RedactResource(requirements.Resource)
+				facilitatorAccepts[i] = &redacted
+			}
+		}
+		facilitatorRequirements := facilitatorAccepts[slices.Index(accepts, paymentRequirements)]
+
+		var payer string
+		observe := func(eventType PaymentEventType, reason string) {
+			now := time.Now()
+			observers.emit(PaymentEvent{
+				Type:     eventType,
+				Resource: resource,
+				Payer:    payer,
+				Amount:   paymentRequirements.MaxAmountRequired,
+				Network:  paymentRequirements.Network,
+				Asset:    paymentRequirements.Asset,
+				Reason:   reason,
+				Time:     now,
+				Elapsed:  now.Sub(start),
+			})
+		}
+
+		// challenge answers with 402 Payment Required, or the status set with WithStatusCodes, advertising the accepts.
+		// Challenges of requests without a payment are served from the cache, unless they carry a fresh challenge nonce
+		// or token, or are priced per request.
+		challenge := func(reason string) {
+			c.Abort()
+			status := options.InvalidPaymentStatus
+			if reason == paymentserver.ErrPaymentRequired.Error() {
+				status = options.PaymentRequiredStatus
+			}
+
+			var body []byte
+			var err error
+			switch {
+			case options.ChallengeNonces != nil || options.ChallengeKey != nil:
+				var challenged []*types.PaymentRequirements
+				var token string
+				if challenged, token, err = options.issueChallenge(accepts); err == nil {
+					body, err = paymentserver.ChallengeBody(reason, challenged...)
+				}
+				if err == nil && token != "" {
+					c.Header(types.PaymentChallengeHeader, token)
+				}
+			case reason == paymentserver.ErrPaymentRequired.Error() && options.RequirementsFunc == nil:
+				key := challengeKey{resource: resource, description: paymentRequirements.Description, mimeType: paymentRequirements.MimeType}
+				body, err = challenges.body(key, accepts)
+			default:
+				body, err = paymentserver.ChallengeBody(reason, accepts...)
+			}
+			if err != nil {
+				fmt.Println("failed to create payment challenge:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":       err.Error(),
+					"x402Version": x402Version,
+				})
+				return
+			}
+			paymentserver.WriteChallenge(c.Writer, status, body)
+		}
 
 		payment := c.GetHeader("X-PAYMENT")
 		paymentPayload, err := types.DecodePaymentPayloadFromBase64(payment)
+		if errors.Is(err, types.ErrUnsupportedX402Version) {
+			fmt.Println("Unsupported payment version:", err)
+			observe(EventVerifyFailed, err.Error())
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":       err.Error(),
+				"x402Version": x402Version,
+			})
+			return
+		}
 		if err != nil {
+			observe(EventChallenged, "")
 			if isWebBrowser {
-				html := options.CustomPaywallHTML
-				if html == "" {
-					html = getPaywallHtml(options)
-				}
 				c.Abort()
-				c.Data(http.StatusPaymentRequired, "text/html", []byte(html))
+				c.Data(options.PaymentRequiredStatus, "text/html", paywallHTML)
 				return
 			}
 
-			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
-				"error":       "X-PAYMENT header is required",
-				"accepts":     []*types.PaymentRequirements{paymentRequirements},
-				"x402Version": x402Version,
-			})
+			challenge(paymentserver.ErrPaymentRequired.Error())
 			return
 		}
-		paymentPayload.X402Version = x402Version
+
+		payer = paymentserver.Payer(paymentPayload, nil)
+		if types.IsDeprecatedX402Version(paymentPayload.X402Version) {
+			c.Header(DeprecatedVersionHeader, fmt.Sprintf("x402Version %d is deprecated, upgrade to %d", paymentPayload.X402Version, x402Version))
+		}
+
+		// Catch payments signed for another chain before asking the facilitator.
+		// VerifyAny only verifies the accepts with the payment's network instead.
+		if err := exactevm.CheckNetwork(paymentPayload, paymentRequirements); err != nil && !options.VerifyAny {
+			fmt.Println("Invalid payment network:", err)
+			observe(EventVerifyFailed, err.Error())
+			challenge(err.Error())
+			return
+		}
+
+		if options.MaxChargeable != nil {
+			for _, requirements := range accepts {
+				if amount, ok := exceedsMaxChargeable(paymentPayload, requirements, options.MaxChargeable); ok {
+					fmt.Printf("WARNING: refusing payment of %s, above the max chargeable amount of %s. Check the configured price.\n", amount, options.MaxChargeable)
+					observe(EventVerifyFailed, "payment exceeds the max chargeable amount")
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+						"error":       "payment exceeds the max chargeable amount",
+						"x402Version": x402Version,
+					})
+					return
+				}
+			}
+		}
+
+		if options.ChallengeKey != nil {
+			if err := verifyChallenge(options.ChallengeKey.Public().(ed25519.PublicKey), c.GetHeader(types.PaymentChallengeHeader), accepts, paymentPayload, options.now()); err != nil {
+				fmt.Println("Invalid payment challenge:", err)
+				observe(EventVerifyFailed, ReasonInvalidChallenge)
+				challenge(ReasonInvalidChallenge)
+				return
+			}
+		}
 
 		// Verify payment
-		response, err := facilitatorClient.Verify(paymentPayload, paymentRequirements)
+		var response *types.VerifyResponse
+		verifyStart := time.Now()
+		if options.VerifyAny {
+			var matched *types.PaymentRequirements
+			matched, response, err = facilitatorClient.VerifyAny(c.Request.Context(), paymentPayload, facilitatorAccepts, options.MaxConcurrentVerifications)
+			if errors.Is(err, facilitatorclient.ErrNoMatchingRequirements) {
+				fmt.Println("Invalid payment network:", err)
+				observe(EventVerifyFailed, err.Error())
+				challenge(err.Error())
+				return
+			}
+			if i := slices.Index(facilitatorAccepts, matched); i >= 0 {
+				paymentRequirements, facilitatorRequirements = accepts[i], matched
+			}
+		} else {
+			response, err = facilitatorClient.Verify(paymentPayload, facilitatorRequirements)
+		}
+		verifyDuration := time.Since(verifyStart)
 		if err != nil {
 			fmt.Println("failed to verify", err)
+			observe(EventVerifyFailed, err.Error())
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 				"error":       err.Error(),
 				"x402Version": x402Version,
@@ -191,15 +657,152 @@ func PaymentMiddleware(amount *big.Float, address string, opts ...Options) gin.H
 
 		if !response.IsValid {
 			fmt.Println("Invalid payment: ", response.InvalidReason)
-			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
-				"error":       response.InvalidReason,
-				"accepts":     []*types.PaymentRequirements{paymentRequirements},
+			observe(EventVerifyFailed, stringValue(response.InvalidReason))
+			if stringValue(response.InvalidReason) == exactevm.ReasonNotYetValid {
+				if seconds, ok := retryAfterSeconds(paymentPayload, options.now()); ok {
+					c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+				}
+			}
+			challenge(stringValue(response.InvalidReason))
+			return
+		}
+
+		payer = paymentserver.Payer(paymentPayload, response)
+		if options.ExactAmount {
+			if err := exactevm.CheckValue(paymentPayload, paymentRequirements, exactevm.WithExactAmount()); err != nil {
+				fmt.Println("Invalid payment amount:", err)
+				observe(EventVerifyFailed, err.Error())
+				challenge(err.Error())
+				return
+			}
+		}
+		if options.RequirementsBinding {
+			if err := exactevm.CheckRequirementsBinding(paymentPayload, paymentRequirements); err != nil {
+				fmt.Println("Payment does not match the requirements:", err)
+				observe(EventVerifyFailed, err.Error())
+				challenge(err.Error())
+				return
+			}
+		}
+		if !options.isPayerAllowed(payer) {
+			fmt.Println("Payer not allowed:", payer)
+			observe(EventVerifyFailed, "payer is not allowed")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":       "payer is not allowed",
 				"x402Version": x402Version,
 			})
 			return
 		}
 
+		if options.ChallengeNonces != nil && !consumeChallengeNonce(options.ChallengeNonces, paymentPayload) {
+			fmt.Println("Payment does not answer a challenge")
+			observe(EventVerifyFailed, "payment does not answer a challenge")
+			challenge("payment does not answer a challenge")
+			return
+		}
+
+		if options.Usages != nil && !useAuthorization(options.Usages, resource, payer, paymentPayload) {
+			fmt.Println("Payment already used for", resource)
+			observe(EventVerifyFailed, ReasonAlreadyUsed)
+			challenge(ReasonAlreadyUsed)
+			return
+		}
+
 		fmt.Println("Payment verified, proceeding")
+		observe(EventVerified, "")
+
+		result := &types.PaymentResult{
+			Payload:        paymentPayload,
+			Requirements:   paymentRequirements,
+			Payer:          payer,
+			Verify:         response,
+			VerifyDuration: verifyDuration,
+		}
+		ctx := context.WithValue(c.Request.Context(), paymentPayloadContextKey{}, paymentPayload)
+		ctx = context.WithValue(ctx, payerContextKey{}, payer)
+		c.Request = c.Request.WithContext(context.WithValue(ctx, paymentResultContextKey{}, result))
+
+		if options.VerifyOnly {
+			c.Next()
+			if !c.IsAborted() && options.OnVerified != nil {
+				options.OnVerified(paymentPayload, facilitatorRequirements)
+			}
+			return
+		}
+
+		// A zero amount only proves control of the payer's wallet, so there is nothing to settle
+		if isFree(paymentRequirements) {
+			c.Next()
+			return
+		}
+
+		// settle settles the payment and sets the X-PAYMENT-RESPONSE header, reporting whether the handler's
+		// response may be written. If not, it has answered the request itself.
+		settle := func() bool {
+			settleStart := time.Now()
+			settleResponse, err := facilitatorClient.Settle(paymentPayload, facilitatorRequirements)
+			result.SettleDuration = time.Since(settleStart)
+			if err != nil {
+				fmt.Println("Settlement failed:", err)
+				observe(EventSettleFailed, err.Error())
+				challenge(err.Error())
+				return false
+			}
+
+			result.Settle = settleResponse
+
+			// A prepared-only settlement is not settled until the payer submits the transaction
+			// returned in the X-PAYMENT-RESPONSE header
+			if settleResponse.Success && settleResponse.NeedsSubmission() {
+				observe(EventSubmissionRequired, "")
+			} else if settleResponse.Success {
+				observe(EventSettled, "")
+			} else {
+				observe(EventSettleFailed, stringValue(settleResponse.ErrorReason))
+			}
+
+			settleResponseHeader, err := settleResponse.EncodeToBase64String()
+			if err != nil {
+				fmt.Println("Settle Header Encoding failed:", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error":       err.Error(),
+					"x402Version": x402Version,
+				})
+				return false
+			}
+
+			c.Header("X-PAYMENT-RESPONSE", settleResponseHeader)
+			return true
+		}
+
+		switch options.SettleMode {
+		case SettleBeforeHandler:
+			if settle() {
+				c.Next()
+			}
+			return
+
+		case SettleOnFirstByte:
+			writer := &firstByteWriter{ResponseWriter: c.Writer}
+			writer.onFirstByte = func() bool {
+				if writer.Status() >= http.StatusBadRequest {
+					return true
+				}
+				c.Writer = writer.ResponseWriter
+				defer func() { c.Writer = writer }()
+				return settle()
+			}
+			c.Writer = writer
+
+			c.Next()
+
+			c.Writer = writer.ResponseWriter
+			// A handler that wrote nothing still answers the request once gin writes its status
+			if !writer.started && !c.IsAborted() && writer.Status() < http.StatusBadRequest {
+				settle()
+			}
+			return
+		}
 
 		// Create a custom response writer to intercept the response
 		writer := &responseWriter{
@@ -212,44 +815,228 @@ func PaymentMiddleware(amount *big.Float, address string, opts ...Options) gin.H
 		// Execute the handler
 		c.Next()
 
-		// Check if the handler was aborted
-		if c.IsAborted() {
+		// Reset the response writer to the original
+		c.Writer = writer.ResponseWriter
+
+		// Only responses of handlers that ran to completion and succeeded are paid for
+		if !c.IsAborted() && writer.statusCode < http.StatusBadRequest && !settle() {
 			return
 		}
 
-		// Settle payment
-		settleResponse, err := facilitatorClient.Settle(paymentPayload, paymentRequirements)
+		// Write the original response, with the settlement header if it was settled
+		c.Writer.WriteHeader(writer.statusCode)
+		c.Writer.Write([]byte(writer.body.String()))
+	}
+}
+
+// accepts returns the requirements the request can be paid with: those computed by the function given to
+// DynamicPaymentMiddleware, or else the amount in USDC paid to address
+func (options *PaymentMiddlewareOptions) accepts(c *gin.Context, amount *big.Float, address, resource string) ([]*types.PaymentRequirements, error) {
+	defaults := &types.PaymentRequirements{
+		Resource:    resource,
+		Description: options.description(c),
+		MimeType:    options.mimeType(c),
+	}
+
+	if options.RequirementsFunc != nil {
+		computed, err := options.RequirementsFunc(c.Request)
+		if err == nil && len(computed) == 0 {
+			err = errors.New("no payment requirements for the request")
+		}
 		if err != nil {
-			fmt.Println("Settlement failed:", err)
-			// Reset the response writer
-			c.Writer = writer.ResponseWriter
-			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
-				"error":       err.Error(),
-				"accepts":     []*types.PaymentRequirements{paymentRequirements},
-				"x402Version": x402Version,
-			})
-			return
+			fmt.Println("failed to compute payment requirements:", err)
+			return nil, err
 		}
 
-		settleResponseHeader, err := settleResponse.EncodeToBase64String()
-		if err != nil {
-			fmt.Println("Settle Header Encoding failed:", err)
-			// Reset the response writer
-			c.Writer = writer.ResponseWriter
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error":       err.Error(),
-				"x402Version": x402Version,
-			})
-			return
+		accepts := make([]*types.PaymentRequirements, len(computed))
+		for i := range computed {
+			accepts[i] = withDefaults(&computed[i], defaults)
 		}
+		return accepts, nil
+	}
 
-		// Write the original response with the settlement header
-		c.Header("X-PAYMENT-RESPONSE", settleResponseHeader)
-		// Reset the response writer to the original
-		c.Writer = writer.ResponseWriter
-		c.Writer.WriteHeader(writer.statusCode)
-		c.Writer.Write([]byte(writer.body.String()))
+	network := types.NetworkBase
+	if options.Testnet {
+		network = types.NetworkBaseSepolia
+	}
+	maxAmountRequired, _ := new(big.Float).Mul(amount, big.NewFloat(1e6)).Int(nil)
+
+	paymentRequirements := &types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           network,
+		MaxAmountRequired: maxAmountRequired.String(),
+		Resource:          defaults.Resource,
+		Description:       defaults.Description,
+		MimeType:          defaults.MimeType,
+		PayTo:             address,
+		MaxTimeoutSeconds: options.MaxTimeoutSeconds,
+		Asset:             types.USDCAssets[network].Address,
+		OutputSchema:      options.OutputSchema,
+		Extra:             nil,
+	}
+
+	if err := paymentRequirements.SetUSDCInfo(options.Testnet); err != nil {
+		fmt.Println("failed to set USDC info:", err)
+		return nil, err
+	}
+
+	if options.SettlementRecipient != "" {
+		if err := setSettlementRecipient(paymentRequirements, options.SettlementRecipient); err != nil {
+			fmt.Println("failed to set settlement recipient:", err)
+			return nil, err
+		}
+	}
+
+	return []*types.PaymentRequirements{paymentRequirements}, nil
+}
+
+// withDefaults returns a copy of the alternative requirements with an empty resource, description or
+// mime type taken from the default requirements
+func withDefaults(alternative, defaults *types.PaymentRequirements) *types.PaymentRequirements {
+	requirements := *alternative
+	if requirements.Resource == "" {
+		requirements.Resource = defaults.Resource
+	}
+	if requirements.Description == "" {
+		requirements.Description = defaults.Description
 	}
+	if requirements.MimeType == "" {
+		requirements.MimeType = defaults.MimeType
+	}
+
+	return &requirements
+}
+
+// isFree reports whether the requirements charge nothing, neither an amount nor a relayer fee
+func isFree(requirements *types.PaymentRequirements) bool {
+	value, err := exactevm.RequiredValue(requirements)
+	return err == nil && value.Sign() == 0
+}
+
+// exceedsMaxChargeable returns the first of the required or authorized amounts above maxChargeable.
+// Amounts that don't parse are treated as exceeding it.
+func exceedsMaxChargeable(payload *types.PaymentPayload, requirements *types.PaymentRequirements, maxChargeable *big.Int) (string, bool) {
+	amounts := []string{requirements.MaxAmountRequired}
+	if payload.Payload != nil && payload.Payload.Authorization != nil {
+		amounts = append(amounts, payload.Payload.Authorization.Value)
+	}
+
+	for _, amount := range amounts {
+		value, ok := new(big.Int).SetString(amount, 10)
+		if !ok || value.Cmp(maxChargeable) > 0 {
+			return amount, true
+		}
+	}
+
+	return "", false
+}
+
+// retryAfterSeconds returns how many seconds from now the payload's authorization becomes valid, rounded up
+func retryAfterSeconds(payload *types.PaymentPayload, now time.Time) (int64, bool) {
+	if payload.Payload == nil || payload.Payload.Authorization == nil {
+		return 0, false
+	}
+
+	validAfter, err := strconv.ParseInt(payload.Payload.Authorization.ValidAfter, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Unix(validAfter, 0).Sub(now)
+	if wait <= 0 {
+		return 0, false
+	}
+
+	return int64((wait + time.Second - 1) / time.Second), true
+}
+
+// stringValue returns the string s points to, or "" if s is nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+// now returns the current time of the configured clock
+func (options *PaymentMiddlewareOptions) now() time.Time {
+	if options.Clock != nil {
+		return options.Clock.Now()
+	}
+
+	return time.Now()
+}
+
+// description returns the requirements description for the request
+func (options *PaymentMiddlewareOptions) description(c *gin.Context) string {
+	if options.DescriptionFunc != nil {
+		if description := options.DescriptionFunc(c); description != "" {
+			return description
+		}
+	}
+
+	return options.Description
+}
+
+// mimeType returns the requirements mime type for the request
+func (options *PaymentMiddlewareOptions) mimeType(c *gin.Context) string {
+	if options.MimeTypeFunc != nil {
+		if mimeType := options.MimeTypeFunc(c); mimeType != "" {
+			return mimeType
+		}
+	}
+
+	return options.MimeType
+}
+
+// isPayerAllowed reports whether the payer passes the configured allowlist and blocklist
+func (options *PaymentMiddlewareOptions) isPayerAllowed(payer string) bool {
+	normalized := normalizeAddress(payer)
+	if options.PayerAllowlist != nil {
+		if _, ok := options.PayerAllowlist[normalized]; !ok {
+			return false
+		}
+	}
+	if _, ok := options.PayerBlocklist[normalized]; ok {
+		return false
+	}
+
+	return true
+}
+
+// newAddressSet builds a set of normalized addresses
+func newAddressSet(addresses []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		set[normalizeAddress(address)] = struct{}{}
+	}
+
+	return set
+}
+
+// setSettlementRecipient records the settlement recipient in the requirements' extra
+func setSettlementRecipient(requirements *types.PaymentRequirements, recipient string) error {
+	normalized, err := exactevm.NormalizeAddress(recipient)
+	if err != nil {
+		return fmt.Errorf("invalid settlement recipient: %w", err)
+	}
+
+	var extra types.ExactEvmExtra
+	if _, err := requirements.DecodeExtra(&extra); err != nil {
+		return err
+	}
+	extra.SettlementRecipient = normalized
+
+	return requirements.SetExtra(extra)
+}
+
+// normalizeAddress checksums hex addresses, falling back to lower case for anything else
+func normalizeAddress(address string) string {
+	if normalized, err := exactevm.NormalizeAddress(address); err == nil {
+		return normalized
+	}
+
+	return strings.ToLower(strings.TrimSpace(address))
 }
 
 // responseWriter is a custom response writer that captures the response
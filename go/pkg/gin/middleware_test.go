@@ -1,17 +1,30 @@
 package gin_test
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
 	x402gin "github.com/coinbase/x402/go/pkg/gin"
+	"github.com/coinbase/x402/go/pkg/middlewaretest/testclock"
 	"github.com/coinbase/x402/go/pkg/types"
 )
 
@@ -29,6 +42,8 @@ type TestServerConfig struct {
 	Transaction       string
 	Network           string
 	Payer             *string
+	// PreparedTransaction makes the facilitator prepare the settlement without submitting it
+	PreparedTransaction *types.PreparedTransaction
 
 	// Server behavior
 	VerifyStatusCode int
@@ -74,6 +89,30 @@ func NewTestConfig() TestServerConfig {
 func setupTest(t *testing.T, amount *big.Float, address string, config TestServerConfig, opts ...x402gin.Options) (*gin.Engine, *httptest.ResponseRecorder, *http.Request) {
 	t.Helper()
 
+	facilitatorServer := newTestFacilitator(t, config)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	facilitatorConfig := &types.FacilitatorConfig{
+		URL: facilitatorServer.URL,
+	}
+	allOpts := append([]x402gin.Options{x402gin.WithFacilitatorConfig(facilitatorConfig)}, opts...)
+
+	router.GET("/protected", x402gin.PaymentMiddleware(amount, address, allOpts...), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/protected", nil)
+
+	return router, w, req
+}
+
+// newTestFacilitator creates a test facilitator server responding according to config.
+func newTestFacilitator(t *testing.T, config TestServerConfig) *httptest.Server {
+	t.Helper()
+
 	// Create a test facilitator server
 	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -92,27 +131,14 @@ func setupTest(t *testing.T, amount *big.Float, address string, config TestServe
 				Transaction: config.Transaction,
 				Network:     config.Network,
 				Payer:       config.Payer,
+
+				PreparedTransaction: config.PreparedTransaction,
 			})
 		}
 	}))
 	t.Cleanup(func() { facilitatorServer.Close() })
 
-	gin.SetMode(gin.TestMode)
-	router := gin.New()
-
-	facilitatorConfig := &types.FacilitatorConfig{
-		URL: facilitatorServer.URL,
-	}
-	allOpts := append([]x402gin.Options{x402gin.WithFacilitatorConfig(facilitatorConfig)}, opts...)
-
-	router.GET("/protected", x402gin.PaymentMiddleware(amount, address, allOpts...), func(c *gin.Context) {
-		c.String(http.StatusOK, "success")
-	})
-
-	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/protected", nil)
-
-	return router, w, req
+	return facilitatorServer
 }
 
 func TestPaymentMiddleware_NoPaymentHeader(t *testing.T) {
@@ -357,3 +383,1337 @@ func TestPaymentMiddleware_NetworkSelection(t *testing.T) {
 		})
 	}
 }
+
+func TestPaymentMiddleware_VerifyOnly(t *testing.T) {
+	config := NewTestConfig()
+
+	var deferredPayload *types.PaymentPayload
+	var deferredRequirements *types.PaymentRequirements
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config,
+		x402gin.WithVerifyOnly(func(payload *types.PaymentPayload, requirements *types.PaymentRequirements) {
+			deferredPayload = payload
+			deferredRequirements = requirements
+		}),
+	)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+
+	paymentPayloadBase64 := base64.StdEncoding.EncodeToString(paymentPayloadJson)
+	req.Header.Set("X-PAYMENT", paymentPayloadBase64)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "success")
+	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	if assert.NotNil(t, deferredPayload) && assert.NotNil(t, deferredRequirements) {
+		assert.Equal(t, config.PaymentPayload.Payload.Signature, deferredPayload.Payload.Signature)
+		assert.Equal(t, "0xTestAddress", deferredRequirements.PayTo)
+	}
+}
+
+func TestPaymentMiddleware_PayerLists(t *testing.T) {
+	const payer = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+
+	testCases := []struct {
+		name     string
+		opts     []x402gin.Options
+		expected int
+	}{
+		{
+			name:     "allowlisted payer",
+			opts:     []x402gin.Options{x402gin.WithPayerAllowlist([]string{"0x857B06519E91E3A54538791BDBB0E22373E36B66"})},
+			expected: http.StatusOK,
+		},
+		{
+			name:     "payer not on allowlist",
+			opts:     []x402gin.Options{x402gin.WithPayerAllowlist([]string{"0x209693Bc6afc0C5328bA36FaF03C514EF312287C"})},
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "blocklisted payer",
+			opts:     []x402gin.Options{x402gin.WithPayerBlocklist([]string{"0x857b06519e91e3a54538791bdbb0e22373e36b66"})},
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "payer not on blocklist",
+			opts:     []x402gin.Options{x402gin.WithPayerBlocklist([]string{"0x209693Bc6afc0C5328bA36FaF03C514EF312287C"})},
+			expected: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := NewTestConfig()
+			verifiedPayer := payer
+			config.Payer = &verifiedPayer
+
+			router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, tc.opts...)
+
+			paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+			assert.NoError(t, err, "marshaling payment payload should not fail")
+
+			req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expected, w.Code)
+			if tc.expected == http.StatusForbidden {
+				assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+			}
+		})
+	}
+}
+
+func TestPaymentMiddleware_PaymentFromContext(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	var (
+		fromGinContext     *types.PaymentPayload
+		fromRequestContext *types.PaymentPayload
+	)
+	router.GET("/protected",
+		x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		),
+		func(c *gin.Context) {
+			fromGinContext, _ = x402gin.PaymentFromContext(c)
+			fromRequestContext, _ = x402gin.PaymentFromContext(c.Request.Context())
+			c.String(http.StatusOK, "success")
+		},
+	)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.NotNil(t, fromGinContext) && assert.NotNil(t, fromRequestContext) {
+		assert.Equal(t, config.PaymentPayload.Payload.Authorization.Value, fromGinContext.Payload.Authorization.Value)
+		assert.Equal(t, config.PaymentPayload.Payload.Authorization.Nonce, fromRequestContext.Payload.Authorization.Nonce)
+	}
+
+	_, ok := x402gin.PaymentFromContext(req.Context())
+	assert.False(t, ok, "unverified request context should not carry a payment")
+}
+
+func TestPaymentMiddleware_Version(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	var version int
+	router.GET("/protected",
+		x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		),
+		func(c *gin.Context) {
+			version, _ = x402gin.PaymentVersionFromContext(c)
+			c.String(http.StatusOK, "success")
+		},
+	)
+	pay := func(payload *types.PaymentPayload) *httptest.ResponseRecorder {
+		paymentPayloadJson, err := json.Marshal(payload)
+		assert.NoError(t, err, "marshaling payment payload should not fail")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := pay(config.PaymentPayload)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, types.X402Version, version)
+	assert.Empty(t, w.Header().Get(x402gin.DeprecatedVersionHeader), "the current version should not be deprecated")
+
+	newer := *config.PaymentPayload
+	newer.X402Version = types.X402Version + 1
+	w = pay(&newer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "is newer than the supported")
+}
+
+func TestPaymentMiddleware_WrongNetwork(t *testing.T) {
+	config := NewTestConfig()
+	config.PaymentPayload.Network = "base"
+	// The facilitator would accept the payment, so a 402 proves the local network check rejected it
+	config.VerifySuccess = true
+
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+	var response map[string]any
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response["error"], "wrong_network")
+	assert.Contains(t, response, "accepts")
+}
+
+// collectEvents returns an observer recording events, and a function waiting for n of them
+func collectEvents(t *testing.T) (x402gin.Observer, func(n int) []x402gin.PaymentEvent) {
+	t.Helper()
+
+	events := make(chan x402gin.PaymentEvent, 16)
+	observer := x402gin.ObserverFunc(func(event x402gin.PaymentEvent) {
+		events <- event
+	})
+
+	return observer, func(n int) []x402gin.PaymentEvent {
+		var collected []x402gin.PaymentEvent
+		for range n {
+			select {
+			case event := <-events:
+				collected = append(collected, event)
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for event %d of %d", len(collected)+1, n)
+			}
+		}
+		return collected
+	}
+}
+
+func TestPaymentMiddleware_Observer(t *testing.T) {
+	config := NewTestConfig()
+	observer, wait := collectEvents(t)
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithObserver(observer))
+
+	router.ServeHTTP(w, req)
+	challenged := wait(1)[0]
+	assert.Equal(t, x402gin.EventChallenged, challenged.Type)
+	assert.Equal(t, "1000000", challenged.Amount)
+	assert.Equal(t, "base-sepolia", challenged.Network)
+	assert.Empty(t, challenged.Payer)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	events := wait(2)
+	assert.Equal(t, x402gin.EventVerified, events[0].Type)
+	assert.Equal(t, x402gin.EventSettled, events[1].Type)
+	assert.Equal(t, "0xvalidPayer", events[1].Payer)
+	assert.Equal(t, "/protected", events[1].Resource)
+	assert.GreaterOrEqual(t, events[1].Elapsed, events[0].Elapsed)
+}
+
+func TestPaymentMiddleware_ObserverFailures(t *testing.T) {
+	config := NewTestConfig()
+	config.VerifySuccess = false
+	observer, wait := collectEvents(t)
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithObserver(observer))
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	event := wait(1)[0]
+	assert.Equal(t, x402gin.EventVerifyFailed, event.Type)
+	assert.Equal(t, "Invalid payment", event.Reason)
+	assert.Equal(t, "0xvalidFrom", event.Payer)
+
+	config = NewTestConfig()
+	config.SettleSuccess = false
+	router, w, req = setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithObserver(observer))
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	events := wait(2)
+	assert.Equal(t, x402gin.EventVerified, events[0].Type)
+	assert.Equal(t, x402gin.EventSettleFailed, events[1].Type)
+	assert.Equal(t, "Settlement failed", events[1].Reason)
+}
+
+func TestPaymentMiddleware_SubmissionRequired(t *testing.T) {
+	config := NewTestConfig()
+	config.Transaction = ""
+	config.PreparedTransaction = &types.PreparedTransaction{Network: "base-sepolia", Transaction: "0x02f8"}
+	observer, wait := collectEvents(t)
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithObserver(observer))
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	events := wait(2)
+	assert.Equal(t, x402gin.EventVerified, events[0].Type)
+	assert.Equal(t, x402gin.EventSubmissionRequired, events[1].Type)
+
+	// The payer gets the prepared transaction to submit
+	decoded, err := base64.StdEncoding.DecodeString(w.Header().Get("X-PAYMENT-RESPONSE"))
+	assert.NoError(t, err)
+	var settleResponse types.SettleResponse
+	assert.NoError(t, json.Unmarshal(decoded, &settleResponse))
+	assert.True(t, settleResponse.NeedsSubmission())
+	assert.Equal(t, "0x02f8", settleResponse.PreparedTransaction.Transaction)
+}
+
+func TestPaymentMiddleware_ObserverDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	var delivered, dropped atomic.Int64
+	observer := x402gin.ObserverFunc(func(event x402gin.PaymentEvent) {
+		<-release
+		delivered.Add(1)
+		dropped.Add(event.Dropped)
+	})
+	router, _, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", NewTestConfig(), x402gin.WithObserver(observer))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// More requests than the observer queue holds
+		for range 300 {
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a stalled observer not to block requests")
+	}
+
+	// Once the observer catches up, every event is either delivered or counted as dropped
+	close(release)
+	assert.Eventually(t, func() bool {
+		return delivered.Load()+dropped.Load() == 300
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Positive(t, dropped.Load())
+}
+
+func TestPaymentMiddleware_MaxChargeable(t *testing.T) {
+	config := NewTestConfig()
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	header := base64.StdEncoding.EncodeToString(paymentPayloadJson)
+
+	// The authorization carries 1000000, matching a $1 price
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithMaxChargeable("1000000"))
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A misconfigured $1000 price is refused
+	router, w, req = setupTest(t, big.NewFloat(1000.0), "0xTestAddress", config, x402gin.WithMaxChargeable("1000000"))
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	// So is an authorization for more than the cap
+	router, w, req = setupTest(t, big.NewFloat(0.5), "0xTestAddress", config, x402gin.WithMaxChargeable("500000"))
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	assert.Panics(t, func() { x402gin.WithMaxChargeable("$1") })
+}
+
+func TestPaymentMiddleware_ExactAmount(t *testing.T) {
+	config := NewTestConfig()
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	header := base64.StdEncoding.EncodeToString(paymentPayloadJson)
+
+	// The authorization carries 1000000, overpaying a $0.50 price
+	router, w, req := setupTest(t, big.NewFloat(0.5), "0xTestAddress", config)
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	router, w, req = setupTest(t, big.NewFloat(0.5), "0xTestAddress", config, x402gin.WithExactAmount())
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Contains(t, w.Body.String(), exactevm.ReasonValueMismatch)
+	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	router, w, req = setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithExactAmount())
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPaymentMiddleware_RequirementsBinding(t *testing.T) {
+	const payTo = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signer := exactevm.NewPrivateKeySigner(key)
+
+	// Sign a payment for the requirements challenged by a $1 route paying payTo
+	router, w, req := setupTest(t, big.NewFloat(1.0), payTo, NewTestConfig())
+	router.ServeHTTP(w, req)
+	var challenge types.PaymentRequiredResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	assert.Len(t, challenge.Accepts, 1)
+	payload, err := exactevm.CreatePayment(signer, &challenge.Accepts[0])
+	assert.NoError(t, err)
+	header, err := payload.EncodeToBase64String()
+	assert.NoError(t, err)
+
+	// The test facilitator reports every payment valid, so only the local check can refuse them
+	testCases := []struct {
+		name    string
+		amount  *big.Float
+		address string
+		status  int
+	}{
+		{"signed requirements", big.NewFloat(1.0), payTo, http.StatusOK},
+		{"other recipient", big.NewFloat(1.0), "0x857b06519E91e3A54538791bDbb0E22373e36b66", http.StatusPaymentRequired},
+		{"higher price", big.NewFloat(2.0), payTo, http.StatusPaymentRequired},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router, w, req := setupTest(t, tc.amount, tc.address, NewTestConfig(), x402gin.WithRequirementsBinding())
+			req.Header.Set("X-PAYMENT", header)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+			if tc.status == http.StatusPaymentRequired {
+				assert.Contains(t, w.Body.String(), exactevm.ReasonRequirementsMismatch)
+				assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+			}
+		})
+	}
+
+	// Without the option, the facilitator's verdict is trusted
+	router, w, req = setupTest(t, big.NewFloat(2.0), payTo, NewTestConfig())
+	req.Header.Set("X-PAYMENT", header)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPaymentMiddleware_UsageStore(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+	header, err := config.PaymentPayload.EncodeToBase64String()
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	store := x402gin.NewMemoryUsageStore()
+	for _, path := range []string{"/reports", "/exports"} {
+		router.GET(path, x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+			x402gin.WithUsageStore(store),
+		), func(c *gin.Context) {
+			c.String(http.StatusOK, "success")
+		})
+	}
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{"/reports", http.StatusOK},
+		// A replay for the same resource is refused, whatever the facilitator says
+		{"/reports", http.StatusPaymentRequired},
+		// The authorization is honored once per resource
+		{"/exports", http.StatusOK},
+		{"/exports", http.StatusPaymentRequired},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		req.Header.Set("X-PAYMENT", header)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		if tc.status == http.StatusPaymentRequired {
+			assert.Contains(t, w.Body.String(), x402gin.ReasonAlreadyUsed)
+		}
+	}
+}
+
+func TestMemoryUsageStore(t *testing.T) {
+	store := x402gin.NewMemoryUsageStore()
+	key := x402gin.UsageKey{Resource: "/reports", Payer: "0xABC", Nonce: "0xDEF"}
+
+	assert.True(t, store.Use(key, time.Now().Add(time.Minute)))
+	// Payer and nonce are compared case-insensitively
+	assert.False(t, store.Use(x402gin.UsageKey{Resource: "/reports", Payer: "0xabc", Nonce: "0xdef"}, time.Now().Add(time.Minute)))
+	assert.True(t, store.Use(x402gin.UsageKey{Resource: "/REPORTS", Payer: "0xABC", Nonce: "0xDEF"}, time.Now().Add(time.Minute)))
+
+	expired := x402gin.UsageKey{Resource: "/exports", Payer: "0xABC", Nonce: "0xDEF"}
+	assert.True(t, store.Use(expired, time.Now().Add(-time.Second)))
+	assert.True(t, store.Use(expired, time.Now().Add(time.Minute)), "an expired usage can be recorded again")
+
+	// Concurrent replays are honored once
+	var wg sync.WaitGroup
+	var honored atomic.Int32
+	concurrent := x402gin.UsageKey{Resource: "/concurrent", Payer: "0xABC", Nonce: "0xDEF"}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if store.Use(concurrent, time.Now().Add(time.Minute)) {
+				honored.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), honored.Load())
+
+	// Once full, new usages are refused rather than old ones forgotten
+	full := x402gin.NewMemoryUsageStore()
+	for i := 0; i < x402gin.MaxMemoryUsages; i++ {
+		full.Use(x402gin.UsageKey{Resource: "/reports", Payer: "0xABC", Nonce: strconv.Itoa(i)}, time.Now().Add(time.Minute))
+	}
+	assert.False(t, full.Use(x402gin.UsageKey{Resource: "/reports", Payer: "0xABC", Nonce: "new"}, time.Now().Add(time.Minute)))
+	assert.False(t, full.Use(x402gin.UsageKey{Resource: "/reports", Payer: "0xABC", Nonce: "0"}, time.Now().Add(time.Minute)))
+}
+
+func TestPaymentMiddleware_PaymentResult(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+	header, err := config.PaymentPayload.EncodeToBase64String()
+	assert.NoError(t, err)
+
+	for _, mode := range []x402gin.SettleMode{x402gin.SettleAfterSuccess, x402gin.SettleBeforeHandler} {
+		t.Run(mode.String(), func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			var result *types.PaymentResult
+			var settledInHandler bool
+			router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+				x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+				x402gin.WithSettleMode(mode),
+			), func(c *gin.Context) {
+				var ok bool
+				result, ok = x402gin.PaymentResultFromContext(c)
+				assert.True(t, ok)
+				settledInHandler = result.Settled()
+				c.String(http.StatusOK, "success")
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			req.Header.Set("X-PAYMENT", header)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, mode == x402gin.SettleBeforeHandler, settledInHandler)
+			if assert.NotNil(t, result) {
+				assert.True(t, result.Verify.IsValid)
+				assert.True(t, result.Settled())
+				assert.Equal(t, "0xtesthash", result.Settle.Transaction)
+				assert.Equal(t, "0xvalidPayer", result.Payer)
+				assert.Equal(t, "1000000", result.Requirements.MaxAmountRequired)
+				assert.Positive(t, result.VerifyDuration)
+				assert.Positive(t, result.SettleDuration)
+			}
+		})
+	}
+
+	_, ok := x402gin.PaymentResultFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestPaymentMiddleware_RequestMetadata(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/reports/:id", x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithDescription("A report"),
+		x402gin.WithMimeType("application/json"),
+		x402gin.WithDescriptionFunc(func(c *gin.Context) string {
+			if c.Param("id") == "default" {
+				return ""
+			}
+			return "Report " + c.Param("id")
+		}),
+		x402gin.WithMimeTypeFunc(func(c *gin.Context) string {
+			if c.Query("format") == "pdf" {
+				return "application/pdf"
+			}
+			return ""
+		}),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	testCases := []struct {
+		path        string
+		description string
+		mimeType    string
+	}{
+		{"/reports/42?format=pdf", "Report 42", "application/pdf"},
+		{"/reports/default", "A report", "application/json"},
+		// Cached challenges are kept apart by their metadata
+		{"/reports/42?format=pdf", "Report 42", "application/pdf"},
+		{"/reports/43", "Report 43", "application/json"},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+		var response struct {
+			Accepts []types.PaymentRequirements `json:"accepts"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response.Accepts, 1) {
+			assert.Equal(t, tc.description, response.Accepts[0].Description)
+			assert.Equal(t, tc.mimeType, response.Accepts[0].MimeType)
+		}
+	}
+}
+
+func TestPaymentMiddleware_RetryAfterNotYetValid(t *testing.T) {
+	config := NewTestConfig()
+	config.VerifySuccess = false
+	notYetValid := exactevm.ReasonNotYetValid
+	config.InvalidReason = &notYetValid
+	clock := testclock.New(time.Unix(1745323800, 0))
+	validAfter := clock.Now().Add(90 * time.Second).Unix()
+	config.PaymentPayload.Payload.Authorization.ValidAfter = strconv.FormatInt(validAfter, 10)
+
+	router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithClock(clock))
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Equal(t, "90", w.Header().Get("Retry-After"))
+
+	// Other verification failures carry no Retry-After
+	config.InvalidReason = nil
+	router, w, req = setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
+
+func TestPaymentMiddleware_ResourceRedaction(t *testing.T) {
+	const resource = "https://api.example.com/patients/42?ssn=123-45-6789"
+	testCases := []struct {
+		name     string
+		redact   func(string) string
+		expected string
+	}{
+		{"strip query", x402gin.StripResourceQuery, "https://api.example.com/patients/42"},
+		{"hash", x402gin.HashResource, x402gin.HashResource(resource)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := NewTestConfig()
+			var facilitatorResources []string
+			facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					PaymentRequirements types.PaymentRequirements `json:"paymentRequirements"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				facilitatorResources = append(facilitatorResources, body.PaymentRequirements.Resource)
+
+				if r.URL.Path == "/verify" {
+					json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: config.Payer})
+				} else {
+					json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: config.Transaction, Network: config.Network})
+				}
+			}))
+			t.Cleanup(facilitatorServer.Close)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+				x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+				x402gin.WithResource(resource),
+				x402gin.WithResourceRedaction(tc.redact),
+			), func(c *gin.Context) {
+				c.String(http.StatusOK, "success")
+			})
+
+			// Clients are still challenged with the full resource
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			router.ServeHTTP(w, req)
+			var response struct {
+				Accepts []types.PaymentRequirements `json:"accepts"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			if assert.Len(t, response.Accepts, 1) {
+				assert.Equal(t, resource, response.Accepts[0].Resource)
+			}
+
+			paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+			assert.NoError(t, err, "marshaling payment payload should not fail")
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", "/protected", nil)
+			req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, []string{tc.expected, tc.expected}, facilitatorResources)
+		})
+	}
+}
+
+func TestPaymentMiddleware_ReusesFacilitatorConnections(t *testing.T) {
+	config := NewTestConfig()
+	var connections atomic.Int32
+	facilitatorServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/verify" {
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: config.Payer})
+		} else {
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: config.Transaction, Network: config.Network})
+		}
+	}))
+	facilitatorServer.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	facilitatorServer.Start()
+	t.Cleanup(facilitatorServer.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	assert.Equal(t, int32(1), connections.Load(), "paid requests should reuse the facilitator connection")
+}
+
+func TestPaymentMiddleware_VerifyAny(t *testing.T) {
+	config := NewTestConfig()
+	var settledPayTo atomic.Value
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			PaymentRequirements types.PaymentRequirements `json:"paymentRequirements"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if r.URL.Path == "/verify" {
+			// Only the alternative accept matches the payment
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: body.PaymentRequirements.PayTo == "0xAlternative", InvalidReason: config.InvalidReason})
+			return
+		}
+		settledPayTo.Store(body.PaymentRequirements.PayTo)
+		json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: config.Transaction, Network: config.Network})
+	}))
+	t.Cleanup(facilitatorServer.Close)
+
+	alternative := &types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           types.NetworkBaseSepolia,
+		MaxAmountRequired: "1000000",
+		PayTo:             "0xAlternative",
+		MaxTimeoutSeconds: 60,
+		Asset:             types.USDCAssets[types.NetworkBaseSepolia].Address,
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+		x402gin.WithVerifyAny(2, alternative),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	// Both accepts are advertised
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	router.ServeHTTP(w, req)
+	var challenge struct {
+		Accepts []types.PaymentRequirements `json:"accepts"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	assert.Len(t, challenge.Accepts, 2)
+	assert.Equal(t, "0xAlternative", challenge.Accepts[1].PayTo)
+	assert.Equal(t, "/protected", challenge.Accepts[1].Resource)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	w = httptest.NewRecorder()
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0xAlternative", settledPayTo.Load(), "the payment should settle against the accept it is valid for")
+
+	// A payment for a network no accept has is rejected without verifying
+	otherNetwork := *config.PaymentPayload
+	otherNetwork.Network = types.NetworkBase
+	paymentPayloadJson, err = json.Marshal(&otherNetwork)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	w = httptest.NewRecorder()
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+}
+
+func TestPaymentMiddleware_NameResolver(t *testing.T) {
+	var resolutions atomic.Int32
+	resolver := x402gin.NameResolverFunc(func(ctx context.Context, name string) (string, error) {
+		resolutions.Add(1)
+		if name != "myapi.eth" {
+			return "", errors.New("no such name")
+		}
+		return "0x209693bc6afc0c5328ba36faf03c514ef312287c", nil
+	})
+
+	router, _, req := setupTest(t, big.NewFloat(1.0), "myapi.eth", NewTestConfig(), x402gin.WithNameResolver(resolver))
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var challenge struct {
+			Accepts []types.PaymentRequirements `json:"accepts"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+		assert.Equal(t, "0x209693Bc6afc0C5328bA36FaF03C514EF312287C", challenge.Accepts[0].PayTo)
+	}
+	assert.Equal(t, int32(1), resolutions.Load(), "the name should be resolved once, at setup")
+
+	assert.PanicsWithValue(t, `invalid payTo address: failed to resolve "unknown.eth": no such name`, func() {
+		x402gin.PaymentMiddleware(big.NewFloat(1.0), "unknown.eth", x402gin.WithNameResolver(resolver))
+	})
+}
+
+func TestPaymentMiddleware_FreeTier(t *testing.T) {
+	config := NewTestConfig()
+	config.PaymentPayload.Payload.Authorization.Value = "0"
+	var settlements atomic.Int32
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/settle" {
+			settlements.Add(1)
+		}
+		json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true, Payer: config.Payer})
+	}))
+	t.Cleanup(facilitatorServer.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var payer string
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(0), "0xTestAddress",
+		x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+	), func(c *gin.Context) {
+		payer, _ = x402gin.PayerFromContext(c)
+		c.String(http.StatusOK, "success")
+	})
+
+	// A signature is still required
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Contains(t, w.Body.String(), `"maxAmountRequired":"0"`)
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	w = httptest.NewRecorder()
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "success", w.Body.String())
+	assert.Equal(t, *config.Payer, payer)
+	assert.Equal(t, int32(0), settlements.Load(), "a zero amount should not be settled")
+	assert.Empty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
+
+func TestPaymentMiddleware_AcceptsOrder(t *testing.T) {
+	alternatives := []*types.PaymentRequirements{
+		{Scheme: "exact", Network: types.NetworkAvalancheFuji, MaxAmountRequired: "1000000", PayTo: "0xFuji", Asset: types.USDCAssets[types.NetworkAvalancheFuji].Address},
+		{Scheme: "exact", Network: types.NetworkBaseSepolia, MaxAmountRequired: "1000000", PayTo: "0xAlternative", Asset: types.USDCAssets[types.NetworkBaseSepolia].Address},
+	}
+
+	payTo := func(opts ...x402gin.Options) []string {
+		router, w, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", NewTestConfig(), append([]x402gin.Options{x402gin.WithVerifyAny(2, alternatives...)}, opts...)...)
+		router.ServeHTTP(w, req)
+
+		var challenge struct {
+			Accepts []types.PaymentRequirements `json:"accepts"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+		addresses := make([]string, len(challenge.Accepts))
+		for i, accept := range challenge.Accepts {
+			addresses[i] = accept.PayTo
+		}
+		return addresses
+	}
+
+	assert.Equal(t, []string{"0xTestAddress", "0xFuji", "0xAlternative"}, payTo(), "accepts should keep their configured order by default")
+
+	// Ties keep their configured order, so the ordering is stable across requests
+	for range 5 {
+		assert.Equal(t, []string{"0xFuji", "0xTestAddress", "0xAlternative"}, payTo(x402gin.WithAcceptsOrder(x402gin.PreferNetworks(types.NetworkAvalancheFuji))))
+	}
+}
+
+func TestPaymentMiddleware_ChallengeCache(t *testing.T) {
+	config := NewTestConfig()
+	router, _, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config)
+
+	challenge := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		return w
+	}
+
+	first := challenge()
+	assert.Equal(t, first.Body.String(), challenge().Body.String())
+
+	// Refused payments still report why
+	payload := *config.PaymentPayload
+	payload.Network = "base"
+	paymentPayloadJson, err := json.Marshal(&payload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	refused := challenge()
+	assert.NotEqual(t, first.Body.String(), refused.Body.String())
+	assert.Contains(t, refused.Body.String(), "does not match required network")
+}
+
+func TestPaymentMiddleware_StatusCodes(t *testing.T) {
+	config := NewTestConfig()
+	config.VerifySuccess = false
+	router, _, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithStatusCodes(0, http.StatusForbidden))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code, "a zero status should keep 402")
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, *config.InvalidReason, response["error"])
+	assert.Contains(t, response, "accepts")
+
+	router, w, req = setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithStatusCodes(http.StatusUnauthorized, 0))
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the paywall should use the no-payment status")
+
+	assert.Panics(t, func() { x402gin.WithStatusCodes(http.StatusOK, 0) })
+}
+
+func TestPaymentMiddlewareSingle(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+	client := facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: facilitatorServer.URL})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/report", x402gin.PaymentMiddlewareSingle(types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           types.NetworkBaseSepolia,
+		MaxAmountRequired: "1000000",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Asset:             types.USDCAssets[types.NetworkBaseSepolia].Address,
+	}, client, x402gin.WithDescription("Report")), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/report", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	var response types.PaymentRequiredResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Len(t, response.Accepts, 1) {
+		assert.Equal(t, "1000000", response.Accepts[0].MaxAmountRequired)
+		assert.Equal(t, "Report", response.Accepts[0].Description)
+	}
+
+	// The payment is verified and settled through the given client
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	w = httptest.NewRecorder()
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+}
+
+func TestDynamicPaymentMiddleware(t *testing.T) {
+	config := NewTestConfig()
+	facilitatorServer := newTestFacilitator(t, config)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/search", x402gin.DynamicPaymentMiddleware(func(r *http.Request) ([]types.PaymentRequirements, error) {
+		size, err := strconv.Atoi(r.URL.Query().Get("size"))
+		if err != nil {
+			return nil, errors.New("invalid size")
+		}
+		return []types.PaymentRequirements{{
+			Scheme:            "exact",
+			Network:           types.NetworkBaseSepolia,
+			MaxAmountRequired: strconv.Itoa(size * 1000),
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 60,
+			Asset:             types.USDCAssets[types.NetworkBaseSepolia].Address,
+		}}, nil
+	}, x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}), x402gin.WithDescription("Search")), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+
+	challenge := func(path string) types.PaymentRequirements {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+		var response struct {
+			Accepts []types.PaymentRequirements `json:"accepts"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if !assert.Len(t, response.Accepts, 1) {
+			t.FailNow()
+		}
+		return response.Accepts[0]
+	}
+
+	small := challenge("/search?size=1")
+	assert.Equal(t, "1000", small.MaxAmountRequired)
+	assert.Equal(t, "/search", small.Resource)
+	assert.Equal(t, "Search", small.Description)
+	assert.Equal(t, "5000", challenge("/search?size=5").MaxAmountRequired, "each request should be priced")
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?size=5", nil)
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/search?size=large", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "a failed requirements function should not challenge")
+	assert.Contains(t, w.Body.String(), "invalid size")
+}
+
+func TestPaymentMiddleware_SettleMode(t *testing.T) {
+	var settleCalls atomic.Int32
+	var settleFails atomic.Bool
+	facilitatorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(types.VerifyResponse{IsValid: true})
+		case "/settle":
+			settleCalls.Add(1)
+			if settleFails.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(types.SettleResponse{Success: true, Transaction: "0xtesthash", Network: "base-sepolia"})
+		}
+	}))
+	t.Cleanup(facilitatorServer.Close)
+
+	paymentPayloadJson, err := json.Marshal(NewTestConfig().PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+
+	// The handler records how many settlements had happened when it ran and after it wrote the first byte
+	var settledBefore, settledAfterWrite int32
+	run := func(mode x402gin.SettleMode, status int) *httptest.ResponseRecorder {
+		settleCalls.Store(0)
+		settledBefore, settledAfterWrite = -1, -1
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(1.0), "0xTestAddress",
+			x402gin.WithFacilitatorConfig(&types.FacilitatorConfig{URL: facilitatorServer.URL}),
+			x402gin.WithSettleMode(mode),
+		), func(c *gin.Context) {
+			settledBefore = settleCalls.Load()
+			c.Status(status)
+			c.Writer.WriteString("first")
+			settledAfterWrite = settleCalls.Load()
+			c.Writer.WriteString(" second")
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := run(x402gin.SettleAfterSuccess, http.StatusOK)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "first second", w.Body.String())
+	assert.Equal(t, int32(0), settledAfterWrite, "the default should settle after the handler")
+	assert.Equal(t, int32(1), settleCalls.Load())
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	w = run(x402gin.SettleAfterSuccess, http.StatusInternalServerError)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "first second", w.Body.String())
+	assert.Equal(t, int32(0), settleCalls.Load(), "a failed response should not be paid for")
+
+	w = run(x402gin.SettleBeforeHandler, http.StatusOK)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(1), settledBefore)
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	w = run(x402gin.SettleOnFirstByte, http.StatusOK)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "first second", w.Body.String())
+	assert.Equal(t, int32(0), settledBefore)
+	assert.Equal(t, int32(1), settledAfterWrite)
+	assert.NotEmpty(t, w.Header().Get("X-PAYMENT-RESPONSE"))
+
+	settleFails.Store(true)
+	w = run(x402gin.SettleBeforeHandler, http.StatusOK)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Equal(t, int32(-1), settledBefore, "the handler should not run without a settled payment")
+
+	w = run(x402gin.SettleOnFirstByte, http.StatusOK)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.NotContains(t, w.Body.String(), "first", "the handler's writes should be discarded")
+
+	assert.Panics(t, func() { x402gin.WithSettleMode(x402gin.SettleMode(42)) })
+}
+
+func TestPaymentMiddleware_ChallengeNonce(t *testing.T) {
+	config := NewTestConfig()
+	store := x402gin.NewMemoryNonceStore()
+	router, _, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithChallengeNonce(store))
+	failing := config
+	failing.VerifySuccess = false
+	failingRouter, _, _ := setupTest(t, big.NewFloat(1.0), "0xTestAddress", failing, x402gin.WithChallengeNonce(store))
+
+	challengeNonce := func() string {
+		w := httptest.NewRecorder()
+		req.Header.Del("X-PAYMENT")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+		var challenge struct {
+			Accepts []types.PaymentRequirements `json:"accepts"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+		var extra types.ExactEvmExtra
+		_, err := challenge.Accepts[0].DecodeExtra(&extra)
+		assert.NoError(t, err)
+		return extra.ChallengeNonce
+	}
+	pay := func(router *gin.Engine, nonce string) int {
+		payload := *config.PaymentPayload
+		authorization := *payload.Payload.Authorization
+		authorization.Nonce = nonce
+		payload.Payload = &types.ExactEvmPayload{Signature: payload.Payload.Signature, Authorization: &authorization}
+		paymentPayloadJson, err := json.Marshal(&payload)
+		assert.NoError(t, err, "marshaling payment payload should not fail")
+
+		w := httptest.NewRecorder()
+		req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	first, second := challengeNonce(), challengeNonce()
+	assert.NotEmpty(t, first)
+	assert.NotEqual(t, first, second, "each challenge should carry a fresh nonce")
+
+	assert.Equal(t, http.StatusPaymentRequired, pay(failingRouter, first))
+	assert.Equal(t, http.StatusOK, pay(router, first), "a failed verification should not consume the challenge nonce")
+	assert.Equal(t, http.StatusPaymentRequired, pay(router, first), "a challenge nonce should only be paid with once")
+	assert.Equal(t, http.StatusPaymentRequired, pay(router, config.PaymentPayload.Payload.Authorization.Nonce), "a payment not answering a challenge should be refused")
+	assert.Equal(t, http.StatusOK, pay(router, second))
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := x402gin.NewMemoryNonceStore()
+
+	store.Add("paid", time.Now().Add(time.Minute))
+	assert.True(t, store.Consume("paid"))
+	assert.False(t, store.Consume("paid"), "a nonce should only be consumed once")
+	assert.False(t, store.Consume("unknown"))
+
+	store.Add("expired", time.Now().Add(-time.Second))
+	assert.False(t, store.Consume("expired"))
+
+	// Once full, the nonce closest to expiring makes room
+	store.Add("first", time.Now().Add(time.Second))
+	for i := 1; i < x402gin.MaxMemoryNonces; i++ {
+		store.Add(strconv.Itoa(i), time.Now().Add(time.Minute))
+	}
+	store.Add("last", time.Now().Add(time.Minute))
+	assert.False(t, store.Consume("first"))
+	assert.True(t, store.Consume("1"))
+	assert.True(t, store.Consume("last"))
+}
+
+func TestPaymentMiddleware_SignedChallenge(t *testing.T) {
+	config := NewTestConfig()
+	clock := testclock.New(time.Unix(1745323800, 0))
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	router, _, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithSignedChallenge(key), x402gin.WithClock(clock))
+
+	challenge := func(router *gin.Engine) (string, *types.ChallengeClaims) {
+		w := httptest.NewRecorder()
+		req.Header.Del("X-PAYMENT")
+		req.Header.Del(types.PaymentChallengeHeader)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+		var body types.PaymentRequiredResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), "the challenge body should stay plain JSON")
+		token := w.Header().Get(types.PaymentChallengeHeader)
+		claims, err := types.ParseChallengeToken(key.Public().(ed25519.PublicKey), token, clock.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, body.Accepts, claims.Accepts, "the token should hold the accepts as advertised")
+		var extra types.ExactEvmExtra
+		_, err = body.Accepts[0].DecodeExtra(&extra)
+		assert.NoError(t, err)
+		assert.Equal(t, claims.Nonce, extra.ChallengeNonce)
+		return token, claims
+	}
+	pay := func(router *gin.Engine, token, nonce string) (int, string) {
+		payload := *config.PaymentPayload
+		authorization := *payload.Payload.Authorization
+		authorization.Nonce = nonce
+		payload.Payload = &types.ExactEvmPayload{Signature: payload.Payload.Signature, Authorization: &authorization}
+		paymentPayloadJson, err := json.Marshal(&payload)
+		assert.NoError(t, err, "marshaling payment payload should not fail")
+
+		w := httptest.NewRecorder()
+		req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+		req.Header.Set(types.PaymentChallengeHeader, token)
+		router.ServeHTTP(w, req)
+
+		var body types.PaymentRequiredResponse
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Error
+	}
+
+	token, claims := challenge(router)
+	otherToken, otherClaims := challenge(router)
+	assert.NotEqual(t, claims.Nonce, otherClaims.Nonce, "each challenge should carry a fresh nonce")
+
+	status, _ := pay(router, token, claims.Nonce)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, reason := pay(router, "", claims.Nonce)
+	assert.Equal(t, http.StatusPaymentRequired, status, "a payment without a challenge token should be refused")
+	assert.Equal(t, x402gin.ReasonInvalidChallenge, reason)
+
+	status, _ = pay(router, otherToken, claims.Nonce)
+	assert.Equal(t, http.StatusPaymentRequired, status, "a payment should carry the nonce of the token it echoes")
+
+	parts := strings.Split(token, ".")
+	encoded, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	tampered := strings.Replace(string(encoded), `"exp":`, `"exp":9`, 1)
+	status, _ = pay(router, parts[0]+"."+base64.RawURLEncoding.EncodeToString([]byte(tampered))+"."+parts[2], claims.Nonce)
+	assert.Equal(t, http.StatusPaymentRequired, status, "a tampered challenge token should be refused")
+
+	otherRouter, _, _ := setupTest(t, big.NewFloat(2.0), "0xTestAddress", config, x402gin.WithSignedChallenge(key), x402gin.WithClock(clock))
+	status, _ = pay(otherRouter, token, claims.Nonce)
+	assert.Equal(t, http.StatusPaymentRequired, status, "a challenge for other requirements should be refused")
+
+	clock.Advance(x402gin.SignedChallengeTTL)
+	status, _ = pay(router, token, claims.Nonce)
+	assert.Equal(t, http.StatusPaymentRequired, status, "an expired challenge should be refused")
+
+	// With WithChallengeNonce, the token's nonce is the recorded one and is paid with once
+	nonceRouter, _, _ := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithSignedChallenge(key), x402gin.WithClock(clock), x402gin.WithChallengeNonce(nil))
+	token, claims = challenge(nonceRouter)
+	status, _ = pay(nonceRouter, token, claims.Nonce)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = pay(nonceRouter, token, claims.Nonce)
+	assert.Equal(t, http.StatusPaymentRequired, status)
+
+	assert.Panics(t, func() { x402gin.WithSignedChallenge(ed25519.PrivateKey("short")) })
+}
+
+func TestSettlementLedger(t *testing.T) {
+	config := NewTestConfig()
+	ledger := x402gin.NewSettlementLedger()
+	router, _, req := setupTest(t, big.NewFloat(1.0), "0xTestAddress", config, x402gin.WithObserver(ledger))
+
+	paymentPayloadJson, err := json.Marshal(config.PaymentPayload)
+	assert.NoError(t, err, "marshaling payment payload should not fail")
+	req.Header.Set("X-PAYMENT", base64.StdEncoding.EncodeToString(paymentPayloadJson))
+	for range 2 {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Eventually(t, func() bool { return ledger.Count() == 2 }, time.Second, time.Millisecond, "the middleware should feed settlements to the ledger")
+
+	usdc := types.USDCAssets[types.NetworkBaseSepolia].Address
+	assert.Equal(t, "2000000", ledger.TotalByNetwork()[types.NetworkBaseSepolia].String())
+	assert.Equal(t, "2000000", ledger.TotalByAsset()[usdc].String())
+
+	// Concurrent records all count, including amounts beyond 64 bits
+	var done sync.WaitGroup
+	for range 10 {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			assert.NoError(t, ledger.Record(types.NetworkBase, usdc, "100000000000000000000"))
+		}()
+	}
+	done.Wait()
+	assert.Equal(t, 12, ledger.Count())
+	assert.Equal(t, "1000000000000000000000", ledger.TotalByNetwork()[types.NetworkBase].String())
+	assert.Equal(t, "1000000000000002000000", ledger.TotalByAsset()[usdc].String())
+	assert.Error(t, ledger.Record(types.NetworkBase, usdc, "-1"))
+
+	// Totals returned are copies
+	ledger.TotalByNetwork()[types.NetworkBase].SetInt64(0)
+	assert.Equal(t, "1000000000000000000000", ledger.TotalByNetwork()[types.NetworkBase].String())
+
+	ledger.Reset()
+	assert.Zero(t, ledger.Count())
+	assert.Empty(t, ledger.TotalByNetwork())
+	assert.Empty(t, ledger.TotalByAsset())
+}
@@ -0,0 +1,126 @@
+package gin
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// observerQueueSize bounds the events waiting for the observer; further events are dropped
+const observerQueueSize = 256
+
+// dropLogInterval is the minimum time between log lines about dropped events
+const dropLogInterval = 10 * time.Second
+
+// PaymentEventType identifies a stage of the payment lifecycle
+type PaymentEventType string
+
+const (
+	// EventChallenged is emitted when a request without a payment receives 402 Payment Required
+	EventChallenged PaymentEventType = "challenged"
+	// EventVerified is emitted when a payment is verified and the request is passed to the handler
+	EventVerified PaymentEventType = "verified"
+	// EventVerifyFailed is emitted when a payment is rejected or cannot be verified
+	EventVerifyFailed PaymentEventType = "verify_failed"
+	// EventSettled is emitted when the facilitator reports a successful settlement
+	EventSettled PaymentEventType = "settled"
+	// EventSubmissionRequired is emitted when the facilitator only prepared the settlement transaction,
+	// which is returned to the payer to sign and submit
+	EventSubmissionRequired PaymentEventType = "submission_required"
+	// EventSettleFailed is emitted when settlement fails or the facilitator cannot be reached
+	EventSettleFailed PaymentEventType = "settle_failed"
+)
+
+// PaymentEvent describes a payment lifecycle transition observed by the PaymentMiddleware
+type PaymentEvent struct {
+	Type     PaymentEventType
+	Resource string
+	// Payer is empty until the request carries a payment
+	Payer string
+	// Amount is the required amount in atomic units of the asset
+	Amount  string
+	Network string
+	Asset   string
+	// Reason explains a failure event
+	Reason string
+	// Time is when the event occurred, and Elapsed how long after the request entered the middleware
+	Time    time.Time
+	Elapsed time.Duration
+	// Dropped is the number of events dropped since the previous delivered event, because the observer fell behind
+	Dropped int64
+}
+
+// Observer receives payment lifecycle events, e.g. to record business metrics
+type Observer interface {
+	ObservePayment(event PaymentEvent)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(event PaymentEvent)
+
+// ObservePayment calls f(event)
+func (f ObserverFunc) ObservePayment(event PaymentEvent) {
+	f(event)
+}
+
+// WithObserver is an option for the PaymentMiddleware to report each payment lifecycle event to observer.
+// Events are delivered in order from a single goroutine, so the observer never blocks requests;
+// if the observer falls more than observerQueueSize events behind, further events are dropped and counted
+// in the Dropped field of the next delivered event.
+func WithObserver(observer Observer) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.Observer = observer
+	}
+}
+
+// observerQueue delivers events to an observer off the request path
+type observerQueue struct {
+	events chan PaymentEvent
+	// dropped counts events dropped since the last delivered event, and unlogged since the last log line
+	dropped  atomic.Int64
+	unlogged atomic.Int64
+	// lastLog is the unix nano time of the last log line about dropped events
+	lastLog atomic.Int64
+}
+
+// newObserverQueue starts delivering events to observer. It returns nil if observer is nil.
+func newObserverQueue(observer Observer) *observerQueue {
+	if observer == nil {
+		return nil
+	}
+
+	q := &observerQueue{events: make(chan PaymentEvent, observerQueueSize)}
+	go func() {
+		for event := range q.events {
+			event.Dropped = q.dropped.Swap(0)
+			observer.ObservePayment(event)
+		}
+	}()
+
+	return q
+}
+
+// emit queues the event without blocking, dropping it if the queue is full
+func (q *observerQueue) emit(event PaymentEvent) {
+	if q == nil {
+		return
+	}
+
+	select {
+	case q.events <- event:
+	default:
+		q.dropped.Add(1)
+		q.unlogged.Add(1)
+		q.logDropped(time.Now())
+	}
+}
+
+// logDropped reports dropped events at most once per dropLogInterval, so a stalled observer doesn't flood the log
+func (q *observerQueue) logDropped(now time.Time) {
+	last := q.lastLog.Load()
+	if now.UnixNano()-last < int64(dropLogInterval) || !q.lastLog.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	fmt.Println("Payment observer is falling behind, dropped events:", q.unlogged.Swap(0))
+}
@@ -0,0 +1,64 @@
+package gin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+)
+
+// nameResolutionTimeout bounds resolving the configured names when the middleware is built
+const nameResolutionTimeout = 30 * time.Second
+
+// NameResolver resolves human readable names such as ENS names ("myapi.eth") or Basenames to hex addresses
+type NameResolver interface {
+	ResolveName(ctx context.Context, name string) (string, error)
+}
+
+// NameResolverFunc is an adapter to allow the use of ordinary functions as a NameResolver
+type NameResolverFunc func(ctx context.Context, name string) (string, error)
+
+// ResolveName calls f(ctx, name)
+func (f NameResolverFunc) ResolveName(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// WithNameResolver is an option for the PaymentMiddleware to accept names such as "myapi.eth" for the payTo
+// address and the settlement recipient. Names are resolved once when the middleware is built, so requests
+// never wait on the resolver and keep the resolved address for the life of the middleware.
+// Resolution is off by default, so no RPC dependency is needed for hex addresses.
+func WithNameResolver(resolver NameResolver) Options {
+	return func(options *PaymentMiddlewareOptions) {
+		options.NameResolver = resolver
+	}
+}
+
+// resolveAddress returns address unchanged if it is a hex address or no resolver is configured,
+// and otherwise resolves it as a name
+func resolveAddress(resolver NameResolver, address string) (string, error) {
+	if resolver == nil || !isName(address) {
+		return address, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nameResolutionTimeout)
+	defer cancel()
+
+	resolved, err := resolver.ResolveName(ctx, strings.TrimSpace(address))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q: %w", address, err)
+	}
+	normalized, err := exactevm.NormalizeAddress(resolved)
+	if err != nil {
+		return "", fmt.Errorf("name %q resolved to an %w", address, err)
+	}
+
+	return normalized, nil
+}
+
+// isName reports whether address looks like a dotted name rather than a hex address
+func isName(address string) bool {
+	address = strings.TrimSpace(address)
+	return strings.Contains(address, ".") && !strings.HasPrefix(address, "0x")
+}
@@ -0,0 +1,108 @@
+package gin
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettleMode is when the PaymentMiddleware settles a verified payment relative to running the handler
+type SettleMode int
+
+const (
+	// SettleAfterSuccess buffers the handler's response and settles once the handler has finished without
+	// aborting and with a status below 400, then writes the response. The payer is only charged for responses
+	// that succeeded, but nothing reaches the client until settlement returns, and side effects of the handler
+	// happen before the payment is settled: if settlement fails, the client is challenged again and the work
+	// was done for free. This is the default, suited to idempotent routes such as GETs.
+	SettleAfterSuccess SettleMode = iota
+	// SettleBeforeHandler settles before running the handler, which only runs once the payment has settled.
+	// Suited to side-effecting routes such as POSTs, where the work must not be done before the payment is
+	// final. The payer is charged even if the handler then fails; use the facilitator client's Refund to
+	// reconcile such failures where the facilitator supports it.
+	SettleBeforeHandler
+	// SettleOnFirstByte runs the handler unbuffered and settles when it writes the first byte of a response
+	// with a status below 400, so streaming responses start as soon as the payment settles. Handler side effects
+	// before the first write happen before settlement. If settlement fails, the client is challenged and the
+	// handler's writes are discarded; once the first byte is sent, a failing handler has still been paid for.
+	SettleOnFirstByte
+)
+
+// String returns the name of the settle mode
+func (m SettleMode) String() string {
+	switch m {
+	case SettleAfterSuccess:
+		return "SettleAfterSuccess"
+	case SettleBeforeHandler:
+		return "SettleBeforeHandler"
+	case SettleOnFirstByte:
+		return "SettleOnFirstByte"
+	default:
+		return fmt.Sprintf("SettleMode(%d)", int(m))
+	}
+}
+
+// WithSettleMode is an option for the PaymentMiddleware to settle payments at the given point of the request,
+// see SettleMode for the failure and refund implications of each. Since the option applies to one middleware,
+// routes with different semantics each get their own. It panics on an unknown mode.
+// A zero amount and WithVerifyOnly are never settled, whatever the mode.
+func WithSettleMode(mode SettleMode) Options {
+	if mode < SettleAfterSuccess || mode > SettleOnFirstByte {
+		panic(fmt.Sprintf("invalid settle mode %s", mode))
+	}
+
+	return func(options *PaymentMiddlewareOptions) {
+		options.SettleMode = mode
+	}
+}
+
+// firstByteWriter calls onFirstByte before the first byte of the response is written, passing the writes
+// through if it returns true and discarding them otherwise
+type firstByteWriter struct {
+	gin.ResponseWriter
+	onFirstByte func() bool
+	started     bool
+	refused     bool
+}
+
+// begin calls onFirstByte on the first write and reports whether writes are passed through
+func (w *firstByteWriter) begin() bool {
+	if !w.started {
+		w.started = true
+		w.refused = !w.onFirstByte()
+	}
+
+	return !w.refused
+}
+
+func (w *firstByteWriter) WriteHeader(code int) {
+	if !w.refused {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *firstByteWriter) WriteHeaderNow() {
+	if w.begin() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *firstByteWriter) Write(b []byte) (int, error) {
+	if !w.begin() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *firstByteWriter) WriteString(s string) (int, error) {
+	if !w.begin() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *firstByteWriter) Flush() {
+	if w.begin() {
+		w.ResponseWriter.Flush()
+	}
+}
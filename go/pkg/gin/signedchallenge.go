@@ -0,0 +1,91 @@
+package gin
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ReasonInvalidChallenge is the reason the PaymentMiddleware challenges a payment that doesn't answer a valid
+// signed challenge for the requirements, see WithSignedChallenge
+const ReasonInvalidChallenge = "invalid_payment_challenge"
+
+// SignedChallengeTTL is how long a challenge signed with WithSignedChallenge can be paid with
+const SignedChallengeTTL = 5 * time.Minute
+
+// WithSignedChallenge is an option for the PaymentMiddleware to sign its challenges, for deployments where proxies
+// or other untrusted network paths sit between it and its clients. Every challenge carries a fresh challengeNonce
+// in the accepts' extra, as with WithChallengeNonce, and a types.ChallengeClaims token signed with key in the
+// types.PaymentChallengeHeader header, holding the accepts as advertised and their nonce.
+// Clients holding the public key, such as a paymentclient.PaymentTransport with a ChallengeKey, pay for the
+// token's accepts, so requirements altered in transit can't redirect their payment. Payments are only accepted
+// if they echo an unexpired token whose accepts are those the request would be challenged with now, and whose
+// nonce is the payment's authorization nonce, so each token answers one signed authorization. Other payments
+// are refused with ReasonInvalidChallenge before they are verified. Tokens aren't single use by themselves:
+// combine with WithChallengeNonce or WithUsageStore where replays of an authorization must be refused.
+// The challenge body stays plain JSON. It panics if key isn't an ed25519 private key.
+func WithSignedChallenge(key ed25519.PrivateKey) Options {
+	if len(key) != ed25519.PrivateKeySize {
+		panic(fmt.Sprintf("invalid challenge key: must be %d bytes, got %d", ed25519.PrivateKeySize, len(key)))
+	}
+
+	return func(options *PaymentMiddlewareOptions) {
+		options.ChallengeKey = key
+	}
+}
+
+// signChallenge returns a token signed with key for a challenge issued at now, advertising the challenged
+// accepts carrying nonce
+func signChallenge(key ed25519.PrivateKey, challenged []*types.PaymentRequirements, nonce string, now time.Time) (string, error) {
+	claims := &types.ChallengeClaims{
+		Accepts:   make([]types.PaymentRequirements, len(challenged)),
+		Nonce:     nonce,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(SignedChallengeTTL).Unix(),
+	}
+	for i, requirements := range challenged {
+		claims.Accepts[i] = *requirements
+	}
+
+	return types.SignChallengeToken(key, claims)
+}
+
+// verifyChallenge checks that token was signed with the private key of key, hasn't expired by now,
+// challenged with the accepts carrying its nonce and that the payload's authorization nonce is the token's
+func verifyChallenge(key ed25519.PublicKey, token string, accepts []*types.PaymentRequirements, payload *types.PaymentPayload, now time.Time) error {
+	if token == "" {
+		return errors.New("missing challenge token")
+	}
+	claims, err := types.ParseChallengeToken(key, token, now)
+	if err != nil {
+		return err
+	}
+
+	if payload.Payload == nil || payload.Payload.Authorization == nil || !strings.EqualFold(payload.Payload.Authorization.Nonce, claims.Nonce) {
+		return errors.New("payment nonce is not the challenge nonce")
+	}
+
+	challenged, err := withChallengeNonce(accepts, claims.Nonce)
+	if err != nil {
+		return err
+	}
+	expected, err := json.Marshal(challenged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal accepts: %w", err)
+	}
+	actual, err := json.Marshal(claims.Accepts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal challenge accepts: %w", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		return errors.New("challenge was issued for other requirements")
+	}
+
+	return nil
+}
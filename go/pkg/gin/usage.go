@@ -0,0 +1,103 @@
+package gin
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ReasonAlreadyUsed is the reason the PaymentMiddleware challenges a payment already honored for the resource
+const ReasonAlreadyUsed = "payment_already_used"
+
+// MaxMemoryUsages is the most unexpired usages a MemoryUsageStore keeps
+const MaxMemoryUsages = 1 << 16
+
+// minUsageTTL is the shortest time a usage is kept, so an authorization whose validBefore has passed by the
+// server's clock but not by the facilitator's can't be replayed
+const minUsageTTL = 10 * time.Minute
+
+// UsageKey identifies an authorization honored for a resource
+type UsageKey struct {
+	Resource string
+	Payer    string
+	Nonce    string
+}
+
+// String returns the key as a single string, e.g. a Redis key. Payer and nonce are lowercased, so it is the same
+// for every spelling of the same authorization.
+func (k UsageKey) String() string {
+	return strconv.Quote(k.Resource) + ":" + strings.ToLower(k.Payer) + ":" + strings.ToLower(k.Nonce)
+}
+
+// UsageStore records the authorizations honored by WithUsageStore.
+// Implementations must be safe for concurrent use, and Use must check and record the key atomically so
+// concurrent replays of one payment aren't both honored, e.g. with Redis SET key 1 NX PXAT expiresAt.
+type UsageStore interface {
+	// Use records key as used until expiresAt, reporting false if it was already recorded and hasn't expired
+	Use(key UsageKey, expiresAt time.Time) bool
+}
+
+// MemoryUsageStore is an in-memory UsageStore. It keeps at most MaxMemoryUsages usages: once full, it refuses
+// new usages until old ones expire rather than forget a usage that could then be replayed.
+type MemoryUsageStore struct {
+	mu     sync.Mutex
+	usages *expirySet
+}
+
+// NewMemoryUsageStore creates a new in-memory usage store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{
+		usages: newExpirySet(),
+	}
+}
+
+// Use records key as used until expiresAt, reporting false if it was already recorded and hasn't expired
+func (s *MemoryUsageStore) Use(key UsageKey, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usages.sweep(time.Now())
+	usage := key.String()
+	if _, ok := s.usages.get(usage); ok || s.usages.len() >= MaxMemoryUsages {
+		return false
+	}
+	s.usages.set(usage, expiresAt)
+
+	return true
+}
+
+// WithUsageStore is an option for the PaymentMiddleware to honor each verified authorization at most once per
+// resource, keyed by the resource, the payer and the authorization nonce in store until the authorization expires
+// and for at least ten minutes. A replay of a payment already honored for the resource, e.g. on a verify-only
+// route or before the first use has settled, is challenged with ReasonAlreadyUsed. A payment is recorded once
+// verified, so one refused afterwards, e.g. by a failed settlement, can't be retried and the client must sign a
+// new one. If store is nil, an in-memory store is used; share a store such as Redis between instances behind a
+// load balancer.
+func WithUsageStore(store UsageStore) Options {
+	if store == nil {
+		store = NewMemoryUsageStore()
+	}
+
+	return func(options *PaymentMiddlewareOptions) {
+		options.Usages = store
+	}
+}
+
+// useAuthorization records the payment's authorization as used for resource in store,
+// reporting false if it already was
+func useAuthorization(store UsageStore, resource, payer string, payload *types.PaymentPayload) bool {
+	if payload.Payload == nil || payload.Payload.Authorization == nil {
+		return false
+	}
+	authorization := payload.Payload.Authorization
+
+	expiresAt := time.Now().Add(minUsageTTL)
+	if validBefore, err := strconv.ParseInt(authorization.ValidBefore, 10, 64); err == nil && time.Unix(validBefore, 0).After(expiresAt) {
+		expiresAt = time.Unix(validBefore, 0)
+	}
+
+	return store.Use(UsageKey{Resource: resource, Payer: payer, Nonce: authorization.Nonce}, expiresAt)
+}
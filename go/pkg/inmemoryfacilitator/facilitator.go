@@ -0,0 +1,181 @@
+// Package inmemoryfacilitator provides an offline facilitator for integration tests.
+//
+// It verifies exact EVM payments for real (EIP-712 signature recovery, recipient, value and
+// validity window) but does not check balances or submit anything on chain. Settlement marks
+// the authorization nonce as used and returns a deterministic fake transaction hash.
+package inmemoryfacilitator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ReasonNonceUsed is reported for an authorization whose nonce was already settled
+const ReasonNonceUsed = "invalid_exact_evm_payload_authorization_nonce_used"
+
+// Facilitator is an in-memory facilitator serving the verify, settle and supported endpoints
+type Facilitator struct {
+	clock exactevm.Clock
+
+	mu         sync.Mutex
+	usedNonces map[string]struct{}
+	settled    []*types.PaymentPayload
+}
+
+// FacilitatorOptions is the options for the in-memory Facilitator.
+type FacilitatorOptions struct {
+	Clock exactevm.Clock
+}
+
+// Options is the type for the options for the in-memory Facilitator.
+type Options func(*FacilitatorOptions)
+
+// WithClock is an option for the Facilitator to check validity windows against clock instead of the system time,
+// e.g. a testclock.Clock advanced past an authorization's validBefore
+func WithClock(clock exactevm.Clock) Options {
+	return func(options *FacilitatorOptions) {
+		options.Clock = clock
+	}
+}
+
+// facilitatorRequest is the request body sent to the verify and settle endpoints
+type facilitatorRequest struct {
+	X402Version         int                        `json:"x402Version"`
+	PaymentPayload      *types.PaymentPayload      `json:"paymentPayload"`
+	PaymentRequirements *types.PaymentRequirements `json:"paymentRequirements"`
+}
+
+// New creates a new in-memory facilitator
+func New(opts ...Options) *Facilitator {
+	options := &FacilitatorOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Facilitator{
+		clock:      options.Clock,
+		usedNonces: make(map[string]struct{}),
+	}
+}
+
+// ServeHTTP implements the facilitator HTTP API, so the facilitator can back an httptest.Server
+func (f *Facilitator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/supported" {
+		json.NewEncoder(w).Encode(f.Supported())
+		return
+	}
+	if r.URL.Path != "/verify" && r.URL.Path != "/settle" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(types.ErrorResponse{Error: "not found"})
+		return
+	}
+
+	var req facilitatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PaymentRequirements == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(types.ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	if r.URL.Path == "/verify" {
+		json.NewEncoder(w).Encode(f.Verify(req.PaymentPayload, req.PaymentRequirements))
+	} else {
+		json.NewEncoder(w).Encode(f.Settle(req.PaymentPayload, req.PaymentRequirements))
+	}
+}
+
+// Verify verifies the payment against the requirements and checks its nonce has not been settled
+func (f *Facilitator) Verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) *types.VerifyResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payer, reason := f.verify(payload, requirements)
+	if reason != "" {
+		return &types.VerifyResponse{IsValid: false, InvalidReason: &reason}
+	}
+
+	payerHex := payer
+	return &types.VerifyResponse{IsValid: true, Payer: &payerHex}
+}
+
+// Settle verifies the payment and, if it is valid, marks its nonce as used
+func (f *Facilitator) Settle(payload *types.PaymentPayload, requirements *types.PaymentRequirements) *types.SettleResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payer, reason := f.verify(payload, requirements)
+	if reason != "" {
+		return &types.SettleResponse{Success: false, ErrorReason: &reason, Network: requirements.Network}
+	}
+
+	nonce := payload.Payload.Authorization.Nonce
+	f.usedNonces[strings.ToLower(nonce)] = struct{}{}
+	f.settled = append(f.settled, payload)
+
+	return &types.SettleResponse{
+		Success:     true,
+		Transaction: Transaction(nonce),
+		Network:     requirements.Network,
+		Payer:       &payer,
+	}
+}
+
+// Supported returns the exact scheme on every known EVM network
+func (f *Facilitator) Supported() *types.SupportedResponse {
+	resp := &types.SupportedResponse{}
+	for network := range types.EvmNetworkToChainID {
+		resp.Kinds = append(resp.Kinds, types.SupportedKind{
+			X402Version: types.X402Version,
+			Scheme:      exactevm.Scheme,
+			Network:     network,
+		})
+	}
+
+	return resp
+}
+
+// Settled returns the payments settled so far, in the order they were settled
+func (f *Facilitator) Settled() []*types.PaymentPayload {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]*types.PaymentPayload(nil), f.settled...)
+}
+
+// Transaction returns the fake transaction hash the facilitator reports for settling the given nonce
+func Transaction(nonce string) string {
+	return crypto.Keccak256Hash([]byte(strings.ToLower(nonce))).Hex()
+}
+
+// verify returns the payer of a valid payment, or the reason it is invalid. f.mu must be held.
+func (f *Facilitator) verify(payload *types.PaymentPayload, requirements *types.PaymentRequirements) (string, string) {
+	var verifyOpts []exactevm.Options
+	if f.clock != nil {
+		verifyOpts = append(verifyOpts, exactevm.WithClock(f.clock))
+	}
+
+	payer, err := exactevm.VerifyPayment(payload, requirements, verifyOpts...)
+	if err != nil {
+		var verificationErr *exactevm.VerificationError
+		if errors.As(err, &verificationErr) {
+			return "", verificationErr.Reason
+		}
+		return "", err.Error()
+	}
+
+	if _, used := f.usedNonces[strings.ToLower(payload.Payload.Authorization.Nonce)]; used {
+		return "", ReasonNonceUsed
+	}
+
+	return payer.Hex(), ""
+}
@@ -0,0 +1,143 @@
+package inmemoryfacilitator_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/inmemoryfacilitator"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/middlewaretest/testclock"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newTestClient(t *testing.T, facilitator *inmemoryfacilitator.Facilitator) *facilitatorclient.FacilitatorClient {
+	t.Helper()
+
+	server := httptest.NewServer(facilitator)
+	t.Cleanup(server.Close)
+
+	return facilitatorclient.NewFacilitatorClient(&types.FacilitatorConfig{URL: server.URL})
+}
+
+func TestSignVerifySettle(t *testing.T) {
+	facilitator := inmemoryfacilitator.New()
+	client := newTestClient(t, facilitator)
+	signer := middlewaretest.NewTestSigner(t)
+	requirements := middlewaretest.NewTestRequirements()
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	verifyResp, err := client.Verify(payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !verifyResp.IsValid || verifyResp.Payer == nil || *verifyResp.Payer != signer.Address().Hex() {
+		t.Fatalf("Expected valid payment from %s, got: %+v", signer.Address().Hex(), verifyResp)
+	}
+
+	settleResp, err := client.Settle(payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !settleResp.Success {
+		t.Fatalf("Expected successful settlement, got: %s", *settleResp.ErrorReason)
+	}
+	expectedTransaction := inmemoryfacilitator.Transaction(payload.Payload.Authorization.Nonce)
+	if settleResp.Transaction != expectedTransaction {
+		t.Errorf("Expected transaction %s, got: %s", expectedTransaction, settleResp.Transaction)
+	}
+	if len(facilitator.Settled()) != 1 {
+		t.Errorf("Expected 1 settled payment, got: %d", len(facilitator.Settled()))
+	}
+
+	// The nonce cannot be replayed
+	verifyResp, err = client.Verify(payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if verifyResp.IsValid || *verifyResp.InvalidReason != inmemoryfacilitator.ReasonNonceUsed {
+		t.Errorf("Expected %s, got: %+v", inmemoryfacilitator.ReasonNonceUsed, verifyResp)
+	}
+	settleResp, err = client.Settle(payload, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if settleResp.Success {
+		t.Error("Expected replayed settlement to fail")
+	}
+}
+
+func TestVerifyRejectsInvalidPayments(t *testing.T) {
+	facilitator := inmemoryfacilitator.New()
+	signer := middlewaretest.NewTestSigner(t)
+	requirements := middlewaretest.NewTestRequirements()
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	otherSigner := middlewaretest.NewTestSigner(t)
+	forged := *payload.Payload.Authorization
+	forged.From = otherSigner.Address().Hex()
+	forgedPayload := *payload
+	forgedPayload.Payload = &types.ExactEvmPayload{Signature: payload.Payload.Signature, Authorization: &forged}
+
+	tests := []struct {
+		name    string
+		payload *types.PaymentPayload
+		reason  string
+	}{
+		{"forged from", &forgedPayload, exactevm.ReasonInvalidSignature},
+	}
+	for _, tt := range tests {
+		resp := facilitator.Verify(tt.payload, requirements)
+		if resp.IsValid || resp.InvalidReason == nil || *resp.InvalidReason != tt.reason {
+			t.Errorf("%s: expected %s, got: %+v", tt.name, tt.reason, resp)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	client := newTestClient(t, inmemoryfacilitator.New())
+
+	resp, err := client.Supported()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(resp.Kinds) != len(types.EvmNetworkToChainID) {
+		t.Errorf("Expected %d kinds, got: %d", len(types.EvmNetworkToChainID), len(resp.Kinds))
+	}
+}
+
+func TestValidityWindow(t *testing.T) {
+	clock := testclock.New(time.Now())
+	facilitator := inmemoryfacilitator.New(inmemoryfacilitator.WithClock(clock))
+	requirements := middlewaretest.NewTestRequirements()
+
+	payload, err := exactevm.CreatePayment(middlewaretest.NewTestSigner(t), requirements, exactevm.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp := facilitator.Verify(payload, requirements); !resp.IsValid {
+		t.Fatalf("Expected a valid payment, got: %+v", resp)
+	}
+
+	clock.Advance(-time.Hour)
+	resp := facilitator.Verify(payload, requirements)
+	if resp.IsValid || *resp.InvalidReason != exactevm.ReasonNotYetValid {
+		t.Errorf("Expected %s, got: %+v", exactevm.ReasonNotYetValid, resp)
+	}
+
+	clock.Advance(time.Hour + time.Duration(requirements.MaxTimeoutSeconds)*time.Second)
+	resp = facilitator.Verify(payload, requirements)
+	if resp.IsValid || *resp.InvalidReason != exactevm.ReasonExpired {
+		t.Errorf("Expected %s, got: %+v", exactevm.ReasonExpired, resp)
+	}
+}
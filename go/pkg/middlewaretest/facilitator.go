@@ -0,0 +1,46 @@
+package middlewaretest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/inmemoryfacilitator"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// MockFacilitator is a test facilitator server backed by an inmemoryfacilitator.Facilitator.
+// It verifies exact payments locally and records every payment it settles, without
+// checking balances or reaching any chain.
+type MockFacilitator struct {
+	*inmemoryfacilitator.Facilitator
+	Server *httptest.Server
+}
+
+// NewMockFacilitator starts a MockFacilitator that is closed when the test finishes
+func NewMockFacilitator(t testing.TB) *MockFacilitator {
+	t.Helper()
+
+	f := &MockFacilitator{Facilitator: inmemoryfacilitator.New()}
+	f.Server = httptest.NewServer(f.Facilitator)
+	t.Cleanup(f.Server.Close)
+
+	return f
+}
+
+// Config returns a facilitator config pointing at the mock facilitator
+func (f *MockFacilitator) Config() *types.FacilitatorConfig {
+	return &types.FacilitatorConfig{
+		URL: f.Server.URL,
+	}
+}
+
+// settledNonce reports whether a payment with the given authorization nonce was settled
+func (f *MockFacilitator) settledNonce(nonce string) bool {
+	for _, payload := range f.Settled() {
+		if strings.EqualFold(payload.Payload.Authorization.Nonce, nonce) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Package middlewaretest provides helpers for testing routes protected by the x402 payment middleware.
+package middlewaretest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// NewTestSigner creates a signer for a freshly generated key
+func NewTestSigner(t testing.TB) *exactevm.PrivateKeySigner {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	return exactevm.NewPrivateKeySigner(key)
+}
+
+// NewTestRequirements returns exact requirements for 0.01 USDC on base-sepolia,
+// for the resource http://example.com/protected
+func NewTestRequirements() *types.PaymentRequirements {
+	extra := json.RawMessage(`{"name":"USDC","version":"2"}`)
+	return &types.PaymentRequirements{
+		Scheme:            exactevm.Scheme,
+		Network:           types.NetworkBaseSepolia,
+		MaxAmountRequired: "10000",
+		Resource:          "http://example.com/protected",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Asset:             types.USDCAssets[types.NetworkBaseSepolia].Address,
+		Extra:             &extra,
+	}
+}
+
+// NewPaidRequest builds a GET request for the requirements' resource carrying a valid
+// X-PAYMENT header signed by signer
+func NewPaidRequest(t testing.TB, requirements *types.PaymentRequirements, signer exactevm.Signer) *http.Request {
+	t.Helper()
+
+	payload, err := exactevm.CreatePayment(signer, requirements)
+	if err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+
+	header, err := payload.EncodeToBase64String()
+	if err != nil {
+		t.Fatalf("Failed to encode payment: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, requirements.Resource, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("X-PAYMENT", header)
+
+	return req
+}
+
+// PaymentNonce returns the authorization nonce of the X-PAYMENT header on req
+func PaymentNonce(t testing.TB, req *http.Request) string {
+	t.Helper()
+
+	payload, err := types.DecodePaymentPayloadFromBase64(req.Header.Get("X-PAYMENT"))
+	if err != nil {
+		t.Fatalf("Failed to decode X-PAYMENT header: %v", err)
+	}
+	if payload.Payload == nil || payload.Payload.Authorization == nil {
+		t.Fatalf("X-PAYMENT header is missing its authorization")
+	}
+
+	return payload.Payload.Authorization.Nonce
+}
+
+// AssertSettled fails the test if the facilitator did not settle the payment with the given nonce
+func AssertSettled(t testing.TB, facilitator *MockFacilitator, nonce string) {
+	t.Helper()
+
+	if !facilitator.settledNonce(nonce) {
+		t.Errorf("Expected payment with nonce %s to be settled", nonce)
+	}
+}
+
+// AssertNotSettled fails the test if the facilitator settled the payment with the given nonce
+func AssertNotSettled(t testing.TB, facilitator *MockFacilitator, nonce string) {
+	t.Helper()
+
+	if facilitator.settledNonce(nonce) {
+		t.Errorf("Expected payment with nonce %s not to be settled", nonce)
+	}
+}
@@ -0,0 +1,122 @@
+package middlewaretest_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	x402gin "github.com/coinbase/x402/go/pkg/gin"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const testPayTo = "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+
+func newTestRouter(facilitator *middlewaretest.MockFacilitator, status int, opts ...x402gin.Options) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	allOpts := append([]x402gin.Options{
+		x402gin.WithFacilitatorConfig(facilitator.Config()),
+		x402gin.WithResourceRootURL("http://example.com"),
+	}, opts...)
+	router.GET("/protected", x402gin.PaymentMiddleware(big.NewFloat(0.01), testPayTo, allOpts...), func(c *gin.Context) {
+		c.String(status, "success")
+		if status != http.StatusOK {
+			c.Abort()
+		}
+	})
+
+	return router
+}
+
+func TestNewPaidRequest(t *testing.T) {
+	facilitator := middlewaretest.NewMockFacilitator(t)
+	router := newTestRouter(facilitator, http.StatusOK)
+
+	req := middlewaretest.NewPaidRequest(t, middlewaretest.NewTestRequirements(), middlewaretest.NewTestSigner(t))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-PAYMENT-RESPONSE") == "" {
+		t.Error("Expected X-PAYMENT-RESPONSE header to be set")
+	}
+	middlewaretest.AssertSettled(t, facilitator, middlewaretest.PaymentNonce(t, req))
+}
+
+func TestUnderpaidRequestIsRejected(t *testing.T) {
+	facilitator := middlewaretest.NewMockFacilitator(t)
+	router := newTestRouter(facilitator, http.StatusOK)
+
+	requirements := middlewaretest.NewTestRequirements()
+	requirements.MaxAmountRequired = "1"
+	req := middlewaretest.NewPaidRequest(t, requirements, middlewaretest.NewTestSigner(t))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402, got: %d", w.Code)
+	}
+	middlewaretest.AssertNotSettled(t, facilitator, middlewaretest.PaymentNonce(t, req))
+}
+
+func TestAbortedHandlerIsNotSettled(t *testing.T) {
+	facilitator := middlewaretest.NewMockFacilitator(t)
+	router := newTestRouter(facilitator, http.StatusInternalServerError)
+
+	req := middlewaretest.NewPaidRequest(t, middlewaretest.NewTestRequirements(), middlewaretest.NewTestSigner(t))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	middlewaretest.AssertNotSettled(t, facilitator, middlewaretest.PaymentNonce(t, req))
+	if len(facilitator.Settled()) != 0 {
+		t.Errorf("Expected no settlements, got: %d", len(facilitator.Settled()))
+	}
+}
+
+func TestSettlementRecipient(t *testing.T) {
+	const escrow = "0x3333333333333333333333333333333333333333"
+	facilitator := middlewaretest.NewMockFacilitator(t)
+	router := newTestRouter(facilitator, http.StatusOK, x402gin.WithSettlementRecipient(escrow))
+
+	// The 402 challenge advertises the settlement recipient in extra
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/protected", nil))
+	var challenge struct {
+		Accepts []*types.PaymentRequirements `json:"accepts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &challenge); err != nil || len(challenge.Accepts) != 1 {
+		t.Fatalf("Expected a 402 challenge with one requirement, got: %s", w.Body.String())
+	}
+	requirements := challenge.Accepts[0]
+	if requirements.PayTo != testPayTo {
+		t.Errorf("Expected payTo %s, got: %s", testPayTo, requirements.PayTo)
+	}
+
+	req := middlewaretest.NewPaidRequest(t, requirements, middlewaretest.NewTestSigner(t))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d (%s)", w.Code, w.Body.String())
+	}
+	middlewaretest.AssertSettled(t, facilitator, middlewaretest.PaymentNonce(t, req))
+
+	settled := facilitator.Settled()
+	if len(settled) != 1 || settled[0].Payload.Authorization.To != escrow {
+		t.Errorf("Expected settlement to %s, got: %+v", escrow, settled)
+	}
+
+	// A payment signed to payTo is rejected
+	direct := middlewaretest.NewPaidRequest(t, middlewaretest.NewTestRequirements(), middlewaretest.NewTestSigner(t))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, direct)
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402, got: %d", w.Code)
+	}
+}
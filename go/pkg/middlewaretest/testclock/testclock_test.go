@@ -0,0 +1,27 @@
+package testclock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/middlewaretest/testclock"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Unix(1745323800, 0)
+	clock := testclock.New(start)
+
+	if !clock.Now().Equal(start) {
+		t.Errorf("Expected %s, got: %s", start, clock.Now())
+	}
+
+	clock.Advance(90 * time.Second)
+	if want := start.Add(90 * time.Second); !clock.Now().Equal(want) {
+		t.Errorf("Expected %s, got: %s", want, clock.Now())
+	}
+
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Errorf("Expected %s, got: %s", start, clock.Now())
+	}
+}
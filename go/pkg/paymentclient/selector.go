@@ -0,0 +1,180 @@
+package paymentclient
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// FeeEstimateTTL is how long CheapestAfterFees reuses a fee estimate for a network and asset
+const FeeEstimateTTL = 30 * time.Second
+
+// PaymentSelector chooses which of a server's advertised payment requirements to pay.
+// Select is only given requirements the client can satisfy and is never called with an empty slice.
+type PaymentSelector interface {
+	Select(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error)
+}
+
+// SelectorFunc is an adapter to allow the use of ordinary functions as a PaymentSelector
+type SelectorFunc func(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error)
+
+// Select calls f(accepts)
+func (f SelectorFunc) Select(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error) {
+	return f(accepts)
+}
+
+// First selects the first satisfiable requirements in the order the server advertised them
+func First() PaymentSelector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error) {
+		return &accepts[0], nil
+	})
+}
+
+// PreferNetwork selects the first requirements on the earliest listed network,
+// falling back to the first satisfiable requirements if none match
+func PreferNetwork(networks ...string) PaymentSelector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error) {
+		for _, network := range networks {
+			for i := range accepts {
+				if accepts[i].Network == network {
+					return &accepts[i], nil
+				}
+			}
+		}
+
+		return &accepts[0], nil
+	})
+}
+
+// Cheapest selects the requirements with the lowest maxAmountRequired.
+// Amounts are compared in atomic units, so it is meant for options denominated in the same asset.
+func Cheapest() PaymentSelector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error) {
+		var (
+			cheapest *types.PaymentRequirements
+			lowest   *big.Int
+		)
+		for i := range accepts {
+			amount, ok := new(big.Int).SetString(accepts[i].MaxAmountRequired, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid maxAmountRequired: %q", accepts[i].MaxAmountRequired)
+			}
+			if lowest == nil || amount.Cmp(lowest) < 0 {
+				cheapest = &accepts[i]
+				lowest = amount
+			}
+		}
+
+		return cheapest, nil
+	})
+}
+
+// FeeEstimator estimates the fees a payer bears to settle a payment beyond the value it authorizes,
+// in atomic units of the requirements' asset, e.g. the gas of settlements the payer submits itself
+type FeeEstimator interface {
+	EstimateFee(requirements *types.PaymentRequirements) (*big.Int, error)
+}
+
+// FeeEstimatorFunc is an adapter to allow the use of ordinary functions as a FeeEstimator
+type FeeEstimatorFunc func(requirements *types.PaymentRequirements) (*big.Int, error)
+
+// EstimateFee calls f(requirements)
+func (f FeeEstimatorFunc) EstimateFee(requirements *types.PaymentRequirements) (*big.Int, error) {
+	return f(requirements)
+}
+
+// CheapestAfterFees selects the requirements with the lowest total cost to the payer: the authorized value,
+// which includes any relayer fee advertised in extra, plus the fee estimated by estimator, if not nil.
+// Estimates are cached per network and asset for FeeEstimateTTL, so paying doesn't query the estimator on
+// every request. Requirements whose fee can't be estimated are skipped, unless none can be.
+// Like Cheapest, costs are compared in atomic units, so it is meant for options denominated in the same asset.
+func CheapestAfterFees(estimator FeeEstimator) PaymentSelector {
+	estimates := &feeEstimates{estimator: estimator, entries: make(map[feeEstimateKey]feeEstimate)}
+
+	return SelectorFunc(func(accepts []types.PaymentRequirements) (*types.PaymentRequirements, error) {
+		var (
+			cheapest *types.PaymentRequirements
+			lowest   *big.Int
+			firstErr error
+		)
+		for i := range accepts {
+			cost, err := exactevm.RequiredValue(&accepts[i])
+			if err == nil {
+				var fee *big.Int
+				if fee, err = estimates.get(&accepts[i]); err == nil {
+					cost.Add(cost, fee)
+				}
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			if lowest == nil || cost.Cmp(lowest) < 0 {
+				cheapest = &accepts[i]
+				lowest = cost
+			}
+		}
+		if cheapest == nil {
+			return nil, firstErr
+		}
+
+		return cheapest, nil
+	})
+}
+
+// feeEstimateKey identifies the fee estimates that can be shared between requirements
+type feeEstimateKey struct {
+	network string
+	asset   string
+}
+
+type feeEstimate struct {
+	fee       *big.Int
+	expiresAt time.Time
+}
+
+// feeEstimates caches the estimates of a FeeEstimator
+type feeEstimates struct {
+	estimator FeeEstimator
+	mu        sync.Mutex
+	entries   map[feeEstimateKey]feeEstimate
+}
+
+// get returns the cached fee estimate for the requirements, estimating it if there is none or it has expired
+func (e *feeEstimates) get(requirements *types.PaymentRequirements) (*big.Int, error) {
+	if e.estimator == nil {
+		return new(big.Int), nil
+	}
+
+	key := feeEstimateKey{network: requirements.Network, asset: strings.ToLower(requirements.Asset)}
+	now := time.Now()
+
+	e.mu.Lock()
+	entry, ok := e.entries[key]
+	e.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.fee, nil
+	}
+
+	fee, err := e.estimator.EstimateFee(requirements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate fee on %s: %w", requirements.Network, err)
+	}
+	if fee == nil || fee.Sign() < 0 {
+		return nil, fmt.Errorf("invalid fee estimate on %s: %v", requirements.Network, fee)
+	}
+
+	e.mu.Lock()
+	e.entries[key] = feeEstimate{fee: new(big.Int).Set(fee), expiresAt: now.Add(FeeEstimateTTL)}
+	e.mu.Unlock()
+
+	return fee, nil
+}
@@ -0,0 +1,84 @@
+package paymentclient
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned when paying would take the spend in an asset past the SpendTracker's budget
+var ErrBudgetExceeded = errors.New("x402 spending budget exceeded")
+
+// SpendTracker accumulates the atomic amounts a PaymentTransport pays, per asset, and can cap them.
+// Budgets are per asset, as atomic units of different tokens can't be added up.
+// A payment counts as spent once it is sent, whether or not the server goes on to settle it.
+// It is safe for concurrent use, so one tracker can be shared by every request through a transport.
+type SpendTracker struct {
+	mu      sync.Mutex
+	spent   map[string]*big.Int
+	budgets map[string]*big.Int
+}
+
+// NewSpendTracker creates a new spend tracker without budgets
+func NewSpendTracker() *SpendTracker {
+	return &SpendTracker{
+		spent:   make(map[string]*big.Int),
+		budgets: make(map[string]*big.Int),
+	}
+}
+
+// SetBudget caps the total atomic amount that may be spent in asset, including what was already spent.
+// A nil budget removes the cap.
+func (s *SpendTracker) SetBudget(asset string, budget *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if budget == nil {
+		delete(s.budgets, assetKey(asset))
+		return
+	}
+	s.budgets[assetKey(asset)] = new(big.Int).Set(budget)
+}
+
+// TotalSpent returns the total atomic amount spent in asset
+func (s *SpendTracker) TotalSpent(asset string) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if spent, ok := s.spent[assetKey(asset)]; ok {
+		return new(big.Int).Set(spent)
+	}
+
+	return new(big.Int)
+}
+
+// reserve records a payment of amount in asset, or returns ErrBudgetExceeded without recording it
+// if it would exceed the asset's budget
+func (s *SpendTracker) reserve(asset string, amount string) error {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid payment amount: %q", amount)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := assetKey(asset)
+	total := new(big.Int).Set(value)
+	if spent, ok := s.spent[key]; ok {
+		total.Add(total, spent)
+	}
+	if budget, ok := s.budgets[key]; ok && total.Cmp(budget) > 0 {
+		return fmt.Errorf("%w: paying %s would bring the spend in %s to %s, above the budget of %s", ErrBudgetExceeded, amount, asset, total, budget)
+	}
+	s.spent[key] = total
+
+	return nil
+}
+
+// assetKey normalizes an asset address for use as a map key
+func assetKey(asset string) string {
+	return strings.ToLower(asset)
+}
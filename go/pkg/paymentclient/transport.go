@@ -0,0 +1,317 @@
+package paymentclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrNoSatisfiablePayment is returned when none of the server's advertised payment requirements can be paid
+var ErrNoSatisfiablePayment = errors.New("no satisfiable payment requirements")
+
+// ErrInvalidChallenge is returned when a transport with a ChallengeKey gets a challenge without a valid token
+// signed by the key
+var ErrInvalidChallenge = errors.New("invalid payment challenge")
+
+// NoMatchingPaymentError is returned when the transport can pay none of the server's advertised requirements.
+// It carries both sides so callers can tell the user what they are missing, e.g. USDC on base,
+// and matches ErrNoSatisfiablePayment with errors.Is.
+type NoMatchingPaymentError struct {
+	// Accepts are the requirements the server advertised
+	Accepts []types.PaymentRequirements
+	// Capabilities are what the transport can pay with: its registered capabilities followed by its Signer,
+	// once per network it is restricted to or with an empty Network if it pays on any supported EVM network
+	Capabilities []Capability
+}
+
+func (e *NoMatchingPaymentError) Error() string {
+	wanted := make([]string, 0, len(e.Accepts))
+	for _, requirements := range e.Accepts {
+		wanted = append(wanted, fmt.Sprintf("%s on %s in %s", requirements.Scheme, requirements.Network, assetName(requirements.Network, requirements.Asset)))
+	}
+
+	available := make([]string, 0, len(e.Capabilities))
+	for _, capability := range e.Capabilities {
+		switch {
+		case capability.Network == "":
+			available = append(available, fmt.Sprintf("%s on any EVM network", exactevm.Scheme))
+		case capability.Asset == "":
+			available = append(available, fmt.Sprintf("%s on %s in any asset", exactevm.Scheme, capability.Network))
+		default:
+			available = append(available, fmt.Sprintf("%s on %s in %s", exactevm.Scheme, capability.Network, assetName(capability.Network, capability.Asset)))
+		}
+	}
+
+	if len(wanted) == 0 {
+		wanted = append(wanted, "nothing")
+	}
+	if len(available) == 0 {
+		available = append(available, "nothing")
+	}
+
+	return fmt.Sprintf("%v: server accepts %s; can pay with %s", ErrNoSatisfiablePayment, strings.Join(wanted, ", "), strings.Join(available, ", "))
+}
+
+func (e *NoMatchingPaymentError) Is(target error) bool {
+	return target == ErrNoSatisfiablePayment
+}
+
+// assetName returns the symbol of a known asset, or else its address
+func assetName(network, address string) string {
+	if asset, ok := types.LookupAsset(network, address); ok {
+		return asset.Symbol
+	}
+
+	return address
+}
+
+// Capability is a network and asset the transport can pay in, and the signer paying for it
+type Capability struct {
+	Network string
+	// Asset is the token contract address. If empty, any asset on the network is accepted.
+	Asset  string
+	Signer exactevm.Signer
+}
+
+// PaymentTransport is an http.RoundTripper that pays for x402 protected resources.
+// When a request is answered with 402 Payment Required, it selects one of the advertised
+// payment requirements, signs a payment for it and retries the request with the X-PAYMENT header.
+type PaymentTransport struct {
+	// Base is the underlying transport. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Signer signs payments on behalf of the payer
+	Signer exactevm.Signer
+	// Networks restricts the networks the signer pays on. If empty, any supported EVM network is used.
+	Networks []string
+	// Capabilities are further networks and assets the transport can pay in, each with its own signer.
+	// They are matched in order before falling back to Signer.
+	Capabilities []Capability
+	// Selector chooses among satisfiable requirements. If nil, First is used.
+	Selector PaymentSelector
+	// MaxValidityWindow caps the validity window of the authorizations the transport signs, e.g. to the limit
+	// the server's facilitator advertises. If zero, exactevm.DefaultMaxValidityWindow is used.
+	MaxValidityWindow time.Duration
+	// ClockSkewMargin widens the validity window of the authorizations the transport signs on both ends,
+	// see exactevm.WithClockSkewMargin
+	ClockSkewMargin time.Duration
+	// ChallengeKey, if set, is the public key of a server signing its challenges, see gin.WithSignedChallenge.
+	// Challenges must then carry a token it signed, and the transport pays for the accepts in the token rather
+	// than those of the response body, so requirements altered in transit can't redirect the payment.
+	// Challenges without a valid token fail with ErrInvalidChallenge.
+	ChallengeKey ed25519.PublicKey
+	// Spend, if set, records the amounts the transport pays, and payments past its budget fail with ErrBudgetExceeded
+	Spend *SpendTracker
+}
+
+// NewPaymentTransport creates a new payment transport paying with the given signer
+func NewPaymentTransport(signer exactevm.Signer) *PaymentTransport {
+	return &PaymentTransport{
+		Signer: signer,
+	}
+}
+
+// AddCapability registers a network and asset the transport can pay in with signer.
+// An empty asset accepts any asset on the network. It must not be called concurrently with RoundTrip.
+func (t *PaymentTransport) AddCapability(network string, asset string, signer exactevm.Signer) {
+	t.Capabilities = append(t.Capabilities, Capability{
+		Network: network,
+		Asset:   asset,
+		Signer:  signer,
+	})
+}
+
+// RoundTrip executes the request, paying for it if the server responds with 402 Payment Required
+func (t *PaymentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Buffer the body so the request can be replayed with the payment header
+	if req.Body != nil && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusPaymentRequired {
+		return resp, err
+	}
+
+	var paymentRequired types.PaymentRequiredResponse
+	err = json.NewDecoder(resp.Body).Decode(&paymentRequired)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payment required response: %w", err)
+	}
+
+	if t.ChallengeKey != nil {
+		claims, err := types.ParseChallengeToken(t.ChallengeKey, resp.Header.Get(types.PaymentChallengeHeader), time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidChallenge, err)
+		}
+		paymentRequired.Accepts = claims.Accepts
+	}
+
+	requirements, signer, err := t.selectRequirements(paymentRequired.Accepts)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := exactevm.CreatePayment(signer, requirements,
+		exactevm.WithMaxValidityWindow(t.MaxValidityWindow), exactevm.WithClockSkewMargin(t.ClockSkewMargin))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	paymentHeader, err := payment.EncodeToBase64String()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Spend != nil {
+		if err := t.Spend.reserve(requirements.Asset, payment.Payload.Authorization.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	paidReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		paidReq.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay request body: %w", err)
+		}
+	}
+	paidReq.Header.Set("X-PAYMENT", paymentHeader)
+	// Servers signing their challenges only accept payments echoing the challenge token, see ChallengeKey
+	if token := resp.Header.Get(types.PaymentChallengeHeader); token != "" {
+		paidReq.Header.Set(types.PaymentChallengeHeader, token)
+	}
+
+	resp, err = t.base().RoundTrip(paidReq)
+	if err != nil || resp.StatusCode != http.StatusPaymentRequired {
+		return resp, err
+	}
+
+	return checkClockSkew(resp)
+}
+
+// checkClockSkew returns an error matching exactevm.ErrPossibleClockSkew if the server refused the payment
+// for its validity window, and the response unchanged otherwise
+func checkClockSkew(resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment required response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var paymentRequired types.PaymentRequiredResponse
+	if json.Unmarshal(body, &paymentRequired) == nil && exactevm.IsValidityWindowReason(paymentRequired.Error) {
+		return nil, fmt.Errorf("payment was rejected as %s: %w", paymentRequired.Error, exactevm.ErrPossibleClockSkew)
+	}
+
+	return resp, nil
+}
+
+// selectRequirements filters the advertised requirements down to the ones the transport can pay,
+// selects one and returns it with the signer paying for it
+func (t *PaymentTransport) selectRequirements(accepts []types.PaymentRequirements) (*types.PaymentRequirements, exactevm.Signer, error) {
+	var candidates []types.PaymentRequirements
+	for _, requirements := range accepts {
+		if t.signerFor(&requirements) != nil {
+			candidates = append(candidates, requirements)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, t.noSatisfiablePaymentError(accepts)
+	}
+
+	selector := t.Selector
+	if selector == nil {
+		selector = First()
+	}
+
+	requirements, err := selector.Select(candidates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select payment requirements: %w", err)
+	}
+	if requirements == nil {
+		return nil, nil, ErrNoSatisfiablePayment
+	}
+
+	signer := t.signerFor(requirements)
+	if signer == nil {
+		return nil, nil, fmt.Errorf("%w: selector chose requirements on %s that cannot be paid", ErrNoSatisfiablePayment, requirements.Network)
+	}
+
+	return requirements, signer, nil
+}
+
+// signerFor returns the signer able to pay the requirements, or nil if the transport cannot pay them
+func (t *PaymentTransport) signerFor(requirements *types.PaymentRequirements) exactevm.Signer {
+	if requirements.Scheme != exactevm.Scheme {
+		return nil
+	}
+	if _, err := types.GetChainID(requirements.Network); err != nil {
+		return nil
+	}
+
+	for _, capability := range t.Capabilities {
+		if capability.Signer == nil || capability.Network != requirements.Network {
+			continue
+		}
+		if capability.Asset == "" || strings.EqualFold(capability.Asset, requirements.Asset) {
+			return capability.Signer
+		}
+	}
+
+	if t.Signer == nil {
+		return nil
+	}
+	if len(t.Networks) > 0 && !slices.Contains(t.Networks, requirements.Network) {
+		return nil
+	}
+
+	return t.Signer
+}
+
+// noSatisfiablePaymentError describes what the server accepts and what the transport can pay with
+func (t *PaymentTransport) noSatisfiablePaymentError(accepts []types.PaymentRequirements) error {
+	capabilities := slices.Clone(t.Capabilities)
+	if t.Signer != nil {
+		if len(t.Networks) == 0 {
+			capabilities = append(capabilities, Capability{Signer: t.Signer})
+		}
+		for _, network := range t.Networks {
+			capabilities = append(capabilities, Capability{Network: network, Signer: t.Signer})
+		}
+	}
+
+	return &NoMatchingPaymentError{
+		Accepts:      accepts,
+		Capabilities: capabilities,
+	}
+}
+
+func (t *PaymentTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+
+	return http.DefaultTransport
+}
@@ -0,0 +1,469 @@
+package paymentclient_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/paymentclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func newTestRequirements(network, amount string) types.PaymentRequirements {
+	extra := json.RawMessage(`{"name":"USDC","version":"2"}`)
+	return types.PaymentRequirements{
+		Scheme:            "exact",
+		Network:           network,
+		MaxAmountRequired: amount,
+		Resource:          "https://example.com/resource",
+		PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+		MaxTimeoutSeconds: 60,
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Extra:             &extra,
+	}
+}
+
+// newPaywalledServer creates a test server that requires payment for every request
+// and records the payment payload it was paid with
+func newPaywalledServer(t *testing.T, accepts []types.PaymentRequirements, paid **types.PaymentPayload) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("X-PAYMENT")
+		if header == "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(map[string]any{
+				"x402Version": 1,
+				"error":       "X-PAYMENT header is required",
+				"accepts":     accepts,
+			})
+			return
+		}
+
+		payload, err := types.DecodePaymentPayloadFromBase64(header)
+		if err != nil {
+			t.Errorf("Failed to decode payment header: %v", err)
+		}
+		*paid = payload
+
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append([]byte("paid:"), body...))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestPaymentTransport(t *testing.T) {
+	var paid *types.PaymentPayload
+	server := newPaywalledServer(t, []types.PaymentRequirements{
+		newTestRequirements("avalanche-fuji", "200"),
+		newTestRequirements("base-sepolia", "100"),
+	}, &paid)
+
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.Selector = paymentclient.PreferNetwork("base-sepolia")
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "paid:hello" {
+		t.Errorf("Expected request body to be replayed, got: %s", body)
+	}
+	if paid == nil || paid.Network != "base-sepolia" {
+		t.Errorf("Expected payment on base-sepolia, got: %+v", paid)
+	}
+}
+
+func TestPaymentTransportSignedChallenge(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	signed := newTestRequirements("base-sepolia", "100")
+	tampered := signed
+	tampered.PayTo = "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+	token, err := types.SignChallengeToken(key, &types.ChallengeClaims{
+		Accepts:   []types.PaymentRequirements{signed},
+		Nonce:     "0x01",
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign challenge: %v", err)
+	}
+
+	var echoed string
+	var paid *types.PaymentPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("X-PAYMENT")
+		if header == "" {
+			if r.URL.Path == "/signed" {
+				w.Header().Set(types.PaymentChallengeHeader, token)
+			}
+			(&types.PaymentRequiredResponse{Accepts: []types.PaymentRequirements{tampered}}).WriteResponse(w)
+			return
+		}
+		echoed = r.Header.Get(types.PaymentChallengeHeader)
+		paid, _ = types.DecodePaymentPayloadFromBase64(header)
+	}))
+	t.Cleanup(server.Close)
+
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.ChallengeKey = key.Public().(ed25519.PublicKey)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL + "/signed")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+	if echoed != token {
+		t.Errorf("Expected the challenge token to be echoed, got: %q", echoed)
+	}
+	if paid == nil || paid.Payload.Authorization.To != signed.PayTo {
+		t.Errorf("Expected payment to the signed payTo %s, got: %+v", signed.PayTo, paid)
+	}
+
+	if _, err := client.Get(server.URL + "/unsigned"); !errors.Is(err, paymentclient.ErrInvalidChallenge) {
+		t.Errorf("Expected ErrInvalidChallenge for a challenge without a token, got: %v", err)
+	}
+}
+
+func TestPaymentTransportClockSkew(t *testing.T) {
+	rejection := exactevm.ReasonExpired
+	var validAfter, validBefore string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reason := "X-PAYMENT header is required"
+		if header := r.Header.Get("X-PAYMENT"); header != "" {
+			payload, err := types.DecodePaymentPayloadFromBase64(header)
+			if err != nil {
+				t.Errorf("Failed to decode payment header: %v", err)
+				return
+			}
+			validAfter, validBefore = payload.Payload.Authorization.ValidAfter, payload.Payload.Authorization.ValidBefore
+			reason = rejection
+		}
+		response := &types.PaymentRequiredResponse{
+			Accepts: []types.PaymentRequirements{newTestRequirements("base-sepolia", "100")},
+			Error:   reason,
+		}
+		response.WriteResponse(w)
+	}))
+	defer server.Close()
+
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.ClockSkewMargin = time.Minute
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, exactevm.ErrPossibleClockSkew) {
+		t.Fatalf("Expected %v, got: %v", exactevm.ErrPossibleClockSkew, err)
+	}
+	if !strings.Contains(err.Error(), "payment was rejected as "+exactevm.ReasonExpired) {
+		t.Errorf("Expected the error to name the rejection, got: %v", err)
+	}
+
+	// The 60 second timeout and backdating are both widened by the margin
+	after, _ := strconv.ParseInt(validAfter, 10, 64)
+	before, _ := strconv.ParseInt(validBefore, 10, 64)
+	if before-after != 240 {
+		t.Errorf("Expected a 240 second window, got: %d", before-after)
+	}
+
+	// Other refusals are returned as responses
+	rejection = exactevm.ReasonInsufficientValue
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402, got: %d", resp.StatusCode)
+	}
+}
+
+func TestPaymentTransportOnlySatisfiable(t *testing.T) {
+	var paid *types.PaymentPayload
+	server := newPaywalledServer(t, []types.PaymentRequirements{
+		newTestRequirements("solana", "1"),
+		newTestRequirements("base-sepolia", "100"),
+		newTestRequirements("avalanche-fuji", "50"),
+	}, &paid)
+
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.Networks = []string{"base-sepolia"}
+	transport.Selector = paymentclient.Cheapest()
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if paid == nil || paid.Network != "base-sepolia" {
+		t.Errorf("Expected payment on base-sepolia, got: %+v", paid)
+	}
+}
+
+func TestPaymentTransportNoSatisfiablePayment(t *testing.T) {
+	var paid *types.PaymentPayload
+	server := newPaywalledServer(t, []types.PaymentRequirements{
+		newTestRequirements("solana", "1"),
+	}, &paid)
+
+	client := &http.Client{Transport: paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, paymentclient.ErrNoSatisfiablePayment) {
+		t.Errorf("Expected ErrNoSatisfiablePayment, got: %v", err)
+	}
+	if paid != nil {
+		t.Errorf("Expected no payment to be made")
+	}
+}
+
+func TestPaymentTransportNoMatchingPaymentError(t *testing.T) {
+	var paid *types.PaymentPayload
+	server := newPaywalledServer(t, []types.PaymentRequirements{
+		newTestRequirements("base-sepolia", "10"),
+	}, &paid)
+
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.Networks = []string{"avalanche"}
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get(server.URL)
+	var noMatching *paymentclient.NoMatchingPaymentError
+	if !errors.As(err, &noMatching) {
+		t.Fatalf("Expected a NoMatchingPaymentError, got: %v", err)
+	}
+	if len(noMatching.Accepts) != 1 || noMatching.Accepts[0].Network != "base-sepolia" {
+		t.Errorf("Expected the server's accepts, got: %+v", noMatching.Accepts)
+	}
+	if len(noMatching.Capabilities) != 1 || noMatching.Capabilities[0].Network != "avalanche" || noMatching.Capabilities[0].Signer != transport.Signer {
+		t.Errorf("Expected the signer's network as a capability, got: %+v", noMatching.Capabilities)
+	}
+	expected := "no satisfiable payment requirements: server accepts exact on base-sepolia in USDC; can pay with exact on avalanche in any asset"
+	if noMatching.Error() != expected {
+		t.Errorf("Expected %q, got: %q", expected, err.Error())
+	}
+}
+
+func TestPaymentTransportCapabilities(t *testing.T) {
+	fujiAsset := "0x5425890298aed601595a70AB815c96711a31Bc65"
+	fuji := newTestRequirements("avalanche-fuji", "50")
+	fuji.Asset = fujiAsset
+
+	var paid *types.PaymentPayload
+	server := newPaywalledServer(t, []types.PaymentRequirements{
+		newTestRequirements("base", "10"),
+		fuji,
+	}, &paid)
+
+	baseSepoliaSigner, fujiSigner := middlewaretest.NewTestSigner(t), middlewaretest.NewTestSigner(t)
+	transport := &paymentclient.PaymentTransport{}
+	transport.AddCapability("base-sepolia", "0x036CbD53842c5426634e7929541eC2318f3dCF7e", baseSepoliaSigner)
+	transport.AddCapability("avalanche-fuji", strings.ToLower(fujiAsset), fujiSigner)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if paid == nil || paid.Network != "avalanche-fuji" {
+		t.Fatalf("Expected payment on avalanche-fuji, got: %+v", paid)
+	}
+	if paid.Payload.Authorization.From != fujiSigner.Address().Hex() {
+		t.Errorf("Expected the avalanche-fuji signer to pay, got: %s", paid.Payload.Authorization.From)
+	}
+}
+
+func TestPaymentTransportNoMatchingCapability(t *testing.T) {
+	var paid *types.PaymentPayload
+	server := newPaywalledServer(t, []types.PaymentRequirements{
+		newTestRequirements("base", "10"),
+	}, &paid)
+
+	transport := &paymentclient.PaymentTransport{}
+	transport.AddCapability("base", "0x0000000000000000000000000000000000000001", middlewaretest.NewTestSigner(t))
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, paymentclient.ErrNoSatisfiablePayment) {
+		t.Fatalf("Expected ErrNoSatisfiablePayment, got: %v", err)
+	}
+	for _, want := range []string{"exact on base in 0x036CbD53842c5426634e7929541eC2318f3dCF7e", "exact on base in 0x0000000000000000000000000000000000000001"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %q, got: %v", want, err)
+		}
+	}
+	if paid != nil {
+		t.Errorf("Expected no payment to be made")
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	accepts := []types.PaymentRequirements{
+		newTestRequirements("base", "300"),
+		newTestRequirements("base-sepolia", "20"),
+		newTestRequirements("avalanche", "100"),
+	}
+
+	selected, err := paymentclient.Cheapest().Select(accepts)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if selected.Network != "base-sepolia" {
+		t.Errorf("Expected cheapest option on base-sepolia, got: %s", selected.Network)
+	}
+}
+
+func TestCheapestAfterFees(t *testing.T) {
+	withFee := newTestRequirements("base-sepolia", "20")
+	extra := json.RawMessage(`{"name":"USDC","version":"2","fee":"500","feeRecipient":"0x1111111111111111111111111111111111111111"}`)
+	withFee.Extra = &extra
+	accepts := []types.PaymentRequirements{
+		newTestRequirements("base", "300"),
+		withFee,
+		newTestRequirements("avalanche", "100"),
+	}
+
+	// The relayer fee makes the lowest amount the most expensive option
+	selected, err := paymentclient.CheapestAfterFees(nil).Select(accepts)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if selected.Network != "avalanche" {
+		t.Errorf("Expected cheapest option on avalanche, got: %s", selected.Network)
+	}
+
+	estimates := map[string]int32{}
+	selector := paymentclient.CheapestAfterFees(paymentclient.FeeEstimatorFunc(func(requirements *types.PaymentRequirements) (*big.Int, error) {
+		estimates[requirements.Network]++
+		switch requirements.Network {
+		case "avalanche":
+			return big.NewInt(1000), nil
+		case "base-sepolia":
+			return nil, errors.New("rpc unavailable")
+		}
+		return big.NewInt(10), nil
+	}))
+	for range 3 {
+		selected, err = selector.Select(accepts)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if selected.Network != "base" {
+			t.Errorf("Expected cheapest option after fees on base, got: %s", selected.Network)
+		}
+	}
+	if estimates["base"] != 1 || estimates["avalanche"] != 1 {
+		t.Errorf("Expected estimates to be cached, got: %v", estimates)
+	}
+	if estimates["base-sepolia"] != 3 {
+		t.Errorf("Expected failed estimates not to be cached, got: %v", estimates)
+	}
+
+	if _, err := selector.Select(accepts[1:2]); err == nil {
+		t.Error("Expected an error when no fee can be estimated")
+	}
+}
+
+func TestPaymentTransportSpendTracker(t *testing.T) {
+	var paid *types.PaymentPayload
+	requirements := newTestRequirements("base-sepolia", "100")
+	server := newPaywalledServer(t, []types.PaymentRequirements{requirements}, &paid)
+
+	spend := paymentclient.NewSpendTracker()
+	spend.SetBudget(strings.ToLower(requirements.Asset), big.NewInt(250))
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.Spend = spend
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if spent := spend.TotalSpent(requirements.Asset); spent.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("Expected 200 spent, got: %s", spent)
+	}
+
+	// A third payment would exceed the budget, so nothing is paid
+	paid = nil
+	if _, err := client.Get(server.URL); !errors.Is(err, paymentclient.ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got: %v", err)
+	}
+	if paid != nil {
+		t.Errorf("Expected no payment past the budget, got: %+v", paid)
+	}
+	if spent := spend.TotalSpent(requirements.Asset); spent.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("Expected the refused payment not to count, got: %s spent", spent)
+	}
+}
+
+func TestSpendTrackerConcurrent(t *testing.T) {
+	requirements := newTestRequirements("base-sepolia", "1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") == "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(map[string]any{"x402Version": 1, "accepts": []types.PaymentRequirements{requirements}})
+		}
+	}))
+	defer server.Close()
+
+	spend := paymentclient.NewSpendTracker()
+	spend.SetBudget(requirements.Asset, big.NewInt(20))
+	transport := paymentclient.NewPaymentTransport(middlewaretest.NewTestSigner(t))
+	transport.Spend = spend
+	client := &http.Client{Transport: transport}
+
+	var wg sync.WaitGroup
+	var exceeded atomic.Int32
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if errors.Is(err, paymentclient.ErrBudgetExceeded) {
+				exceeded.Add(1)
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if spent := spend.TotalSpent(requirements.Asset); spent.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("Expected the budget of 20 to be spent, got: %s", spent)
+	}
+	if exceeded.Load() != 10 {
+		t.Errorf("Expected 10 payments past the budget, got: %d", exceeded.Load())
+	}
+}
@@ -0,0 +1,150 @@
+// Package paymentserver provides the resource server side of the x402 payment flow as plain functions,
+// for frameworks without a middleware adapter such as gRPC-Gateway or custom response writers.
+// Framework adapters like the gin PaymentMiddleware build on the same functions.
+package paymentserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrPaymentRequired is returned by HandlePayment for requests without a decodable X-PAYMENT header
+var ErrPaymentRequired = errors.New("X-PAYMENT header is required")
+
+// Challenge responds with 402 Payment Required, advertising the requirements the request can be paid with
+func Challenge(w http.ResponseWriter, requirements ...*types.PaymentRequirements) {
+	ChallengeWithReason(w, ErrPaymentRequired.Error(), requirements...)
+}
+
+// ChallengeWithReason is Challenge with the reason the payment was refused, e.g. an invalid reason from verification
+func ChallengeWithReason(w http.ResponseWriter, reason string, requirements ...*types.PaymentRequirements) {
+	response, err := challengeResponse(reason, requirements)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteResponse(w)
+}
+
+// ChallengeBody returns the JSON body ChallengeWithReason responds with,
+// for servers that marshal it once and answer repeated challenges with WriteChallenge
+func ChallengeBody(reason string, requirements ...*types.PaymentRequirements) ([]byte, error) {
+	response, err := challengeResponse(reason, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(response)
+}
+
+// challengeResponse builds the 402 Payment Required response advertising the requirements
+func challengeResponse(reason string, requirements []*types.PaymentRequirements) (*types.PaymentRequiredResponse, error) {
+	response := &types.PaymentRequiredResponse{
+		X402Version: types.X402Version,
+		Accepts:     make([]types.PaymentRequirements, 0, len(requirements)),
+		Error:       reason,
+	}
+	for _, req := range requirements {
+		if req == nil {
+			return nil, errors.New("payment requirements are nil")
+		}
+		response.Accepts = append(response.Accepts, *req)
+	}
+
+	return response, nil
+}
+
+// WriteChallenge responds with a body returned by ChallengeBody and statusCode, normally 402 Payment Required
+func WriteChallenge(w http.ResponseWriter, statusCode int, body []byte) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// HandlePayment verifies the payment in the request's X-PAYMENT header against the requirements and,
+// if it is valid, settles it, both with the request's context.
+// It returns ErrPaymentRequired if the request carries no payment, to be answered with Challenge.
+// An invalid payment is returned with an invalid verify response, its Settle is nil and there is no error;
+// a payment signed for another network is rejected the same way without asking the facilitator.
+// A valid payment of a zero amount proves control of the payer's wallet and is not settled.
+// A failed settlement is returned with the unsuccessful settle response and no error.
+// Facilitator errors are returned alongside the outcome so far.
+func HandlePayment(r *http.Request, requirements *types.PaymentRequirements, client *facilitatorclient.FacilitatorClient) (*types.PaymentResult, error) {
+	payload, err := types.DecodePaymentPayloadFromBase64(r.Header.Get("X-PAYMENT"))
+	if errors.Is(err, types.ErrUnsupportedX402Version) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrPaymentRequired
+	}
+
+	result := &types.PaymentResult{
+		Payload:      payload,
+		Requirements: requirements,
+		Payer:        Payer(payload, nil),
+	}
+
+	if err := exactevm.CheckNetwork(payload, requirements); err != nil {
+		reason := err.Error()
+		result.Verify = &types.VerifyResponse{IsValid: false, InvalidReason: &reason}
+		return result, nil
+	}
+
+	start := time.Now()
+	result.Verify, err = client.VerifyWithContext(r.Context(), payload, requirements)
+	result.VerifyDuration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	if !result.Verify.IsValid {
+		return result, nil
+	}
+	result.Payer = Payer(payload, result.Verify)
+
+	if value, err := exactevm.RequiredValue(requirements); err == nil && value.Sign() == 0 {
+		return result, nil
+	}
+
+	start = time.Now()
+	result.Settle, err = client.SettleWithContext(r.Context(), payload, requirements)
+	result.SettleDuration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// SetPaymentResponseHeader sets the X-PAYMENT-RESPONSE header to the result's settle response, if it was settled
+func SetPaymentResponseHeader(header http.Header, result *types.PaymentResult) error {
+	if result == nil || result.Settle == nil {
+		return nil
+	}
+
+	encoded, err := result.Settle.EncodeToBase64String()
+	if err != nil {
+		return err
+	}
+	header.Set("X-PAYMENT-RESPONSE", encoded)
+
+	return nil
+}
+
+// Payer returns the payer reported by the facilitator, if any, falling back to the authorization's from address
+func Payer(payload *types.PaymentPayload, response *types.VerifyResponse) string {
+	if response != nil && response.Payer != nil && *response.Payer != "" {
+		return *response.Payer
+	}
+	if payload.Payload != nil && payload.Payload.Authorization != nil {
+		return payload.Payload.Authorization.From
+	}
+
+	return ""
+}
@@ -0,0 +1,87 @@
+package paymentserver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/facilitatorclient"
+	"github.com/coinbase/x402/go/pkg/middlewaretest"
+	"github.com/coinbase/x402/go/pkg/paymentserver"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestChallenge(t *testing.T) {
+	requirements := middlewaretest.NewTestRequirements()
+	w := httptest.NewRecorder()
+
+	paymentserver.Challenge(w, requirements)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402, got: %d", w.Code)
+	}
+	var body struct {
+		Error       string                       `json:"error"`
+		Accepts     []*types.PaymentRequirements `json:"accepts"`
+		X402Version int                          `json:"x402Version"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to unmarshal challenge: %v", err)
+	}
+	if body.Error != paymentserver.ErrPaymentRequired.Error() || body.X402Version != types.X402Version {
+		t.Errorf("Expected the payment required error and x402 version, got: %+v", body)
+	}
+	if len(body.Accepts) != 1 || body.Accepts[0].PayTo != requirements.PayTo {
+		t.Errorf("Expected the requirements to be advertised, got: %+v", body.Accepts)
+	}
+}
+
+func TestHandlePayment(t *testing.T) {
+	facilitator := middlewaretest.NewMockFacilitator(t)
+	client := facilitatorclient.NewFacilitatorClient(facilitator.Config())
+	requirements := middlewaretest.NewTestRequirements()
+	signer := middlewaretest.NewTestSigner(t)
+
+	req := middlewaretest.NewPaidRequest(t, requirements, signer)
+	result, err := paymentserver.HandlePayment(req, requirements, client)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Verify.IsValid || result.Settle == nil || !result.Settle.Success {
+		t.Fatalf("Expected the payment to be verified and settled, got: %+v", result)
+	}
+	if result.Payer != signer.Address().Hex() {
+		t.Errorf("Expected payer %s, got: %s", signer.Address().Hex(), result.Payer)
+	}
+	middlewaretest.AssertSettled(t, facilitator, middlewaretest.PaymentNonce(t, req))
+
+	header := http.Header{}
+	if err := paymentserver.SetPaymentResponseHeader(header, result); err != nil || header.Get("X-PAYMENT-RESPONSE") == "" {
+		t.Errorf("Expected X-PAYMENT-RESPONSE header to be set, got: %v", err)
+	}
+}
+
+func TestHandlePaymentRejected(t *testing.T) {
+	facilitator := middlewaretest.NewMockFacilitator(t)
+	client := facilitatorclient.NewFacilitatorClient(facilitator.Config())
+	requirements := middlewaretest.NewTestRequirements()
+
+	_, err := paymentserver.HandlePayment(httptest.NewRequest(http.MethodGet, requirements.Resource, nil), requirements, client)
+	if !errors.Is(err, paymentserver.ErrPaymentRequired) {
+		t.Errorf("Expected %v, got: %v", paymentserver.ErrPaymentRequired, err)
+	}
+
+	underpaid := *requirements
+	underpaid.MaxAmountRequired = "1"
+	req := middlewaretest.NewPaidRequest(t, &underpaid, middlewaretest.NewTestSigner(t))
+	result, err := paymentserver.HandlePayment(req, requirements, client)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Verify.IsValid || result.Settle != nil {
+		t.Errorf("Expected the underpayment to be invalid and not settled, got: %+v", result)
+	}
+	middlewaretest.AssertNotSettled(t, facilitator, middlewaretest.PaymentNonce(t, req))
+}
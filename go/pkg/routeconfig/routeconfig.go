@@ -0,0 +1,176 @@
+// Package routeconfig loads route to payment requirements mappings from JSON or YAML,
+// so prices can be managed outside of Go code.
+package routeconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/coinbase/x402/go/pkg/exactevm"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// defaultMaxTimeoutSeconds is used for routes that do not set maxTimeoutSeconds
+const defaultMaxTimeoutSeconds = 60
+
+// RouteConfig maps routes to the payment they require
+type RouteConfig struct {
+	Routes []Route `json:"routes" yaml:"routes"`
+}
+
+// Route describes the payment required for a path. Price is a dollar string such as "$0.01",
+// paid in USDC on Network.
+type Route struct {
+	Path              string           `json:"path" yaml:"path"`
+	Price             string           `json:"price" yaml:"price"`
+	Network           string           `json:"network" yaml:"network"`
+	PayTo             string           `json:"payTo" yaml:"payTo"`
+	Description       string           `json:"description,omitempty" yaml:"description,omitempty"`
+	MimeType          string           `json:"mimeType,omitempty" yaml:"mimeType,omitempty"`
+	MaxTimeoutSeconds int              `json:"maxTimeoutSeconds,omitempty" yaml:"maxTimeoutSeconds,omitempty"`
+	OutputSchema      *json.RawMessage `json:"outputSchema,omitempty" yaml:"-"`
+
+	// Requirements is built from the route when the config is loaded
+	Requirements *types.PaymentRequirements `json:"-" yaml:"-"`
+}
+
+// RouteError reports an invalid route in a route configuration
+type RouteError struct {
+	// Index is the position of the route in the configuration
+	Index int
+	Path  string
+	Err   error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("route %d (%q): %v", e.Index, e.Path, e.Err)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// LoadRouteConfig reads a JSON or YAML route configuration, validates every route and builds its payment requirements.
+// Unknown fields are rejected so typos do not silently drop settings. A *RouteError identifies the offending route.
+func LoadRouteConfig(r io.Reader) (*RouteConfig, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read route config: %w", err)
+	}
+
+	var config RouteConfig
+	if isJSON(data) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("failed to decode route config: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to decode route config: %w", err)
+		}
+	}
+
+	seen := make(map[string]bool, len(config.Routes))
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		if seen[route.Path] {
+			return nil, &RouteError{Index: i, Path: route.Path, Err: fmt.Errorf("duplicate path")}
+		}
+		seen[route.Path] = true
+
+		requirements, err := route.requirements()
+		if err != nil {
+			return nil, &RouteError{Index: i, Path: route.Path, Err: err}
+		}
+		route.Requirements = requirements
+	}
+
+	return &config, nil
+}
+
+// Lookup returns the route configured for path
+func (c *RouteConfig) Lookup(path string) (*Route, bool) {
+	for i := range c.Routes {
+		if c.Routes[i].Path == path {
+			return &c.Routes[i], true
+		}
+	}
+	return nil, false
+}
+
+// requirements validates the route and builds its payment requirements
+func (r *Route) requirements() (*types.PaymentRequirements, error) {
+	if !strings.HasPrefix(r.Path, "/") {
+		return nil, fmt.Errorf("path must start with /")
+	}
+
+	asset, err := types.GetUSDCAsset(r.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAmountRequired, err := ParsePrice(r.Price, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	payTo, err := exactevm.NormalizeAddress(r.PayTo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payTo: %w", err)
+	}
+
+	maxTimeoutSeconds := r.MaxTimeoutSeconds
+	if maxTimeoutSeconds < 0 {
+		return nil, fmt.Errorf("maxTimeoutSeconds must not be negative")
+	}
+	if maxTimeoutSeconds == 0 {
+		maxTimeoutSeconds = defaultMaxTimeoutSeconds
+	}
+
+	requirements := &types.PaymentRequirements{
+		Scheme:            exactevm.Scheme,
+		Network:           r.Network,
+		MaxAmountRequired: maxAmountRequired,
+		Resource:          r.Path,
+		Description:       r.Description,
+		MimeType:          r.MimeType,
+		PayTo:             payTo,
+		MaxTimeoutSeconds: maxTimeoutSeconds,
+		Asset:             asset.Address,
+		OutputSchema:      r.OutputSchema,
+	}
+	if err := requirements.SetExtra(types.ExactEvmExtra{Name: asset.EIP712Name, Version: asset.EIP712Version}); err != nil {
+		return nil, err
+	}
+
+	return requirements, nil
+}
+
+// ParsePrice converts a dollar string such as "$0.01" into atomic units of the asset
+func ParsePrice(price string, asset types.AssetInfo) (string, error) {
+	amount := strings.TrimPrefix(strings.TrimSpace(price), "$")
+	if amount == "" {
+		return "", fmt.Errorf("price is required")
+	}
+
+	atomic, err := types.ParseAmount(amount, asset)
+	if err != nil {
+		return "", fmt.Errorf("invalid price %q: %w", price, err)
+	}
+
+	return atomic, nil
+}
+
+// isJSON reports whether the config starts like a JSON document
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
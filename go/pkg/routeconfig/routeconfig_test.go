@@ -0,0 +1,117 @@
+package routeconfig_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/routeconfig"
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+const testYAML = `
+routes:
+  - path: /weather
+    price: "$0.01"
+    network: base-sepolia
+    payTo: "0x209693bc6afc0c5328ba36faf03c514ef312287c"
+    description: Current weather
+  - path: /premium
+    price: "1.50"
+    network: base
+    payTo: "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"
+    maxTimeoutSeconds: 120
+`
+
+const testJSON = `{
+	"routes": [
+		{"path": "/weather", "price": "$0.01", "network": "base-sepolia", "payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}
+	]
+}`
+
+func TestLoadRouteConfigYAML(t *testing.T) {
+	config, err := routeconfig.LoadRouteConfig(strings.NewReader(testYAML))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(config.Routes) != 2 {
+		t.Fatalf("Expected 2 routes, got: %d", len(config.Routes))
+	}
+
+	route, ok := config.Lookup("/weather")
+	if !ok {
+		t.Fatal("Expected /weather to be configured")
+	}
+	requirements := route.Requirements
+	if requirements.MaxAmountRequired != "10000" {
+		t.Errorf("Expected maxAmountRequired 10000, got: %s", requirements.MaxAmountRequired)
+	}
+	if requirements.Asset != types.USDCAssets[types.NetworkBaseSepolia].Address {
+		t.Errorf("Expected base-sepolia USDC, got: %s", requirements.Asset)
+	}
+	if requirements.PayTo != "0x209693Bc6afc0C5328bA36FaF03C514EF312287C" {
+		t.Errorf("Expected checksummed payTo, got: %s", requirements.PayTo)
+	}
+	if requirements.MaxTimeoutSeconds != 60 || requirements.Description != "Current weather" {
+		t.Errorf("Unexpected requirements: %+v", requirements)
+	}
+
+	var extra types.ExactEvmExtra
+	if _, err := requirements.DecodeExtra(&extra); err != nil || extra.Name != "USDC" {
+		t.Errorf("Expected testnet USDC domain, got: %+v (%v)", extra, err)
+	}
+
+	premium, _ := config.Lookup("/premium")
+	if premium.Requirements.MaxAmountRequired != "1500000" || premium.Requirements.MaxTimeoutSeconds != 120 {
+		t.Errorf("Unexpected premium requirements: %+v", premium.Requirements)
+	}
+
+	if _, ok := config.Lookup("/missing"); ok {
+		t.Error("Expected /missing not to be configured")
+	}
+}
+
+func TestLoadRouteConfigJSON(t *testing.T) {
+	config, err := routeconfig.LoadRouteConfig(strings.NewReader(testJSON))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(config.Routes) != 1 || config.Routes[0].Requirements.MaxAmountRequired != "10000" {
+		t.Errorf("Unexpected routes: %+v", config.Routes)
+	}
+}
+
+func TestLoadRouteConfigErrors(t *testing.T) {
+	route := `{"path": "/ok", "price": "$0.01", "network": "base", "payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}`
+
+	testCases := []struct {
+		name   string
+		config string
+		index  int
+		path   string
+	}{
+		{"invalid price", `{"routes": [` + route + `, {"path": "/bad", "price": "$0.0000001", "network": "base", "payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}]}`, 1, "/bad"},
+		{"unknown network", `{"routes": [{"path": "/bad", "price": "$1", "network": "solana", "payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}]}`, 0, "/bad"},
+		{"invalid payTo", `{"routes": [{"path": "/bad", "price": "$1", "network": "base", "payTo": "merchant"}]}`, 0, "/bad"},
+		{"duplicate path", `{"routes": [` + route + `, ` + route + `]}`, 1, "/ok"},
+		{"relative path", `{"routes": [{"path": "bad", "price": "$1", "network": "base", "payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C"}]}`, 0, "bad"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := routeconfig.LoadRouteConfig(strings.NewReader(tc.config))
+
+			var routeErr *routeconfig.RouteError
+			if !errors.As(err, &routeErr) {
+				t.Fatalf("Expected a RouteError, got: %v", err)
+			}
+			if routeErr.Index != tc.index || routeErr.Path != tc.path {
+				t.Errorf("Expected route %d (%s), got: %d (%s)", tc.index, tc.path, routeErr.Index, routeErr.Path)
+			}
+		})
+	}
+
+	if _, err := routeconfig.LoadRouteConfig(strings.NewReader("routes:\n  - path: /x\n    prize: $1\n")); err == nil {
+		t.Error("Expected error for unknown field, got err == nil")
+	}
+}
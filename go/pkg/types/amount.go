@@ -0,0 +1,94 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrNumericAmount is matched by errors.Is when an amount field is a JSON number instead of a decimal string.
+// Most JSON implementations decode numbers as float64, which silently rounds amounts above 2^53,
+// so amounts are only accepted as strings.
+var ErrNumericAmount = errors.New("amount must be a decimal string, not a JSON number")
+
+// ErrBelowMinAmount is matched by errors.Is when requirements ask for less than the facilitator's minimum amount
+var ErrBelowMinAmount = errors.New("amount is below the facilitator's minimum")
+
+// minDisplayDecimals is the minimum number of fractional digits FormatAmount shows, so "100000" USDC reads "0.10"
+const minDisplayDecimals = 2
+
+// FormatAmount converts an atomic token amount into a human readable string such as "0.10 USDC".
+// The decimal shift is exact for any amount: fractional digits are kept up to the asset's decimals,
+// trailing zeros are trimmed down to two fractional digits, and no floating point is involved.
+func FormatAmount(atomic string, asset AssetInfo) (string, error) {
+	amount, ok := new(big.Int).SetString(atomic, 10)
+	if !ok || amount.Sign() < 0 {
+		return "", fmt.Errorf("invalid atomic amount: %q", atomic)
+	}
+
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(asset.Decimals)), nil)
+	whole, fraction := new(big.Int).QuoRem(amount, unit, new(big.Int))
+
+	formatted := whole.String()
+	if asset.Decimals > 0 {
+		digits := fmt.Sprintf("%0*s", int(asset.Decimals), fraction.String())
+		digits = strings.TrimRight(digits, "0")
+		for len(digits) < minDisplayDecimals && len(digits) < int(asset.Decimals) {
+			digits += "0"
+		}
+		if digits != "" {
+			formatted += "." + digits
+		}
+	}
+
+	if asset.Symbol == "" {
+		return formatted, nil
+	}
+
+	return formatted + " " + asset.Symbol, nil
+}
+
+// ParseAmount converts a decimal amount such as "0.10" into atomic units of the asset.
+// It rejects negative amounts and amounts with more fractional digits than the asset supports.
+func ParseAmount(decimal string, asset AssetInfo) (string, error) {
+	decimal = strings.TrimSpace(decimal)
+	whole, fraction, _ := strings.Cut(decimal, ".")
+	if whole == "" && fraction == "" {
+		return "", fmt.Errorf("invalid amount: %q", decimal)
+	}
+	if strings.ContainsAny(whole, "+-") || strings.ContainsAny(fraction, "+-") {
+		return "", fmt.Errorf("invalid amount: %q", decimal)
+	}
+	if len(fraction) > int(asset.Decimals) {
+		return "", fmt.Errorf("amount %q has more than %d decimals", decimal, asset.Decimals)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", int(asset.Decimals)-len(fraction))
+	amount, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid amount: %q", decimal)
+	}
+
+	return amount.String(), nil
+}
+
+// checkStringAmounts returns ErrNumericAmount if one of the named fields of the JSON object is a number.
+// Anything else is left for the regular decoding to accept or reject.
+func checkStringAmounts(data []byte, fields ...string) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+
+	for _, field := range fields {
+		raw := bytes.TrimSpace(object[field])
+		if len(raw) > 0 && (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9') {
+			return fmt.Errorf("%s: %w", field, ErrNumericAmount)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package types_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestFormatAmount(t *testing.T) {
+	usdc := types.AssetInfo{Symbol: "USDC", Decimals: 6}
+	eth := types.AssetInfo{Symbol: "ETH", Decimals: 18}
+
+	testCases := []struct {
+		atomic   string
+		asset    types.AssetInfo
+		expected string
+	}{
+		{"100000", usdc, "0.10 USDC"},
+		{"1000000", usdc, "1.00 USDC"},
+		{"1234567", usdc, "1.234567 USDC"},
+		{"1", usdc, "0.000001 USDC"},
+		{"0", usdc, "0.00 USDC"},
+		{"1000000000000000000000000000001", eth, "1000000000000.000000000000000001 ETH"},
+		{"42", types.AssetInfo{Decimals: 0}, "42"},
+		{"15", types.AssetInfo{Decimals: 1}, "1.5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			formatted, err := types.FormatAmount(tc.atomic, tc.asset)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if formatted != tc.expected {
+				t.Errorf("Expected %q, got: %q", tc.expected, formatted)
+			}
+		})
+	}
+}
+
+func TestFormatAmountInvalid(t *testing.T) {
+	for _, atomic := range []string{"", "-1", "1.5", "abc"} {
+		if _, err := types.FormatAmount(atomic, types.AssetInfo{Decimals: 6}); err == nil {
+			t.Errorf("Expected error for %q, got err == nil", atomic)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	usdc := types.AssetInfo{Symbol: "USDC", Decimals: 6}
+
+	testCases := []struct {
+		decimal  string
+		expected string
+	}{
+		{"0.10", "100000"},
+		{"1", "1000000"},
+		{"1.234567", "1234567"},
+		{".5", "500000"},
+		{"2.", "2000000"},
+		{"0", "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.decimal, func(t *testing.T) {
+			atomic, err := types.ParseAmount(tc.decimal, usdc)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if atomic != tc.expected {
+				t.Errorf("Expected %q, got: %q", tc.expected, atomic)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", ".", "-1", "+1", "1.2345678", "abc", "1e6", "1.0.0"} {
+		if _, err := types.ParseAmount(invalid, usdc); err == nil {
+			t.Errorf("Expected error for %q, got err == nil", invalid)
+		}
+	}
+}
+
+func TestAmountsDecodeWithoutPrecisionLoss(t *testing.T) {
+	// 2^53 + 1 is the smallest integer float64 can't represent, here scaled to 18 decimals and beyond
+	amounts := []string{"9007199254740993", "9007199254740993000000000000000001", "115792089237316195423570985008687907853269984665640564039457584007913129639935"}
+
+	for _, amount := range amounts {
+		t.Run(amount, func(t *testing.T) {
+			var requirements types.PaymentRequirements
+			if err := json.Unmarshal([]byte(`{"maxAmountRequired":"`+amount+`","extra":{"fee":"`+amount+`"}}`), &requirements); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if requirements.MaxAmountRequired != amount {
+				t.Errorf("Expected max amount %s, got: %s", amount, requirements.MaxAmountRequired)
+			}
+			var extra types.ExactEvmExtra
+			if _, err := requirements.DecodeExtra(&extra); err != nil || extra.Fee != amount {
+				t.Errorf("Expected fee %s, got: %s (%v)", amount, extra.Fee, err)
+			}
+
+			var authorization types.ExactEvmPayloadAuthorization
+			if err := json.Unmarshal([]byte(`{"value":"`+amount+`"}`), &authorization); err != nil || authorization.Value != amount {
+				t.Errorf("Expected value %s, got: %s (%v)", amount, authorization.Value, err)
+			}
+		})
+	}
+}
+
+func TestNumericAmountsAreRejected(t *testing.T) {
+	testCases := map[string]struct {
+		json string
+		v    any
+	}{
+		"max amount required": {`{"maxAmountRequired": 9007199254740993}`, &types.PaymentRequirements{}},
+		"fractional amount":   {`{"maxAmountRequired": 1.5}`, &types.PaymentRequirements{}},
+		"fee":                 {`{"name":"USDC","fee": 1e18}`, &types.ExactEvmExtra{}},
+		"value":               {`{"from":"0x1","value": 1000000000000000000}`, &types.ExactEvmPayloadAuthorization{}},
+		"nested value":        {`{"payload":{"authorization":{"value": -1}}}`, &types.PaymentPayload{}},
+		"payer balance":       {`{"isValid":false,"payerBalance": 10}`, &types.VerifyResponse{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tc.json), tc.v); !errors.Is(err, types.ErrNumericAmount) {
+				t.Errorf("Expected %v, got: %v", types.ErrNumericAmount, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AssetInfo describes a token that x402 payments can be made in.
+// EIP712Name and EIP712Version are the token's EIP-712 domain, which differs between deployments:
+// the same USDC contract reports "USDC" on some chains and "USD Coin" on others, and bridged variants use version "1".
+type AssetInfo struct {
+	Address       string
+	Symbol        string
+	Decimals      uint8
+	EIP712Name    string
+	EIP712Version string
+}
+
+// USDCAssets maps x402 network names to the USDC token deployed on that network
+var USDCAssets = map[string]AssetInfo{
+	NetworkBaseSepolia: {
+		Address:       "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Symbol:        "USDC",
+		Decimals:      6,
+		EIP712Name:    "USDC",
+		EIP712Version: "2",
+	},
+	NetworkBase: {
+		Address:       "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Symbol:        "USDC",
+		Decimals:      6,
+		EIP712Name:    "USD Coin",
+		EIP712Version: "2",
+	},
+	NetworkAvalancheFuji: {
+		Address:       "0x5425890298aed601595a70AB815c96711a31Bc65",
+		Symbol:        "USDC",
+		Decimals:      6,
+		EIP712Name:    "USD Coin",
+		EIP712Version: "2",
+	},
+	NetworkAvalanche: {
+		Address:       "0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E",
+		Symbol:        "USDC",
+		Decimals:      6,
+		EIP712Name:    "USD Coin",
+		EIP712Version: "2",
+	},
+	NetworkPolygonAmoy: {
+		Address:       "0x41E94Eb019C0762f9Bfcf9Fb1E58725BfB0e7582",
+		Symbol:        "USDC",
+		Decimals:      6,
+		EIP712Name:    "USDC",
+		EIP712Version: "2",
+	},
+	NetworkPolygon: {
+		Address:       "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359",
+		Symbol:        "USDC",
+		Decimals:      6,
+		EIP712Name:    "USD Coin",
+		EIP712Version: "2",
+	},
+}
+
+// GetUSDCAsset returns the USDC token information for the given x402 network name
+func GetUSDCAsset(network string) (AssetInfo, error) {
+	asset, ok := USDCAssets[network]
+	if !ok {
+		return AssetInfo{}, fmt.Errorf("no USDC asset known for network: %s", network)
+	}
+
+	return asset, nil
+}
+
+// LookupAsset returns the known token deployed at address on the given x402 network
+func LookupAsset(network, address string) (AssetInfo, bool) {
+	asset, ok := USDCAssets[network]
+	if !ok || !strings.EqualFold(asset.Address, address) {
+		return AssetInfo{}, false
+	}
+
+	return asset, true
+}
@@ -0,0 +1,134 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Chain families returned by AuthorizationData.ChainFamily
+const (
+	ChainFamilyEVM = "evm"
+	ChainFamilySVM = "svm"
+)
+
+// AuthorizationData is the scheme-specific part of a payment payload returned by PaymentPayload.Decode.
+// Type-assert it to the concrete payload, e.g. *ExactEvmPayload or *ExactSvmPayload.
+type AuthorizationData interface {
+	// Scheme returns the payment scheme of the payload, e.g. "exact"
+	Scheme() string
+	// Network returns the network of the payment the payload was decoded from
+	Network() string
+	// ChainFamily returns the family of chains the payload is for, e.g. ChainFamilyEVM
+	ChainFamily() string
+}
+
+// Scheme returns "exact"
+func (p *ExactEvmPayload) Scheme() string {
+	return "exact"
+}
+
+// Network returns the network of the payment the payload was decoded from by PaymentPayload.Decode,
+// or "" for a payload that wasn't
+func (p *ExactEvmPayload) Network() string {
+	return p.network
+}
+
+// ChainFamily returns ChainFamilyEVM
+func (p *ExactEvmPayload) ChainFamily() string {
+	return ChainFamilyEVM
+}
+
+// ExactSvmPayload represents the payload for an exact Solana payment: a base64 encoded,
+// partially signed transaction for the facilitator to complete and submit
+type ExactSvmPayload struct {
+	Transaction string `json:"transaction"`
+
+	// network is set by PaymentPayload.Decode, see Network
+	network string
+}
+
+// Scheme returns "exact"
+func (p *ExactSvmPayload) Scheme() string {
+	return "exact"
+}
+
+// Network returns the network of the payment the payload was decoded from by PaymentPayload.Decode,
+// or "" for a payload that wasn't
+func (p *ExactSvmPayload) Network() string {
+	return p.network
+}
+
+// ChainFamily returns ChainFamilySVM
+func (p *ExactSvmPayload) ChainFamily() string {
+	return ChainFamilySVM
+}
+
+// Decode returns a copy of the scheme-specific payload for the payment's network, whose Scheme and Network
+// methods report the payment's scheme and network to generic code holding only the AuthorizationData
+func (p *PaymentPayload) Decode() (AuthorizationData, error) {
+	if p.Scheme != "exact" {
+		return nil, fmt.Errorf("unsupported scheme: %s", p.Scheme)
+	}
+
+	if IsSvmNetwork(p.Network) {
+		if len(p.rawPayload) == 0 {
+			return nil, fmt.Errorf("payment payload is missing its payload")
+		}
+
+		var payload ExactSvmPayload
+		if err := json.Unmarshal([]byte(p.rawPayload), &payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal svm payload: %w", err)
+		}
+		payload.network = p.Network
+		return &payload, nil
+	}
+
+	if _, err := GetChainID(p.Network); err != nil {
+		return nil, err
+	}
+	if p.Payload == nil {
+		return nil, fmt.Errorf("payment payload is missing its payload")
+	}
+
+	payload := *p.Payload
+	payload.network = p.Network
+	return &payload, nil
+}
+
+// paymentPayloadJSON has the fields of PaymentPayload without its JSON methods
+type paymentPayloadJSON PaymentPayload
+
+// UnmarshalJSON decodes the payload as ExactEvmPayload for EVM networks and keeps it raw for Solana networks
+func (p *PaymentPayload) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		paymentPayloadJSON
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*p = PaymentPayload(decoded.paymentPayloadJSON)
+	if len(decoded.Payload) == 0 || string(decoded.Payload) == "null" {
+		return nil
+	}
+
+	if IsSvmNetwork(p.Network) {
+		p.rawPayload = string(decoded.Payload)
+		return nil
+	}
+
+	return json.Unmarshal(decoded.Payload, &p.Payload)
+}
+
+// MarshalJSON encodes the payment payload, including a raw payload kept for Solana networks
+func (p PaymentPayload) MarshalJSON() ([]byte, error) {
+	if p.Payload != nil || len(p.rawPayload) == 0 {
+		return json.Marshal(paymentPayloadJSON(p))
+	}
+
+	return json.Marshal(struct {
+		paymentPayloadJSON
+		Payload json.RawMessage `json:"payload"`
+	}{paymentPayloadJSON(p), json.RawMessage(p.rawPayload)})
+}
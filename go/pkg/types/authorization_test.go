@@ -0,0 +1,110 @@
+package types_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestDecodeEvmPayload(t *testing.T) {
+	data := `{"x402Version":1,"scheme":"exact","network":"base-sepolia","payload":{"signature":"0xsig","authorization":{"from":"0xfrom","to":"0xto","value":"1","validAfter":"0","validBefore":"1","nonce":"0xnonce"}}}`
+
+	var payload types.PaymentPayload
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.Payload == nil || payload.Payload.Signature != "0xsig" {
+		t.Fatalf("Expected the EVM payload to be decoded into Payload, got: %+v", payload.Payload)
+	}
+
+	decoded, err := payload.Decode()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if decoded.ChainFamily() != types.ChainFamilyEVM {
+		t.Errorf("Expected chain family %s, got: %s", types.ChainFamilyEVM, decoded.ChainFamily())
+	}
+	if decoded.Scheme() != "exact" || decoded.Network() != "base-sepolia" {
+		t.Errorf("Expected exact on base-sepolia, got: %s on %s", decoded.Scheme(), decoded.Network())
+	}
+	evmPayload, ok := decoded.(*types.ExactEvmPayload)
+	if !ok || evmPayload.Authorization.From != "0xfrom" {
+		t.Errorf("Expected an ExactEvmPayload from 0xfrom, got: %#v", decoded)
+	}
+
+	encoded, err := json.Marshal(&payload)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if string(encoded) != data {
+		t.Errorf("Expected EVM payload to round-trip, got: %s", encoded)
+	}
+}
+
+func TestDecodeSvmPayload(t *testing.T) {
+	data := `{"x402Version":1,"scheme":"exact","network":"solana-devnet","payload":{"transaction":"AQID"}}`
+
+	var payload types.PaymentPayload
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload.Payload != nil {
+		t.Errorf("Expected no EVM payload for a Solana network, got: %+v", payload.Payload)
+	}
+
+	decoded, err := payload.Decode()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	svmPayload, ok := decoded.(*types.ExactSvmPayload)
+	if !ok || svmPayload.Transaction != "AQID" || decoded.ChainFamily() != types.ChainFamilySVM {
+		t.Errorf("Expected an ExactSvmPayload with transaction AQID, got: %#v", decoded)
+	}
+	if decoded.Scheme() != "exact" || decoded.Network() != "solana-devnet" {
+		t.Errorf("Expected exact on solana-devnet, got: %s on %s", decoded.Scheme(), decoded.Network())
+	}
+
+	// The raw payload survives re-encoding, e.g. when forwarded to the facilitator
+	encoded, err := payload.EncodeToBase64String()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	roundTripped, err := types.DecodePaymentPayloadFromBase64(encoded)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	decoded, err = roundTripped.Decode()
+	if err != nil || decoded.(*types.ExactSvmPayload).Transaction != "AQID" {
+		t.Errorf("Expected the SVM payload to round-trip, got: %#v (%v)", decoded, err)
+	}
+
+	// PaymentPayload stays comparable
+	if *roundTripped != payload {
+		t.Errorf("Expected the round-tripped payload to equal the original, got: %+v", roundTripped)
+	}
+}
+
+func TestDecodeUnsupportedPayload(t *testing.T) {
+	testCases := []string{
+		`{"x402Version":1,"scheme":"upto","network":"base","payload":{}}`,
+		`{"x402Version":1,"scheme":"exact","network":"unknown","payload":{}}`,
+		`{"x402Version":1,"scheme":"exact","network":"solana"}`,
+	}
+
+	for _, data := range testCases {
+		var payload types.PaymentPayload
+		if err := json.Unmarshal([]byte(data), &payload); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := payload.Decode(); err == nil {
+			t.Errorf("Expected error decoding %s, got err == nil", data)
+		}
+	}
+
+	var payload types.PaymentPayload
+	if err := json.Unmarshal([]byte(`{"scheme":"exact","network":"base","payload":"oops"}`), &payload); err == nil || !strings.Contains(err.Error(), "ExactEvmPayload") {
+		t.Errorf("Expected a type error for a malformed EVM payload, got: %v", err)
+	}
+}
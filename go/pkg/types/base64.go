@@ -0,0 +1,34 @@
+package types
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// headerEncodings are the base64 variants accepted in payment headers, tried in order.
+// The spec uses standard padded base64, but some implementations send URL-safe or unpadded values.
+var headerEncodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// decodeBase64 decodes a payment header value in any of the headerEncodings.
+// It returns the standard encoding's error if no variant matches.
+func decodeBase64(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	var firstErr error
+	for _, encoding := range headerEncodings {
+		decoded, err := encoding.DecodeString(encoded)
+		if err == nil {
+			return decoded, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, firstErr
+}
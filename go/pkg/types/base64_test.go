@@ -0,0 +1,98 @@
+package types_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestDecodePaymentPayloadBase64Variants(t *testing.T) {
+	payload := &types.PaymentPayload{
+		X402Version: types.X402Version,
+		Scheme:      "exact",
+		Network:     types.NetworkBase,
+		// Characters chosen so the encodings differ between the standard and URL-safe alphabets
+		Payload: &types.ExactEvmPayload{Signature: "0x~~~???>>>"},
+	}
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	variants := map[string]*base64.Encoding{
+		"standard padded":   base64.StdEncoding,
+		"standard unpadded": base64.RawStdEncoding,
+		"url-safe padded":   base64.URLEncoding,
+		"url-safe unpadded": base64.RawURLEncoding,
+	}
+	for name, encoding := range variants {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := types.DecodePaymentPayloadFromBase64(encoding.EncodeToString(jsonBytes))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if decoded.Payload.Signature != payload.Payload.Signature {
+				t.Errorf("Expected signature %s, got: %s", payload.Payload.Signature, decoded.Payload.Signature)
+			}
+		})
+	}
+
+	standard := base64.StdEncoding.EncodeToString(jsonBytes)
+	urlSafe := base64.RawURLEncoding.EncodeToString(jsonBytes)
+	if !strings.ContainsAny(standard, "+/") || !strings.ContainsAny(urlSafe, "-_") {
+		t.Fatalf("Expected the test payload to exercise both alphabets")
+	}
+}
+
+func TestEncodePaymentPayloadBase64Variant(t *testing.T) {
+	payload := &types.PaymentPayload{Scheme: "exact", Network: types.NetworkBase}
+
+	canonical, err := payload.EncodeToBase64String()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := base64.StdEncoding.DecodeString(canonical); err != nil {
+		t.Errorf("Expected standard padded base64 by default, got: %s", canonical)
+	}
+
+	urlSafe, err := payload.EncodeToBase64StringWith(base64.RawURLEncoding)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.ContainsAny(urlSafe, "+/=") {
+		t.Errorf("Expected unpadded URL-safe base64, got: %s", urlSafe)
+	}
+	if _, err := types.DecodePaymentPayloadFromBase64(urlSafe); err != nil {
+		t.Errorf("Expected the URL-safe encoding to decode, got: %v", err)
+	}
+}
+
+func TestDecodePaymentPayloadInvalidBase64(t *testing.T) {
+	if _, err := types.DecodePaymentPayloadFromBase64("not base64!"); err == nil {
+		t.Error("Expected error for invalid base64, got err == nil")
+	}
+}
+
+func TestIsDeprecatedX402Version(t *testing.T) {
+	if types.IsDeprecatedX402Version(types.X402Version) {
+		t.Errorf("Expected version %d not to be deprecated", types.X402Version)
+	}
+	if types.IsDeprecatedX402Version(types.MinX402Version - 1) {
+		t.Errorf("Expected version %d to be unsupported rather than deprecated", types.MinX402Version-1)
+	}
+
+	for _, version := range []int{types.MinX402Version - 1, types.X402Version + 1} {
+		// EncodeToBase64String would replace a zero version with the current one
+		payload, err := json.Marshal(&types.PaymentPayload{X402Version: version, Scheme: "exact"})
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		if _, err := types.DecodePaymentPayloadFromBase64(base64.StdEncoding.EncodeToString(payload)); !errors.Is(err, types.ErrUnsupportedX402Version) {
+			t.Errorf("Expected version %d to be unsupported, got: %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PaymentChallengeHeader carries a server-signed token binding a 402 challenge to its requirements.
+// Clients echo it unchanged on the request paying for the challenge.
+const PaymentChallengeHeader = "X-PAYMENT-CHALLENGE"
+
+// ErrInvalidChallengeToken is returned by ParseChallengeToken for tokens that are malformed, not signed
+// by the expected key or expired
+var ErrInvalidChallengeToken = errors.New("invalid challenge token")
+
+// challengeTokenHeader is the encoded JOSE header of every challenge token, compared verbatim so tokens can't
+// select another algorithm
+var challengeTokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`))
+
+// ChallengeClaims are the claims of a challenge token: the requirements a server challenged with, and the
+// authorization nonce payments answering the challenge must carry, also set as challengeNonce in the extra
+// of every accept
+type ChallengeClaims struct {
+	Accepts   []PaymentRequirements `json:"accepts"`
+	Nonce     string                `json:"nonce"`
+	IssuedAt  int64                 `json:"iat"`
+	ExpiresAt int64                 `json:"exp"`
+}
+
+// SignChallengeToken returns the claims as a JWT signed with key by EdDSA, for the PaymentChallengeHeader header
+func SignChallengeToken(key ed25519.PrivateKey, claims *ChallengeClaims) (string, error) {
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal challenge claims: %w", err)
+	}
+
+	signingInput := challengeTokenHeader + "." + base64.RawURLEncoding.EncodeToString(encoded)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(signingInput))), nil
+}
+
+// ParseChallengeToken returns the claims of a token signed with SignChallengeToken by the private key of key.
+// It returns an error wrapping ErrInvalidChallengeToken if the token is malformed, its signature doesn't verify
+// or it has expired by now.
+func ParseChallengeToken(key ed25519.PublicKey, token string, now time.Time) (*ChallengeClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != challengeTokenHeader {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidChallengeToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, fmt.Errorf("%w: signature does not verify", ErrInvalidChallengeToken)
+	}
+
+	encoded, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidChallengeToken)
+	}
+	var claims ChallengeClaims
+	if err := json.Unmarshal(encoded, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidChallengeToken)
+	}
+	if !now.Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidChallengeToken)
+	}
+
+	return &claims, nil
+}
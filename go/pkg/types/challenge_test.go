@@ -0,0 +1,59 @@
+package types_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestChallengeToken(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	publicKey := key.Public().(ed25519.PublicKey)
+	now := time.Unix(1745323800, 0)
+	claims := &types.ChallengeClaims{
+		Accepts:   []types.PaymentRequirements{{Scheme: "exact", Network: types.NetworkBaseSepolia, MaxAmountRequired: "1000"}},
+		Nonce:     "0x01",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	}
+
+	token, err := types.SignChallengeToken(key, claims)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	parsed, err := types.ParseChallengeToken(publicKey, token, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if parsed.Nonce != claims.Nonce || len(parsed.Accepts) != 1 || parsed.Accepts[0].MaxAmountRequired != "1000" {
+		t.Errorf("Expected the claims to round-trip, got: %+v", parsed)
+	}
+
+	otherKey := ed25519.NewKeyFromSeed([]byte(strings.Repeat("k", ed25519.SeedSize)))
+	parts := strings.Split(token, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."
+	testCases := []struct {
+		name  string
+		key   ed25519.PublicKey
+		token string
+		now   time.Time
+	}{
+		{name: "expired", key: publicKey, token: token, now: now.Add(time.Minute)},
+		{name: "other key", key: otherKey.Public().(ed25519.PublicKey), token: token, now: now},
+		{name: "unsigned", key: publicKey, token: unsigned, now: now},
+		{name: "malformed", key: publicKey, token: "token", now: now},
+		{name: "missing", key: publicKey, token: "", now: now},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := types.ParseChallengeToken(tc.key, tc.token, tc.now); !errors.Is(err, types.ErrInvalidChallengeToken) {
+				t.Errorf("Expected ErrInvalidChallengeToken, got: %v", err)
+			}
+		})
+	}
+}
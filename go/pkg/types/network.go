@@ -0,0 +1,44 @@
+package types
+
+import "fmt"
+
+// Networks supported by the x402 exact EVM scheme
+const (
+	NetworkBase          = "base"
+	NetworkBaseSepolia   = "base-sepolia"
+	NetworkAvalanche     = "avalanche"
+	NetworkAvalancheFuji = "avalanche-fuji"
+	NetworkPolygon       = "polygon"
+	NetworkPolygonAmoy   = "polygon-amoy"
+)
+
+// Solana networks, whose payloads are decoded as ExactSvmPayload
+const (
+	NetworkSolana       = "solana"
+	NetworkSolanaDevnet = "solana-devnet"
+)
+
+// IsSvmNetwork reports whether the x402 network name refers to a Solana network
+func IsSvmNetwork(network string) bool {
+	return network == NetworkSolana || network == NetworkSolanaDevnet
+}
+
+// EvmNetworkToChainID maps x402 network names to EVM chain IDs
+var EvmNetworkToChainID = map[string]int64{
+	NetworkBaseSepolia:   84532,
+	NetworkBase:          8453,
+	NetworkAvalancheFuji: 43113,
+	NetworkAvalanche:     43114,
+	NetworkPolygonAmoy:   80002,
+	NetworkPolygon:       137,
+}
+
+// GetChainID returns the EVM chain ID for the given x402 network name
+func GetChainID(network string) (int64, error) {
+	chainID, ok := EvmNetworkToChainID[network]
+	if !ok {
+		return 0, fmt.Errorf("unsupported network: %s", network)
+	}
+
+	return chainID, nil
+}
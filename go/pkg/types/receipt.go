@@ -0,0 +1,49 @@
+package types
+
+import "time"
+
+// ExplorerURLs maps x402 network names to the base URL of their block explorer
+var ExplorerURLs = map[string]string{
+	NetworkBase:          "https://basescan.org",
+	NetworkBaseSepolia:   "https://sepolia.basescan.org",
+	NetworkAvalanche:     "https://snowtrace.io",
+	NetworkAvalancheFuji: "https://testnet.snowtrace.io",
+	NetworkPolygon:       "https://polygonscan.com",
+	NetworkPolygonAmoy:   "https://amoy.polygonscan.com",
+}
+
+// Receipt represents a settled payment, for showing to the payer
+type Receipt struct {
+	Network     string
+	Transaction string
+	Payer       string
+	// BlockNumber and SettledAt locate the settlement on chain, and are zero if the facilitator didn't report them
+	BlockNumber uint64
+	SettledAt   time.Time
+}
+
+// NewReceipt creates a receipt from a successful settle response
+func NewReceipt(settle *SettleResponse) *Receipt {
+	receipt := &Receipt{
+		Network:     settle.Network,
+		Transaction: settle.Transaction,
+		BlockNumber: settle.SettledBlock(),
+		SettledAt:   settle.SettledAt(),
+	}
+	if settle.Payer != nil {
+		receipt.Payer = *settle.Payer
+	}
+
+	return receipt
+}
+
+// ExplorerURL returns a block explorer link to the settlement transaction,
+// or an empty string if the network has no known explorer or there is no transaction
+func (r *Receipt) ExplorerURL() string {
+	baseURL, ok := ExplorerURLs[r.Network]
+	if !ok || r.Transaction == "" {
+		return ""
+	}
+
+	return baseURL + "/tx/" + r.Transaction
+}
@@ -0,0 +1,110 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestReceiptExplorerURL(t *testing.T) {
+	payer := "0x857b06519E91e3A54538791bDbb0E22373e36b66"
+
+	testCases := []struct {
+		name     string
+		settle   types.SettleResponse
+		expected string
+	}{
+		{
+			name:     "base",
+			settle:   types.SettleResponse{Success: true, Network: "base", Transaction: "0xabc", Payer: &payer},
+			expected: "https://basescan.org/tx/0xabc",
+		},
+		{
+			name:     "base-sepolia",
+			settle:   types.SettleResponse{Success: true, Network: "base-sepolia", Transaction: "0xabc"},
+			expected: "https://sepolia.basescan.org/tx/0xabc",
+		},
+		{
+			name:     "polygon",
+			settle:   types.SettleResponse{Success: true, Network: "polygon", Transaction: "0xabc"},
+			expected: "https://polygonscan.com/tx/0xabc",
+		},
+		{
+			name:     "polygon-amoy",
+			settle:   types.SettleResponse{Success: true, Network: "polygon-amoy", Transaction: "0xabc"},
+			expected: "https://amoy.polygonscan.com/tx/0xabc",
+		},
+		{
+			name:     "unknown network",
+			settle:   types.SettleResponse{Success: true, Network: "unknown", Transaction: "0xabc"},
+			expected: "",
+		},
+		{
+			name:     "no transaction",
+			settle:   types.SettleResponse{Success: false, Network: "base"},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			receipt := types.NewReceipt(&tc.settle)
+			if url := receipt.ExplorerURL(); url != tc.expected {
+				t.Errorf("Expected %q, got: %q", tc.expected, url)
+			}
+		})
+	}
+
+	receipt := types.NewReceipt(&testCases[0].settle)
+	if receipt.Payer != payer {
+		t.Errorf("Expected payer %s, got: %s", payer, receipt.Payer)
+	}
+}
+
+// TestExplorerURLsCoverNetworks checks that every EVM network has a block explorer for receipts
+func TestExplorerURLsCoverNetworks(t *testing.T) {
+	for network := range types.EvmNetworkToChainID {
+		if _, ok := types.ExplorerURLs[network]; !ok {
+			t.Errorf("Expected an explorer URL for %s", network)
+		}
+	}
+}
+
+func TestSettleResponseBlock(t *testing.T) {
+	settledAt := time.Unix(1745323800, 0).UTC()
+
+	testCases := []struct {
+		name  string
+		json  string
+		block uint64
+		at    time.Time
+	}{
+		{"numbers", `{"blockNumber": 28514152, "blockTimestamp": 1745323800}`, 28514152, settledAt},
+		{"decimal strings", `{"blockNumber": "28514152", "blockTimestamp": "1745323800"}`, 28514152, settledAt},
+		{"hex block number", `{"blockNumber": "0x1b31768", "blockTimestamp": "2025-04-22T12:10:00Z"}`, 28514152, settledAt},
+		{"absent", `{}`, 0, time.Time{}},
+		{"malformed", `{"blockNumber": "latest", "blockTimestamp": -1}`, 0, time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var settle types.SettleResponse
+			if err := json.Unmarshal([]byte(tc.json), &settle); err != nil {
+				t.Fatalf("Failed to unmarshal settle response: %v", err)
+			}
+			if block := settle.SettledBlock(); block != tc.block {
+				t.Errorf("Expected block %d, got: %d", tc.block, block)
+			}
+			if at := settle.SettledAt(); !at.Equal(tc.at) {
+				t.Errorf("Expected timestamp %s, got: %s", tc.at, at)
+			}
+
+			receipt := types.NewReceipt(&settle)
+			if receipt.BlockNumber != tc.block || !receipt.SettledAt.Equal(tc.at) {
+				t.Errorf("Expected the receipt to carry block %d at %s, got: %d at %s", tc.block, tc.at, receipt.BlockNumber, receipt.SettledAt)
+			}
+		})
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,18 +27,101 @@ type PaymentRequirements struct {
 	Extra             *json.RawMessage `json:"extra,omitempty"`
 }
 
-// PaymentPayload represents the decoded payment payload for a client's payment
+// paymentRequirementsJSON has the fields of PaymentRequirements without its JSON methods
+type paymentRequirementsJSON PaymentRequirements
+
+// UnmarshalJSON decodes the requirements, rejecting a maxAmountRequired that isn't a string (see ErrNumericAmount)
+func (p *PaymentRequirements) UnmarshalJSON(data []byte) error {
+	if err := checkStringAmounts(data, "maxAmountRequired"); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, (*paymentRequirementsJSON)(p))
+}
+
+// ExactEvmExtra represents the extra information carried in PaymentRequirements for the exact EVM scheme.
+// Name and Version are the EIP-712 domain parameters of the asset contract.
+// VerifyingContract, when set, completes an explicit domain that is used verbatim instead of the
+// registered domain of the asset, e.g. for bridged or wrapped tokens whose domain differs from native USDC.
+// It must be the asset address.
+//
+// Gasless flows may advertise a relayer fee: Fee is an amount in atomic units that the
+// authorized value must cover on top of MaxAmountRequired, and FeeRecipient is the relayer
+// address the facilitator forwards the fee to.
+//
+// SettlementRecipient, when set, is the address the authorization must transfer to instead of
+// PayTo, e.g. a split contract or escrow in marketplace setups. PayTo stays the quoted merchant.
+//
+// ChallengeNonce, when set, is the authorization nonce the server expects, binding the payment to its challenge.
+type ExactEvmExtra struct {
+	Name                string `json:"name"`
+	Version             string `json:"version"`
+	VerifyingContract   string `json:"verifyingContract,omitempty"`
+	Fee                 string `json:"fee,omitempty"`
+	FeeRecipient        string `json:"feeRecipient,omitempty"`
+	SettlementRecipient string `json:"settlementRecipient,omitempty"`
+	ChallengeNonce      string `json:"challengeNonce,omitempty"`
+}
+
+// exactEvmExtraJSON has the fields of ExactEvmExtra without its JSON methods
+type exactEvmExtraJSON ExactEvmExtra
+
+// UnmarshalJSON decodes the extra, rejecting a fee that isn't a string (see ErrNumericAmount)
+func (e *ExactEvmExtra) UnmarshalJSON(data []byte) error {
+	if err := checkStringAmounts(data, "fee"); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, (*exactEvmExtraJSON)(e))
+}
+
+// DecodeExtra unmarshals the Extra field of PaymentRequirements into v.
+// It returns false if the requirements carry no extra information.
+func (p *PaymentRequirements) DecodeExtra(v any) (bool, error) {
+	if p.Extra == nil || len(*p.Extra) == 0 || string(*p.Extra) == "null" {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(*p.Extra, v); err != nil {
+		return false, fmt.Errorf("failed to unmarshal extra: %w", err)
+	}
+
+	return true, nil
+}
+
+// SetExtra marshals v into the Extra field of PaymentRequirements
+func (p *PaymentRequirements) SetExtra(v any) error {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal extra: %w", err)
+	}
+
+	rawMessage := json.RawMessage(jsonBytes)
+	p.Extra = &rawMessage
+
+	return nil
+}
+
+// PaymentPayload represents the decoded payment payload for a client's payment.
+// Payload is set for EVM networks; use Decode to inspect the payload of any network.
 type PaymentPayload struct {
 	X402Version int              `json:"x402Version"`
 	Scheme      string           `json:"scheme"`
 	Network     string           `json:"network"`
 	Payload     *ExactEvmPayload `json:"payload"`
+
+	// rawPayload keeps the undecoded JSON payload of networks that do not use ExactEvmPayload.
+	// It is a string so PaymentPayload stays comparable.
+	rawPayload string
 }
 
 // ExactEvmPayloadAuthorization represents the payload for an exact EVM payment
 type ExactEvmPayload struct {
 	Signature     string                        `json:"signature"`
 	Authorization *ExactEvmPayloadAuthorization `json:"authorization"`
+
+	// network is set by PaymentPayload.Decode, see Network
+	network string
 }
 
 // ExactEvmPayloadAuthorization represents the payload for an exact EVM payment ERC-3009
@@ -47,11 +135,39 @@ type ExactEvmPayloadAuthorization struct {
 	Nonce       string `json:"nonce"`
 }
 
+// authorizationJSON has the fields of ExactEvmPayloadAuthorization without its JSON methods
+type authorizationJSON ExactEvmPayloadAuthorization
+
+// UnmarshalJSON decodes the authorization, rejecting a value that isn't a string (see ErrNumericAmount)
+func (a *ExactEvmPayloadAuthorization) UnmarshalJSON(data []byte) error {
+	if err := checkStringAmounts(data, "value"); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, (*authorizationJSON)(a))
+}
+
 // VerifyResponse represents the response from the verify endpoint
 type VerifyResponse struct {
 	IsValid       bool    `json:"isValid"`
 	InvalidReason *string `json:"invalidReason,omitempty"`
 	Payer         *string `json:"payer,omitempty"`
+	// PayerBalance is the payer's balance of the asset in atomic units, if the facilitator reports it
+	PayerBalance string `json:"payerBalance,omitempty"`
+	// Shortfall is how many more atomic units the payer needs, if the facilitator reports it
+	Shortfall string `json:"shortfall,omitempty"`
+}
+
+// verifyResponseJSON has the fields of VerifyResponse without its JSON methods
+type verifyResponseJSON VerifyResponse
+
+// UnmarshalJSON decodes the verify response, rejecting balances that aren't strings (see ErrNumericAmount)
+func (v *VerifyResponse) UnmarshalJSON(data []byte) error {
+	if err := checkStringAmounts(data, "payerBalance", "shortfall"); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, (*verifyResponseJSON)(v))
 }
 
 // SettleResponse represents the response from the settle endpoint
@@ -61,6 +177,292 @@ type SettleResponse struct {
 	Transaction string  `json:"transaction"`
 	Network     string  `json:"network"`
 	Payer       *string `json:"payer,omitempty"`
+	// Status is the settlement status reported by facilitators that settle asynchronously, e.g. "pending"
+	Status string `json:"status,omitempty"`
+	// PreparedTransaction is set by facilitators that prepare the settlement transaction but leave it to the
+	// payer to sign and submit, see NeedsSubmission
+	PreparedTransaction *PreparedTransaction `json:"preparedTransaction,omitempty"`
+	// Confirmations is the number of block confirmations the facilitator waited for, if it reports it
+	Confirmations int `json:"confirmations,omitempty"`
+	// FeePayer is the address that paid the settlement gas, if the facilitator reports it
+	FeePayer string `json:"feePayer,omitempty"`
+	// Metadata is the settlement metadata echoed back by facilitators that support it
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// BlockNumber is the number of the block the settlement landed in, as reported by the facilitator,
+	// see SettledBlock
+	BlockNumber json.RawMessage `json:"blockNumber,omitempty"`
+	// BlockTimestamp is the timestamp of the block the settlement landed in, as reported by the facilitator,
+	// see SettledAt
+	BlockTimestamp json.RawMessage `json:"blockTimestamp,omitempty"`
+}
+
+// PreparedTransaction is an unsigned settlement transaction returned for the payer to submit itself
+type PreparedTransaction struct {
+	Network string `json:"network"`
+	// Transaction is the hex encoded binary encoding of the unsigned transaction
+	Transaction string `json:"transaction"`
+}
+
+// NeedsSubmission reports whether the facilitator only prepared the settlement transaction,
+// which the payer must sign and broadcast for the payment to settle
+func (s *SettleResponse) NeedsSubmission() bool {
+	return s.PreparedTransaction != nil && s.PreparedTransaction.Transaction != ""
+}
+
+// SettledBlock returns the number of the block the settlement landed in, given by the facilitator as a JSON
+// number or a decimal or hex string, or 0 if it wasn't reported. A malformed block number is treated as absent.
+func (s *SettleResponse) SettledBlock() uint64 {
+	number, _ := decodeUint64(s.BlockNumber)
+	return number
+}
+
+// SettledAt returns the timestamp of the block the settlement landed in, given by the facilitator as Unix seconds
+// or an RFC 3339 string, or the zero time if it wasn't reported. A malformed timestamp is treated as absent.
+func (s *SettleResponse) SettledAt() time.Time {
+	var text string
+	if json.Unmarshal(s.BlockTimestamp, &text) == nil {
+		if at, err := time.Parse(time.RFC3339, text); err == nil {
+			return at.UTC()
+		}
+	}
+
+	seconds, ok := decodeUint64(s.BlockTimestamp)
+	if !ok || seconds == 0 || seconds > math.MaxInt64 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0).UTC()
+}
+
+// decodeUint64 decodes a JSON number or a decimal or 0x prefixed hex string
+func decodeUint64(raw json.RawMessage) (uint64, bool) {
+	if len(raw) == 0 {
+		return 0, false
+	}
+
+	text := string(raw)
+	if err := json.Unmarshal(raw, &text); err != nil {
+		text = string(raw)
+	}
+	if hex, ok := strings.CutPrefix(text, "0x"); ok {
+		value, err := strconv.ParseUint(hex, 16, 64)
+		return value, err == nil
+	}
+	value, err := strconv.ParseUint(text, 10, 64)
+	return value, err == nil
+}
+
+// PaymentResult is the outcome of handling a payment: the verify response and, if the payment was settled,
+// the settle response, with the requirements it was matched to and how long each leg took. It is the result
+// shared by the facilitator client's VerifyAndSettle, the paymentserver functions and the gin middleware.
+type PaymentResult struct {
+	Payload      *PaymentPayload
+	Requirements *PaymentRequirements
+	// Payer is the payer reported by the facilitator, or else the authorization's from address
+	Payer  string
+	Verify *VerifyResponse
+	// Settle is nil if settlement wasn't attempted
+	Settle *SettleResponse
+	// VerifyDuration and SettleDuration are how long the facilitator took to answer each leg, zero if it wasn't run
+	VerifyDuration time.Duration
+	SettleDuration time.Duration
+}
+
+// Settled reports whether the payment was settled successfully
+func (r *PaymentResult) Settled() bool {
+	return r != nil && r.Settle != nil && r.Settle.Success
+}
+
+// RefundResponse represents the response from the refund endpoint
+type RefundResponse struct {
+	Success     bool    `json:"success"`
+	ErrorReason *string `json:"errorReason,omitempty"`
+	// Transaction is the hash of the refund transaction returning the funds to the payer
+	Transaction string `json:"transaction"`
+	Network     string `json:"network"`
+}
+
+// SupportedResponse represents the response from the supported endpoint
+type SupportedResponse struct {
+	Kinds []SupportedKind `json:"kinds"`
+}
+
+// Resource represents a paid resource listed by a facilitator's discovery endpoint
+type Resource struct {
+	Resource    string                `json:"resource"`
+	Type        string                `json:"type"`
+	X402Version int                   `json:"x402Version"`
+	Accepts     []PaymentRequirements `json:"accepts"`
+	// LastUpdated is when the facilitator last saw the resource, in the facilitator's own format
+	LastUpdated json.RawMessage  `json:"lastUpdated,omitempty"`
+	Metadata    *json.RawMessage `json:"metadata,omitempty"`
+}
+
+// ListResponse represents one page of the response from the discovery endpoint
+type ListResponse struct {
+	X402Version int        `json:"x402Version"`
+	Items       []Resource `json:"items"`
+	Pagination  Pagination `json:"pagination"`
+}
+
+// Pagination locates a page of a discovery listing among the total number of resources
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// SupportedKind represents a scheme and network combination supported by a facilitator
+type SupportedKind struct {
+	X402Version int              `json:"x402Version"`
+	Scheme      string           `json:"scheme"`
+	Network     string           `json:"network"`
+	Extra       *json.RawMessage `json:"extra,omitempty"`
+}
+
+// extraField decodes the field name of the extra information a facilitator may attach to the kind into v.
+// Fields are decoded independently, so one malformed field doesn't hide the others. It reports whether
+// the field is set, with an error if it is set but malformed; extra that isn't an object is treated as absent.
+func (k *SupportedKind) extraField(name string, v any) (bool, error) {
+	if k.Extra == nil {
+		return false, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(*k.Extra, &fields); err != nil {
+		return false, nil
+	}
+	raw, ok := fields[name]
+	if !ok || string(raw) == "null" {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("malformed %s in the extra of %s on %s: %w", name, k.Scheme, k.Network, err)
+	}
+
+	return true, nil
+}
+
+// FeePayer returns the address the facilitator pays network fees from, or "" if it is not advertised
+func (k *SupportedKind) FeePayer() string {
+	var feePayer string
+	if _, err := k.extraField("feePayer", &feePayer); err != nil {
+		return ""
+	}
+	return feePayer
+}
+
+// Sponsored reports whether the facilitator pays the network fees for this kind, so payers
+// do not need to hold native tokens for gas. It is false unless the facilitator says otherwise
+// through an explicit sponsored flag or by advertising a fee payer.
+func (k *SupportedKind) Sponsored() bool {
+	var sponsored bool
+	if set, err := k.extraField("sponsored", &sponsored); set && err == nil {
+		return sponsored
+	}
+	return k.FeePayer() != ""
+}
+
+// MaxTimeoutSeconds returns the longest authorization validity window, validBefore - validAfter, the facilitator
+// accepts for this kind, or 0 if it is not advertised
+func (k *SupportedKind) MaxTimeoutSeconds() int {
+	var maxTimeoutSeconds int
+	if _, err := k.extraField("maxTimeoutSeconds", &maxTimeoutSeconds); err != nil {
+		return 0
+	}
+	return maxTimeoutSeconds
+}
+
+// MinAmount returns the smallest maxAmountRequired, in atomic units, the facilitator settles for this kind,
+// or "" if it is not advertised or malformed. Validate refuses requirements for a kind with a malformed minAmount.
+func (k *SupportedKind) MinAmount() string {
+	var minAmount string
+	if _, err := k.extraField("minAmount", &minAmount); err != nil {
+		return ""
+	}
+	return minAmount
+}
+
+// SettlementAccountPattern returns the regular expression, in Go's RE2 syntax, a settling account requested for
+// this kind must match in full, or "" if the facilitator doesn't specify a format
+func (k *SupportedKind) SettlementAccountPattern() string {
+	var pattern string
+	if _, err := k.extraField("settlementAccountPattern", &pattern); err != nil {
+		return ""
+	}
+	return pattern
+}
+
+// Validate checks the requirements can be settled under this kind: the scheme and network match and
+// maxAmountRequired meets the advertised minimum amount, so requirements the facilitator would refuse
+// are caught when they are built rather than when a payment is settled.
+// A below-minimum amount is returned as an error matching ErrBelowMinAmount, and a malformed advertised
+// minimum as an error, since it can't be checked.
+func (k *SupportedKind) Validate(requirements *PaymentRequirements) error {
+	if requirements == nil {
+		return fmt.Errorf("payment requirements are nil")
+	}
+	if requirements.Scheme != k.Scheme || requirements.Network != k.Network {
+		return fmt.Errorf("requirements for %s on %s don't match the supported kind %s on %s",
+			requirements.Scheme, requirements.Network, k.Scheme, k.Network)
+	}
+
+	var minAmount string
+	if set, err := k.extraField("minAmount", &minAmount); err != nil {
+		return fmt.Errorf("invalid minAmount advertised: %w", err)
+	} else if !set || minAmount == "" {
+		return nil
+	}
+	minimum, ok := new(big.Int).SetString(minAmount, 10)
+	if !ok || minimum.Sign() < 0 {
+		return fmt.Errorf("invalid minAmount %q advertised for %s on %s", minAmount, k.Scheme, k.Network)
+	}
+	amount, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+	if !ok || amount.Sign() < 0 {
+		return fmt.Errorf("invalid maxAmountRequired: %q", requirements.MaxAmountRequired)
+	}
+	if amount.Cmp(minimum) < 0 {
+		return fmt.Errorf("%w: maxAmountRequired %s for %s on %s is below the facilitator's minimum of %s",
+			ErrBelowMinAmount, requirements.MaxAmountRequired, k.Scheme, k.Network, minAmount)
+	}
+
+	return nil
+}
+
+// ErrorResponse represents the standard x402 error response body
+type ErrorResponse struct {
+	Error   string           `json:"error"`
+	Details *json.RawMessage `json:"details,omitempty"`
+}
+
+// PaymentRequiredResponse represents the body of a 402 Payment Required response,
+// advertising the requirements a resource can be paid with
+type PaymentRequiredResponse struct {
+	X402Version int                   `json:"x402Version"`
+	Accepts     []PaymentRequirements `json:"accepts"`
+	// Error is the reason the request wasn't served, e.g. a missing payment or an invalid reason from verification
+	Error string `json:"error,omitempty"`
+}
+
+// WriteResponse responds with status 402 Payment Required and the response as a JSON body.
+// An x402Version of 0 is written as the current X402Version, and nil accepts as an empty list.
+func (p *PaymentRequiredResponse) WriteResponse(w http.ResponseWriter) error {
+	response := *p
+	if response.X402Version == 0 {
+		response.X402Version = X402Version
+	}
+	if response.Accepts == nil {
+		response.Accepts = []PaymentRequirements{}
+	}
+
+	body, err := json.Marshal(&response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the payment required response: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusPaymentRequired)
+	_, err = w.Write(body)
+
+	return err
 }
 
 func (s *SettleResponse) EncodeToBase64String() (string, error) {
@@ -72,9 +474,35 @@ func (s *SettleResponse) EncodeToBase64String() (string, error) {
 	return base64.StdEncoding.EncodeToString(jsonBytes), nil
 }
 
-// DecodePaymentPayloadFromBase64 decodes a base64 encoded string into a PaymentPayload
+// EncodeToBase64String encodes the payment payload for use in the X-PAYMENT header,
+// using the spec's standard padded base64.
+// The payload is stamped with X402Version if it doesn't carry a version yet.
+func (p *PaymentPayload) EncodeToBase64String() (string, error) {
+	return p.EncodeToBase64StringWith(base64.StdEncoding)
+}
+
+// EncodeToBase64StringWith is EncodeToBase64String using the given base64 variant,
+// e.g. base64.RawURLEncoding for servers that only accept URL-safe headers.
+func (p *PaymentPayload) EncodeToBase64StringWith(encoding *base64.Encoding) (string, error) {
+	encoded := *p
+	if encoded.X402Version == 0 {
+		encoded.X402Version = X402Version
+	}
+
+	jsonBytes, err := json.Marshal(&encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to base64 encode the payment payload: %w", err)
+	}
+
+	return encoding.EncodeToString(jsonBytes), nil
+}
+
+// DecodePaymentPayloadFromBase64 decodes a base64 encoded string into a PaymentPayload.
+// Standard and URL-safe base64 are both accepted, with or without padding.
+// It returns an error wrapping ErrUnsupportedX402Version if the payload's version is newer than X402Version
+// or older than MinX402Version.
 func DecodePaymentPayloadFromBase64(encoded string) (*PaymentPayload, error) {
-	decodedBytes, err := base64.StdEncoding.DecodeString(encoded)
+	decodedBytes, err := decodeBase64(encoded)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode base64 string: %w", err)
 	}
@@ -84,8 +512,12 @@ func DecodePaymentPayloadFromBase64(encoded string) (*PaymentPayload, error) {
 		return nil, fmt.Errorf("failed to unmarshal payment payload: %w", err)
 	}
 
-	// Set the x402Version after decoding, matching the TypeScript behavior
-	payload.X402Version = 1
+	if payload.X402Version > X402Version {
+		return nil, fmt.Errorf("%w: %d is newer than the supported %d", ErrUnsupportedX402Version, payload.X402Version, X402Version)
+	}
+	if payload.X402Version < MinX402Version {
+		return nil, fmt.Errorf("%w: %d (supported: %d to %d)", ErrUnsupportedX402Version, payload.X402Version, MinX402Version, X402Version)
+	}
 
 	return &payload, nil
 }
@@ -0,0 +1,78 @@
+package types_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+func TestPaymentRequiredResponse(t *testing.T) {
+	extra := json.RawMessage(`{"name":"USDC","version":"2"}`)
+	response := &types.PaymentRequiredResponse{
+		Accepts: []types.PaymentRequirements{{
+			Scheme:            "exact",
+			Network:           "base-sepolia",
+			MaxAmountRequired: "10000",
+			Resource:          "https://example.com/resource",
+			Description:       "Test resource",
+			MimeType:          "application/json",
+			PayTo:             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			MaxTimeoutSeconds: 60,
+			Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			Extra:             &extra,
+		}},
+		Error: "X-PAYMENT header is required",
+	}
+
+	w := httptest.NewRecorder()
+	if err := response.WriteResponse(w); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("Expected status 402, got: %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+		t.Errorf("Expected a JSON content type, got: %s", contentType)
+	}
+
+	// The body has exactly the shape of the spec's 402 Payment Required response
+	const expected = `{
+		"x402Version": 1,
+		"accepts": [{
+			"scheme": "exact",
+			"network": "base-sepolia",
+			"maxAmountRequired": "10000",
+			"resource": "https://example.com/resource",
+			"description": "Test resource",
+			"mimeType": "application/json",
+			"payTo": "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+			"maxTimeoutSeconds": 60,
+			"asset": "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			"extra": {"name": "USDC", "version": "2"}
+		}],
+		"error": "X-PAYMENT header is required"
+	}`
+	var got, want map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to unmarshal body: %v", err)
+	}
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatalf("Failed to unmarshal expected body: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected body %s, got: %s", expected, w.Body.String())
+	}
+
+	// Without an error or requirements, the error is omitted and accepts is an empty list
+	w = httptest.NewRecorder()
+	if err := (&types.PaymentRequiredResponse{}).WriteResponse(w); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if body := w.Body.String(); body != `{"x402Version":1,"accepts":[]}` {
+		t.Errorf("Expected an empty response, got: %s", body)
+	}
+}
@@ -1,4 +1,21 @@
 package types
 
+import "errors"
+
 // Version represents the current version of the x402 package
 const Version = "0.1.0"
+
+// X402Version is the version of the x402 protocol implemented by this package
+const X402Version = 1
+
+// MinX402Version is the oldest x402 protocol version whose payments this package still decodes.
+// Versions from MinX402Version up to but excluding X402Version are deprecated, see IsDeprecatedX402Version.
+const MinX402Version = 1
+
+// ErrUnsupportedX402Version is returned when a payment uses an x402 protocol version this package doesn't implement
+var ErrUnsupportedX402Version = errors.New("unsupported x402 version")
+
+// IsDeprecatedX402Version reports whether version is older than X402Version but still supported
+func IsDeprecatedX402Version(version int) bool {
+	return version >= MinX402Version && version < X402Version
+}